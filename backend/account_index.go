@@ -0,0 +1,142 @@
+package backend
+
+import (
+	"encoding/binary"
+
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/ethdb"
+)
+
+var (
+	accountDescPrefix  = []byte("d") // address + ^block number + ^position -> tx hash
+	accountAscPrefix   = []byte("u") // address + block number + position -> tx hash
+	senderNoncePrefix  = []byte("s") // sender + nonce -> tx hash
+	accountBlockPrefix = []byte("k") // block number -> transactions of the block
+)
+
+// AccountTx is a transaction as seen by the account index. To is the created
+// contract for a contract creation.
+type AccountTx struct {
+	Hash  ethcmn.Hash     `json:"hash"`
+	From  ethcmn.Address  `json:"from"`
+	To    *ethcmn.Address `json:"to"`
+	Nonce uint64          `json:"nonce"`
+}
+
+// AccountTxRef locates an indexed transaction.
+type AccountTxRef struct {
+	Block uint64
+	Hash  ethcmn.Hash
+}
+
+// AccountIndex keeps the transactions sent from or to each address, in the
+// order they were executed.
+type AccountIndex struct {
+	*heightIndex
+}
+
+func NewAccountIndex(db ethdb.KeyValueStore) (*AccountIndex, error) {
+	h, err := newHeightIndex(db, accountBlockPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return &AccountIndex{h}, nil
+}
+
+func accountTxKeys(number uint64, position int, tx AccountTx) [][]byte {
+	addresses := []ethcmn.Address{tx.From}
+	if tx.To != nil && *tx.To != tx.From {
+		addresses = append(addresses, *tx.To)
+	}
+	pos := make([]byte, 4)
+	binary.BigEndian.PutUint32(pos, uint32(position))
+	invPos := make([]byte, 4)
+	binary.BigEndian.PutUint32(invPos, ^uint32(position))
+
+	var keys [][]byte
+	for _, address := range addresses {
+		keys = append(keys,
+			indexKey(accountDescPrefix, address.Bytes(), encodeNumber(^number), invPos),
+			indexKey(accountAscPrefix, address.Bytes(), encodeNumber(number), pos),
+		)
+	}
+	return keys
+}
+
+// Add indexes the transactions of a block in execution order.
+func (idx *AccountIndex) Add(number uint64, txs []AccountTx) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if err := idx.rewind(number, idx.deleteBlock); err != nil {
+		return err
+	}
+	batch := idx.db.NewBatch()
+	for i, tx := range txs {
+		for _, key := range accountTxKeys(number, i, tx) {
+			batch.Put(key, tx.Hash.Bytes())
+		}
+		batch.Put(indexKey(senderNoncePrefix, tx.From.Bytes(), encodeNumber(tx.Nonce)), tx.Hash.Bytes())
+	}
+	return idx.commit(batch, number, txs)
+}
+
+func (idx *AccountIndex) deleteBlock(batch ethdb.Batch, number uint64) error {
+	var txs []AccountTx
+	if ok, err := idx.record(number, &txs); !ok || err != nil {
+		return err
+	}
+	for i, tx := range txs {
+		for _, key := range accountTxKeys(number, i, tx) {
+			batch.Delete(key)
+		}
+		batch.Delete(indexKey(senderNoncePrefix, tx.From.Bytes(), encodeNumber(tx.Nonce)))
+	}
+	return nil
+}
+
+// accountPage collects whole blocks of transactions from the iterator until
+// there are at least pageSize of them, more reports whether any are left.
+func accountPage(it ethdb.Iterator, prefixLen int, inverted bool, pageSize int) ([]AccountTxRef, bool) {
+	defer it.Release()
+
+	var refs []AccountTxRef
+	for it.Next() {
+		number := binary.BigEndian.Uint64(it.Key()[prefixLen:])
+		if inverted {
+			number = ^number
+		}
+		if len(refs) >= pageSize && refs[len(refs)-1].Block != number {
+			return refs, true
+		}
+		refs = append(refs, AccountTxRef{Block: number, Hash: ethcmn.BytesToHash(it.Value())})
+	}
+	return refs, false
+}
+
+// Before returns the transactions of the address in blocks below number, or
+// the newest ones if number is 0, newest first.
+func (idx *AccountIndex) Before(address ethcmn.Address, number uint64, pageSize int) ([]AccountTxRef, bool) {
+	prefix := indexKey(accountDescPrefix, address.Bytes())
+	var start []byte
+	if number > 0 {
+		start = encodeNumber(^(number - 1))
+	}
+	return accountPage(idx.db.NewIterator(prefix, start), len(prefix), true, pageSize)
+}
+
+// After returns the transactions of the address in blocks above number,
+// oldest first.
+func (idx *AccountIndex) After(address ethcmn.Address, number uint64, pageSize int) ([]AccountTxRef, bool) {
+	prefix := indexKey(accountAscPrefix, address.Bytes())
+	return accountPage(idx.db.NewIterator(prefix, encodeNumber(number+1)), len(prefix), false, pageSize)
+}
+
+// BySenderAndNonce returns the transaction a sender sent with a nonce.
+func (idx *AccountIndex) BySenderAndNonce(sender ethcmn.Address, nonce uint64) (ethcmn.Hash, bool) {
+	data, err := idx.db.Get(indexKey(senderNoncePrefix, sender.Bytes(), encodeNumber(nonce)))
+	if err != nil || len(data) != len(ethcmn.Hash{}) {
+		return ethcmn.Hash{}, false
+	}
+	return ethcmn.BytesToHash(data), true
+}
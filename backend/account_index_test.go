@@ -0,0 +1,62 @@
+package backend
+
+import (
+	"testing"
+
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/ethdb/memorydb"
+)
+
+func TestAccountIndex(t *testing.T) {
+	idx, err := NewAccountIndex(memorydb.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, bob := ethcmn.Address{1}, ethcmn.Address{2}
+	tx := func(block, i byte, from, to ethcmn.Address, nonce uint64) AccountTx {
+		return AccountTx{Hash: ethcmn.Hash{block, i}, From: from, To: &to, Nonce: nonce}
+	}
+	// alice sends to bob twice in every block, bob once back in block 2
+	for block := byte(1); block <= 4; block++ {
+		txs := []AccountTx{
+			tx(block, 0, alice, bob, uint64(block)*2),
+			tx(block, 1, alice, bob, uint64(block)*2+1),
+		}
+		if block == 2 {
+			txs = append(txs, tx(block, 2, bob, alice, 0))
+		}
+		if err := idx.Add(uint64(block), txs); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	refs, more := idx.Before(alice, 0, 3)
+	if len(refs) != 4 || !more || refs[0].Hash != (ethcmn.Hash{4, 1}) || refs[3].Block != 3 {
+		t.Fatalf("expected the two newest blocks, got %v more %v", refs, more)
+	}
+	refs, more = idx.Before(alice, 3, 10)
+	if len(refs) != 5 || more || refs[0].Hash != (ethcmn.Hash{2, 2}) {
+		t.Fatalf("expected blocks 2 and 1 newest first, got %v more %v", refs, more)
+	}
+	refs, more = idx.After(bob, 2, 1)
+	if len(refs) != 2 || !more || refs[0].Hash != (ethcmn.Hash{3, 0}) {
+		t.Fatalf("expected block 3 oldest first, got %v more %v", refs, more)
+	}
+	if hash, ok := idx.BySenderAndNonce(bob, 0); !ok || hash != (ethcmn.Hash{2, 2}) {
+		t.Fatal("sender and nonce not found")
+	}
+
+	// replacing block 2 drops the blocks above it
+	if err := idx.Add(2, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := idx.BySenderAndNonce(bob, 0); ok {
+		t.Fatal("replaced transaction still indexed")
+	}
+	if refs, _ := idx.Before(alice, 0, 10); len(refs) != 2 || refs[0].Block != 1 {
+		t.Fatalf("expected only block 1 left, got %v", refs)
+	}
+	if idx.Head() != 2 {
+		t.Fatalf("expected head 2, got %d", idx.Head())
+	}
+}
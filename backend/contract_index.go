@@ -0,0 +1,142 @@
+package backend
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/ethdb"
+)
+
+var (
+	contractPrefix      = []byte("c") // contract address -> creation
+	deploymentPrefix    = []byte("d") // block number + contract address -> creation it replaced, if any
+	contractBlockPrefix = []byte("k") // block number -> contracts created in the block
+)
+
+// ContractCreation records the transaction that deployed a contract. The
+// code hash is zero if the contract was left without code.
+type ContractCreation struct {
+	Address  ethcmn.Address `json:"address"`
+	Creator  ethcmn.Address `json:"creator"`
+	TxHash   ethcmn.Hash    `json:"transactionHash"`
+	Block    uint64         `json:"blockNumber"`
+	CodeHash ethcmn.Hash    `json:"codeHash"`
+}
+
+// ContractIndex keeps the contracts deployed by transactions, by address and
+// by block. Contracts created by other contracts do not show up in receipts
+// and are not indexed.
+type ContractIndex struct {
+	*heightIndex
+}
+
+func NewContractIndex(db ethdb.KeyValueStore) (*ContractIndex, error) {
+	h, err := newHeightIndex(db, contractBlockPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return &ContractIndex{h}, nil
+}
+
+// Add indexes the contracts deployed in a block.
+func (idx *ContractIndex) Add(number uint64, creations []*ContractCreation) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if err := idx.rewind(number, idx.deleteBlock); err != nil {
+		return err
+	}
+	batch := idx.db.NewBatch()
+	addresses := make([]ethcmn.Address, len(creations))
+	for i, creation := range creations {
+		key := indexKey(contractPrefix, creation.Address.Bytes())
+		// an address redeployed keeps its earlier creation for a rewind
+		var replaced []byte
+		if ok, err := idx.db.Has(key); err != nil {
+			return err
+		} else if ok {
+			if replaced, err = idx.db.Get(key); err != nil {
+				return err
+			}
+		}
+		creation.Block = number
+		data, err := json.Marshal(creation)
+		if err != nil {
+			return err
+		}
+		batch.Put(key, data)
+		batch.Put(indexKey(deploymentPrefix, encodeNumber(number), creation.Address.Bytes()), replaced)
+		addresses[i] = creation.Address
+	}
+	return idx.commit(batch, number, addresses)
+}
+
+func (idx *ContractIndex) deleteBlock(batch ethdb.Batch, number uint64) error {
+	var addresses []ethcmn.Address
+	if ok, err := idx.record(number, &addresses); !ok || err != nil {
+		return err
+	}
+	for _, address := range addresses {
+		key := indexKey(deploymentPrefix, encodeNumber(number), address.Bytes())
+		replaced, err := idx.db.Get(key)
+		if err != nil {
+			return err
+		}
+		if len(replaced) > 0 {
+			batch.Put(indexKey(contractPrefix, address.Bytes()), replaced)
+		} else {
+			batch.Delete(indexKey(contractPrefix, address.Bytes()))
+		}
+		batch.Delete(key)
+	}
+	return nil
+}
+
+// Creation returns how a contract was deployed, nil if it is not indexed.
+func (idx *ContractIndex) Creation(address ethcmn.Address) (*ContractCreation, error) {
+	key := indexKey(contractPrefix, address.Bytes())
+	if ok, err := idx.db.Has(key); !ok || err != nil {
+		return nil, err
+	}
+	data, err := idx.db.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	creation := new(ContractCreation)
+	return creation, json.Unmarshal(data, creation)
+}
+
+// Deployments returns the contracts deployed in the blocks from..to, by block
+// and then address, after the cursor. next is the cursor of the following
+// page, nil on the last page.
+func (idx *ContractIndex) Deployments(from, to uint64, cursor []byte, limit int) ([]*ContractCreation, []byte, error) {
+	start := encodeNumber(from)
+	if cursor != nil {
+		start = append(append([]byte{}, cursor...), 0)
+	}
+	it := idx.db.NewIterator(deploymentPrefix, start)
+	defer it.Release()
+
+	creations := []*ContractCreation{}
+	var last []byte
+	for it.Next() {
+		key := it.Key()[len(deploymentPrefix):]
+		if binary.BigEndian.Uint64(key) > to {
+			break
+		}
+		if len(creations) >= limit {
+			return creations, last, nil
+		}
+		creation, err := idx.Creation(ethcmn.BytesToAddress(key[8:]))
+		if err != nil {
+			return nil, nil, err
+		}
+		// an address redeployed later is listed at its latest deployment only
+		if creation != nil && creation.Block == binary.BigEndian.Uint64(key) {
+			creations = append(creations, creation)
+		}
+		last = append([]byte{}, key...)
+	}
+	return creations, nil, nil
+}
@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"testing"
+
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/ethdb/memorydb"
+)
+
+func TestContractIndex(t *testing.T) {
+	idx, err := NewContractIndex(memorydb.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployer := ethcmn.Address{0xde}
+	deploy := func(block, i byte) *ContractCreation {
+		return &ContractCreation{Address: ethcmn.Address{block, i}, Creator: deployer, TxHash: ethcmn.Hash{block, i}}
+	}
+	for block := byte(1); block <= 4; block++ {
+		if err := idx.Add(uint64(block), []*ContractCreation{deploy(block, 0), deploy(block, 1)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	creation, err := idx.Creation(ethcmn.Address{3, 1})
+	if err != nil || creation == nil || creation.Block != 3 || creation.TxHash != (ethcmn.Hash{3, 1}) || creation.Creator != deployer {
+		t.Fatalf("unexpected creation %+v, err %v", creation, err)
+	}
+
+	creations, next, err := idx.Deployments(2, 3, nil, 3)
+	if err != nil || len(creations) != 3 || next == nil || creations[0].Address != (ethcmn.Address{2, 0}) {
+		t.Fatalf("expected the first page of blocks 2..3, got %v next %x err %v", creations, next, err)
+	}
+	creations, next, err = idx.Deployments(2, 3, next, 3)
+	if err != nil || len(creations) != 1 || next != nil || creations[0].Address != (ethcmn.Address{3, 1}) {
+		t.Fatalf("expected the last deployment of block 3, got %v next %x err %v", creations, next, err)
+	}
+
+	// a reorg at block 3 drops the deployments of blocks 3 and 4
+	if err := idx.Add(3, []*ContractCreation{deploy(3, 9)}); err != nil {
+		t.Fatal(err)
+	}
+	if creation, _ = idx.Creation(ethcmn.Address{4, 0}); creation != nil {
+		t.Fatalf("expected block 4 to be dropped, got %+v", creation)
+	}
+	creations, _, _ = idx.Deployments(3, 10, nil, 10)
+	if len(creations) != 1 || creations[0].Address != (ethcmn.Address{3, 9}) {
+		t.Fatalf("expected only the replacement deployment, got %v", creations)
+	}
+
+	// rewinding a redeployment brings back the earlier one
+	redeploy := &ContractCreation{Address: ethcmn.Address{2, 0}, Creator: deployer, TxHash: ethcmn.Hash{4, 0}, CodeHash: ethcmn.Hash{0xc0}}
+	if err := idx.Add(4, []*ContractCreation{redeploy}); err != nil {
+		t.Fatal(err)
+	}
+	if creation, _ = idx.Creation(ethcmn.Address{2, 0}); creation == nil || creation.Block != 4 || creation.CodeHash != (ethcmn.Hash{0xc0}) {
+		t.Fatalf("expected the redeployment, got %+v", creation)
+	}
+	if err := idx.Add(4, nil); err != nil {
+		t.Fatal(err)
+	}
+	if creation, _ = idx.Creation(ethcmn.Address{2, 0}); creation == nil || creation.Block != 2 || creation.TxHash != (ethcmn.Hash{2, 0}) {
+		t.Fatalf("expected the deployment of block 2 back, got %+v", creation)
+	}
+}
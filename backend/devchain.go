@@ -0,0 +1,683 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/arcology-network/component-lib/ethrpc"
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/core"
+	"github.com/arcology-network/evm/core/rawdb"
+	"github.com/arcology-network/evm/core/state"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	"github.com/arcology-network/evm/core/vm"
+	ethcrp "github.com/arcology-network/evm/crypto"
+	"github.com/arcology-network/evm/params"
+	ethrlp "github.com/arcology-network/evm/rlp"
+	"github.com/arcology-network/evm/trie"
+)
+
+const (
+	devGasLimit = 0xffffffff
+	devGasPrice = 1
+)
+
+var devBalance, _ = new(big.Int).SetString("100000000000000000000000", 10)
+
+type devBlock struct {
+	block    *ethtyp.Block
+	receipts []*ethtyp.Receipt
+}
+
+type devTxLookup struct {
+	tx     *ethtyp.Transaction
+	number uint64
+	index  int
+}
+
+// DevChain is a single process chain that executes transactions with the evm
+// on an in-memory state database. It mines a block for every transaction, or
+// on a fixed interval when a block time is given.
+type DevChain struct {
+	config    *params.ChainConfig
+	signer    ethtyp.Signer
+	coinbase  ethcmn.Address
+	blockTime time.Duration
+	filters   *Filters
+
+	chainGuard    sync.RWMutex
+	db            state.Database
+	statedb       *state.StateDB
+	blocks        []*devBlock
+	roots         []ethcmn.Hash // state root per block, including direct state edits
+	hashes        map[ethcmn.Hash]uint64
+	txs           map[ethcmn.Hash]*devTxLookup
+	pending       []*ethtyp.Transaction
+	timeOffset    uint64
+	nextTimestamp uint64
+	impersonated  map[ethcmn.Address]bool
+	senders       map[ethcmn.Hash]ethcmn.Address // senders of unsigned impersonated txs
+	snapshots     []*devSnapshot
+}
+
+func NewDevChain(chainID *big.Int, accounts []ethcmn.Address, blockTime time.Duration, filters *Filters) *DevChain {
+	config := chainConfig(chainID)
+
+	db := state.NewDatabase(rawdb.NewMemoryDatabase())
+	statedb, _ := state.New(ethcmn.Hash{}, db, nil)
+	for _, account := range accounts {
+		statedb.SetBalance(account, new(big.Int).Set(devBalance))
+	}
+	root, _ := statedb.Commit(true)
+
+	genesis := ethtyp.NewBlock(&ethtyp.Header{
+		Number:     big.NewInt(0),
+		GasLimit:   devGasLimit,
+		Difficulty: big.NewInt(1),
+		Time:       uint64(time.Now().Unix()),
+		Root:       root,
+	}, nil, nil, nil, trie.NewStackTrie(nil))
+
+	chain := &DevChain{
+		config:       config,
+		signer:       ethtyp.NewEIP155Signer(chainID),
+		blockTime:    blockTime,
+		filters:      filters,
+		db:           db,
+		statedb:      statedb,
+		blocks:       []*devBlock{{block: genesis}},
+		roots:        []ethcmn.Hash{root},
+		hashes:       map[ethcmn.Hash]uint64{genesis.Hash(): 0},
+		txs:          make(map[ethcmn.Hash]*devTxLookup),
+		impersonated: make(map[ethcmn.Address]bool),
+		senders:      make(map[ethcmn.Hash]ethcmn.Address),
+	}
+	if blockTime > 0 {
+		go chain.mineLoop()
+	}
+	return chain
+}
+
+func (c *DevChain) mineLoop() {
+	ticker := time.NewTicker(c.blockTime)
+	defer ticker.Stop()
+
+	for {
+		<-ticker.C
+		c.chainGuard.Lock()
+		txs := c.pending
+		c.pending = nil
+		c.mine(txs)
+		c.chainGuard.Unlock()
+	}
+}
+
+// mine executes txs on top of the current head and appends the resulting
+// block. Transactions that fail validation are left out of the block and the
+// first such error is returned. The caller must hold chainGuard.
+func (c *DevChain) mine(txs []*ethtyp.Transaction) (*devBlock, error) {
+	parent := c.blocks[len(c.blocks)-1].block
+	header := &ethtyp.Header{
+		ParentHash: parent.Hash(),
+		Coinbase:   c.coinbase,
+		Number:     new(big.Int).Add(parent.Number(), big.NewInt(1)),
+		GasLimit:   devGasLimit,
+		Difficulty: big.NewInt(1),
+		Time:       uint64(time.Now().Unix()) + c.timeOffset,
+	}
+	if c.nextTimestamp != 0 {
+		header.Time = c.nextTimestamp
+		c.nextTimestamp = 0
+	}
+	if header.Time <= parent.Time() {
+		header.Time = parent.Time() + 1
+	}
+
+	var (
+		firstErr error
+		included []*ethtyp.Transaction
+		receipts []*ethtyp.Receipt
+		usedGas  uint64
+		gp       = new(core.GasPool).AddGas(header.GasLimit)
+	)
+	for _, tx := range txs {
+		receipt, err := c.applyTransaction(header, tx, len(included), gp, &usedGas)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		included = append(included, tx)
+		receipts = append(receipts, receipt)
+	}
+	if len(txs) > 0 && len(included) == 0 {
+		return nil, firstErr
+	}
+
+	root, err := c.statedb.Commit(true)
+	if err != nil {
+		return nil, err
+	}
+	// a fresh state database per block, the old one keeps the logs of every
+	// transaction it ran
+	if c.statedb, err = state.New(root, c.db, nil); err != nil {
+		return nil, err
+	}
+	header.Root = root
+	header.GasUsed = usedGas
+
+	block := ethtyp.NewBlock(header, included, nil, receipts, trie.NewStackTrie(nil))
+	var logs []*ethtyp.Log
+	for i, receipt := range receipts {
+		receipt.BlockHash = block.Hash()
+		for _, log := range receipt.Logs {
+			log.BlockHash = receipt.BlockHash
+			log.BlockNumber = block.NumberU64()
+			log.Index = uint(len(logs))
+			logs = append(logs, log)
+		}
+		c.txs[included[i].Hash()] = &devTxLookup{
+			tx:     included[i],
+			number: block.NumberU64(),
+			index:  i,
+		}
+	}
+
+	mined := &devBlock{block: block, receipts: receipts}
+	c.blocks = append(c.blocks, mined)
+	c.roots = append(c.roots, root)
+	c.hashes[block.Hash()] = block.NumberU64()
+	if c.filters != nil {
+		c.filters.OnLogsArrived(block.NumberU64(), logs, block.Hash(), block.ParentHash())
+	}
+	return mined, firstErr
+}
+
+func (c *DevChain) applyTransaction(header *ethtyp.Header, tx *ethtyp.Transaction, index int, gp *core.GasPool, usedGas *uint64) (*ethtyp.Receipt, error) {
+	msg, err := c.asMessage(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.statedb.Prepare(tx.Hash(), ethcmn.Hash{}, index)
+	snapshot := c.statedb.Snapshot()
+	evm := vm.NewEVM(c.blockContext(header), core.NewEVMTxContext(msg), c.statedb, c.config, vm.Config{})
+	result, err := core.ApplyMessage(evm, msg, gp)
+	if err != nil {
+		c.statedb.RevertToSnapshot(snapshot)
+		return nil, err
+	}
+	c.statedb.Finalise(true)
+	*usedGas += result.UsedGas
+
+	receipt := ethtyp.NewReceipt(nil, result.Failed(), *usedGas)
+	receipt.TxHash = tx.Hash()
+	receipt.GasUsed = result.UsedGas
+	if msg.To() == nil {
+		receipt.ContractAddress = ethcrp.CreateAddress(msg.From(), tx.Nonce())
+	}
+	receipt.Logs = c.statedb.GetLogs(tx.Hash())
+	receipt.Bloom = ethtyp.CreateBloom(ethtyp.Receipts{receipt})
+	receipt.BlockNumber = new(big.Int).Set(header.Number)
+	receipt.TransactionIndex = uint(index)
+	return receipt, nil
+}
+
+// asMessage recovers the sender of tx, or takes it from the impersonation
+// record for transactions submitted without a signature.
+func (c *DevChain) asMessage(tx *ethtyp.Transaction) (ethtyp.Message, error) {
+	if from, ok := c.senders[tx.Hash()]; ok {
+		return ethtyp.NewMessage(from, tx.To(), tx.Nonce(), tx.Value(), tx.Gas(), tx.GasPrice(), tx.Data(), tx.AccessList(), true), nil
+	}
+	return tx.AsMessage(c.signer)
+}
+
+func (c *DevChain) sender(tx *ethtyp.Transaction) ethcmn.Address {
+	if from, ok := c.senders[tx.Hash()]; ok {
+		return from
+	}
+	from, _ := ethtyp.Sender(c.signer, tx)
+	return from
+}
+
+func (c *DevChain) blockContext(header *ethtyp.Header) vm.BlockContext {
+	return headerContext(header, func(number uint64) ethcmn.Hash {
+		if number < uint64(len(c.blocks)) {
+			return c.blocks[number].block.Hash()
+		}
+		return ethcmn.Hash{}
+	})
+}
+
+// blockAt resolves a block number or one of the ethrpc block tags. The caller
+// must hold chainGuard.
+func (c *DevChain) blockAt(number int64) (*devBlock, error) {
+	switch number {
+	case ethrpc.BlockNumberLatest, ethrpc.BlockNumberPending:
+		return c.blocks[len(c.blocks)-1], nil
+	case ethrpc.BlockNumberEarliest:
+		return c.blocks[0], nil
+	}
+	if number < 0 || number >= int64(len(c.blocks)) {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+	return c.blocks[number], nil
+}
+
+// stateAt opens the state as of the given block. The caller must hold
+// chainGuard.
+func (c *DevChain) stateAt(number int64) (*state.StateDB, error) {
+	b, err := c.blockAt(number)
+	if err != nil {
+		return nil, err
+	}
+	return state.New(c.roots[b.block.NumberU64()], c.db, nil)
+}
+
+func (c *DevChain) rpcBlock(b *devBlock, fullTx bool) *ethrpc.RPCBlock {
+	txs := b.block.Transactions()
+	transactions := make([]interface{}, len(txs))
+	for i, tx := range txs {
+		if fullTx {
+			transactions[i] = c.rpcTransaction(tx, b.block.NumberU64(), i)
+		} else {
+			transactions[i] = tx.Hash()
+		}
+	}
+	return &ethrpc.RPCBlock{
+		Header:       ethtyp.CopyHeader(b.block.Header()),
+		Transactions: transactions,
+	}
+}
+
+func (c *DevChain) rpcTransaction(tx *ethtyp.Transaction, number uint64, index int) *ethrpc.RPCTransaction {
+	from := c.sender(tx)
+	ti := uint64(index)
+	return &ethrpc.RPCTransaction{
+		Hash:             tx.Hash(),
+		From:             from,
+		To:               tx.To(),
+		Nonce:            tx.Nonce(),
+		Input:            tx.Data(),
+		Gas:              tx.Gas(),
+		GasPrice:         tx.GasPrice(),
+		BlockNumber:      new(big.Int).SetUint64(number),
+		TransactionIndex: &ti,
+		Value:            tx.Value(),
+	}
+}
+
+func (c *DevChain) BlockNumber() (uint64, error) {
+	c.chainGuard.RLock()
+	defer c.chainGuard.RUnlock()
+
+	return uint64(len(c.blocks) - 1), nil
+}
+
+func (c *DevChain) GetBlockByNumber(number int64, fullTx bool) (*ethrpc.RPCBlock, error) {
+	c.chainGuard.RLock()
+	defer c.chainGuard.RUnlock()
+
+	b, err := c.blockAt(number)
+	if err != nil {
+		return nil, err
+	}
+	return c.rpcBlock(b, fullTx), nil
+}
+
+func (c *DevChain) GetBlockByHash(hash ethcmn.Hash, fullTx bool) (*ethrpc.RPCBlock, error) {
+	c.chainGuard.RLock()
+	defer c.chainGuard.RUnlock()
+
+	number, ok := c.hashes[hash]
+	if !ok {
+		return nil, fmt.Errorf("block %x not found", hash)
+	}
+	return c.rpcBlock(c.blocks[number], fullTx), nil
+}
+
+func (c *DevChain) GetCode(address ethcmn.Address, number int64) ([]byte, error) {
+	c.chainGuard.RLock()
+	defer c.chainGuard.RUnlock()
+
+	statedb, err := c.stateAt(number)
+	if err != nil {
+		return nil, err
+	}
+	return statedb.GetCode(address), nil
+}
+
+func (c *DevChain) GetBalance(address ethcmn.Address, number int64) (*big.Int, error) {
+	c.chainGuard.RLock()
+	defer c.chainGuard.RUnlock()
+
+	statedb, err := c.stateAt(number)
+	if err != nil {
+		return nil, err
+	}
+	return statedb.GetBalance(address), nil
+}
+
+func (c *DevChain) GetTransactionCount(address ethcmn.Address, number int64) (uint64, error) {
+	c.chainGuard.RLock()
+	defer c.chainGuard.RUnlock()
+
+	statedb, err := c.stateAt(number)
+	if err != nil {
+		return 0, err
+	}
+	return statedb.GetNonce(address), nil
+}
+
+func (c *DevChain) GetStorageAt(address ethcmn.Address, key string, number int64) ([]byte, error) {
+	c.chainGuard.RLock()
+	defer c.chainGuard.RUnlock()
+
+	statedb, err := c.stateAt(number)
+	if err != nil {
+		return nil, err
+	}
+	value := statedb.GetState(address, ethcmn.HexToHash(key))
+	return value.Bytes(), nil
+}
+
+func (c *DevChain) GetProof(address ethcmn.Address, keys []string, number int64) (*AccountResult, error) {
+	c.chainGuard.RLock()
+	defer c.chainGuard.RUnlock()
+
+	statedb, err := c.stateAt(number)
+	if err != nil {
+		return nil, err
+	}
+	return accountProof(statedb, address, keys)
+}
+
+// EstimateGas binary searches the lowest gas limit the message succeeds with.
+func (c *DevChain) EstimateGas(msg eth.CallMsg) (uint64, error) {
+	hi := msg.Gas
+	if hi == 0 || hi > devGasLimit {
+		hi = devGasLimit
+	}
+	lo := uint64(21000 - 1)
+
+	msg.Gas = hi
+	if _, err := c.Call(msg); err != nil {
+		return 0, err
+	}
+	for lo+1 < hi {
+		mid := (lo + hi) / 2
+		msg.Gas = mid
+		if _, err := c.Call(msg); err != nil {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi, nil
+}
+
+func (c *DevChain) GasPrice() (*big.Int, error) {
+	return big.NewInt(devGasPrice), nil
+}
+
+func (c *DevChain) GetTransactionByHash(hash ethcmn.Hash) (*ethrpc.RPCTransaction, error) {
+	c.chainGuard.RLock()
+	defer c.chainGuard.RUnlock()
+
+	lookup, ok := c.txs[hash]
+	if !ok {
+		return nil, fmt.Errorf("transaction %x not found", hash)
+	}
+	return c.rpcTransaction(lookup.tx, lookup.number, lookup.index), nil
+}
+
+func (c *DevChain) Call(msg eth.CallMsg) ([]byte, error) {
+	c.chainGuard.RLock()
+	defer c.chainGuard.RUnlock()
+
+	statedb, err := c.stateAt(ethrpc.BlockNumberLatest)
+	if err != nil {
+		return nil, err
+	}
+	head := c.blocks[len(c.blocks)-1].block.Header()
+
+	gas := msg.Gas
+	if gas == 0 {
+		gas = devGasLimit
+	}
+	gasPrice := msg.GasPrice
+	if gasPrice == nil {
+		gasPrice = new(big.Int)
+	}
+	value := msg.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	message := ethtyp.NewMessage(msg.From, msg.To, statedb.GetNonce(msg.From), value, gas, gasPrice, msg.Data, msg.AccessList, false)
+
+	evm := vm.NewEVM(c.blockContext(head), core.NewEVMTxContext(message), statedb, c.config, vm.Config{})
+	result, err := core.ApplyMessage(evm, message, new(core.GasPool).AddGas(gas))
+	if err != nil {
+		return nil, err
+	}
+	if result.Err != nil {
+		return result.Revert(), result.Err
+	}
+	return result.Return(), nil
+}
+
+func (c *DevChain) SendRawTransaction(rawTx []byte) (ethcmn.Hash, error) {
+	tx := new(ethtyp.Transaction)
+	if err := ethrlp.DecodeBytes(rawTx, tx); err != nil {
+		return ethcmn.Hash{}, err
+	}
+	if _, err := ethtyp.Sender(c.signer, tx); err != nil {
+		return ethcmn.Hash{}, err
+	}
+
+	c.chainGuard.Lock()
+	defer c.chainGuard.Unlock()
+
+	if c.blockTime > 0 {
+		c.addPending(tx)
+		return tx.Hash(), nil
+	}
+	if _, err := c.mine([]*ethtyp.Transaction{tx}); err != nil {
+		return ethcmn.Hash{}, err
+	}
+	return tx.Hash(), nil
+}
+
+// addPending queues a transaction for the next interval block and announces
+// it to the pending transaction filters.
+func (c *DevChain) addPending(tx *ethtyp.Transaction) {
+	c.pending = append(c.pending, tx)
+	if c.filters != nil {
+		c.filters.OnPendingTransactions([]ethcmn.Hash{tx.Hash()})
+	}
+}
+
+func (c *DevChain) GetTransactionReceipt(hash ethcmn.Hash) (*ethtyp.Receipt, error) {
+	c.chainGuard.RLock()
+	defer c.chainGuard.RUnlock()
+
+	lookup, ok := c.txs[hash]
+	if !ok {
+		return nil, fmt.Errorf("receipt %x not found", hash)
+	}
+	return c.blocks[lookup.number].receipts[lookup.index], nil
+}
+
+func (c *DevChain) GetBlockReceipts(number int64) ([]*BlockReceipt, error) {
+	c.chainGuard.RLock()
+	defer c.chainGuard.RUnlock()
+
+	b, err := c.blockAt(number)
+	if err != nil {
+		return nil, err
+	}
+	return c.blockReceipts(b), nil
+}
+
+func (c *DevChain) GetBlockReceiptsByHash(hash ethcmn.Hash) ([]*BlockReceipt, error) {
+	c.chainGuard.RLock()
+	defer c.chainGuard.RUnlock()
+
+	number, ok := c.hashes[hash]
+	if !ok {
+		return nil, fmt.Errorf("block %x not found", hash)
+	}
+	return c.blockReceipts(c.blocks[number]), nil
+}
+
+func (c *DevChain) blockReceipts(b *devBlock) []*BlockReceipt {
+	txs := b.block.Transactions()
+	receipts := make([]*BlockReceipt, len(b.receipts))
+	for i, receipt := range b.receipts {
+		receipts[i] = &BlockReceipt{
+			Receipt:  receipt,
+			From:     c.sender(txs[i]),
+			To:       txs[i].To(),
+			GasPrice: txs[i].GasPrice(),
+		}
+	}
+	return receipts
+}
+
+func (c *DevChain) GetLogs(filter eth.FilterQuery) ([]*ethtyp.Log, error) {
+	c.chainGuard.RLock()
+	defer c.chainGuard.RUnlock()
+
+	if filter.BlockHash != nil {
+		number, ok := c.hashes[*filter.BlockHash]
+		if !ok {
+			return nil, errors.New("unknown block")
+		}
+		return returnLogs(ethrpc.FilteLogs(c.blockLogs(number), filter)), nil
+	}
+
+	head := uint64(len(c.blocks) - 1)
+	from, to := uint64(0), head
+	if filter.FromBlock != nil && filter.FromBlock.Sign() >= 0 {
+		from = filter.FromBlock.Uint64()
+	}
+	if filter.ToBlock != nil && filter.ToBlock.Sign() >= 0 && filter.ToBlock.Uint64() < head {
+		to = filter.ToBlock.Uint64()
+	}
+
+	var logs []*ethtyp.Log
+	for number := from; number <= to; number++ {
+		logs = append(logs, ethrpc.FilteLogs(c.blockLogs(number), filter)...)
+	}
+	return returnLogs(logs), nil
+}
+
+func (c *DevChain) blockLogs(number uint64) []*ethtyp.Log {
+	var logs []*ethtyp.Log
+	for _, receipt := range c.blocks[number].receipts {
+		logs = append(logs, receipt.Logs...)
+	}
+	return logs
+}
+
+func (c *DevChain) GetBlockTransactionCountByHash(hash ethcmn.Hash) (int, error) {
+	block, err := c.GetBlockByHash(hash, false)
+	if err != nil {
+		return 0, err
+	}
+	return len(block.Transactions), nil
+}
+
+func (c *DevChain) GetBlockTransactionCountByNumber(number int64) (int, error) {
+	block, err := c.GetBlockByNumber(number, false)
+	if err != nil {
+		return 0, err
+	}
+	return len(block.Transactions), nil
+}
+
+func (c *DevChain) GetTransactionByBlockHashAndIndex(hash ethcmn.Hash, index int) (*ethrpc.RPCTransaction, error) {
+	c.chainGuard.RLock()
+	defer c.chainGuard.RUnlock()
+
+	number, ok := c.hashes[hash]
+	if !ok {
+		return nil, fmt.Errorf("block %x not found", hash)
+	}
+	return c.transactionAt(c.blocks[number], index)
+}
+
+func (c *DevChain) GetTransactionByBlockNumberAndIndex(number int64, index int) (*ethrpc.RPCTransaction, error) {
+	c.chainGuard.RLock()
+	defer c.chainGuard.RUnlock()
+
+	b, err := c.blockAt(number)
+	if err != nil {
+		return nil, err
+	}
+	return c.transactionAt(b, index)
+}
+
+func (c *DevChain) transactionAt(b *devBlock, index int) (*ethrpc.RPCTransaction, error) {
+	txs := b.block.Transactions()
+	if index < 0 || index >= len(txs) {
+		return nil, errors.New("transaction index out of range")
+	}
+	return c.rpcTransaction(txs[index], b.block.NumberU64(), index), nil
+}
+
+func (c *DevChain) GetUncleCountByBlockHash(hash ethcmn.Hash) (int, error) {
+	return 0x0, nil
+}
+func (c *DevChain) GetUncleCountByBlockNumber(number int64) (int, error) {
+	return 0x0, nil
+}
+func (c *DevChain) SubmitWork() (bool, error) {
+	return true, nil
+}
+func (c *DevChain) SubmitHashrate() (bool, error) {
+	return true, nil
+}
+func (c *DevChain) Hashrate() (int, error) {
+	return 0x0, nil
+}
+func (c *DevChain) GetWork() ([]string, error) {
+	return []string{}, nil
+}
+func (c *DevChain) ProtocolVersion() (int, error) {
+	return 10000 + 2, nil
+}
+func (c *DevChain) Syncing() (bool, error) {
+	return false, nil
+}
+func (c *DevChain) Proposer() (bool, error) {
+	return true, nil
+}
+
+func (c *DevChain) NewFilter(filter eth.FilterQuery) (ID, error) {
+	return c.filters.NewFilter(filter), nil
+}
+func (c *DevChain) NewBlockFilter() (ID, error) {
+	return c.filters.NewBlockFilter(), nil
+}
+func (c *DevChain) NewPendingTransactionFilter() (ID, error) {
+	return c.filters.NewPendingTransactionFilter(), nil
+}
+func (c *DevChain) UninstallFilter(id ID) (bool, error) {
+	return c.filters.UninstallFilter(id)
+}
+func (c *DevChain) GetFilterChanges(id ID) (interface{}, error) {
+	return c.filters.GetFilterChanges(id)
+}
+func (c *DevChain) GetFilterLogs(id ID) ([]*ethtyp.Log, error) {
+	crit, err := c.filters.GetFilterLogsCrit(id)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetLogs(*crit)
+}
@@ -0,0 +1,227 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/core/state"
+	ethtyp "github.com/arcology-network/evm/core/types"
+)
+
+type devSnapshot struct {
+	blocks        int
+	root          ethcmn.Hash
+	pending       []*ethtyp.Transaction
+	timeOffset    uint64
+	nextTimestamp uint64
+}
+
+// Mine produces a block with the pending transactions. A non-zero timestamp
+// is used as the block time.
+func (c *DevChain) Mine(timestamp uint64) (uint64, error) {
+	c.chainGuard.Lock()
+	defer c.chainGuard.Unlock()
+
+	if timestamp != 0 {
+		c.nextTimestamp = timestamp
+	}
+	txs := c.pending
+	c.pending = nil
+	block, err := c.mine(txs)
+	if block == nil {
+		return 0, err
+	}
+	return block.block.NumberU64(), nil
+}
+
+// Snapshot saves the current chain and returns its id for Revert.
+func (c *DevChain) Snapshot() (string, error) {
+	c.chainGuard.Lock()
+	defer c.chainGuard.Unlock()
+
+	c.snapshots = append(c.snapshots, &devSnapshot{
+		blocks:        len(c.blocks),
+		root:          c.roots[len(c.roots)-1],
+		pending:       append([]*ethtyp.Transaction{}, c.pending...),
+		timeOffset:    c.timeOffset,
+		nextTimestamp: c.nextTimestamp,
+	})
+	return fmt.Sprintf("0x%x", len(c.snapshots)), nil
+}
+
+// Revert rolls the chain back to a snapshot. The snapshot and all the ones
+// taken after it are consumed.
+func (c *DevChain) Revert(id string) (bool, error) {
+	index, err := strconv.ParseUint(strings.TrimPrefix(id, "0x"), 16, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid snapshot id %s", id)
+	}
+
+	c.chainGuard.Lock()
+	defer c.chainGuard.Unlock()
+
+	if index == 0 || index > uint64(len(c.snapshots)) {
+		return false, nil
+	}
+	snapshot := c.snapshots[index-1]
+	statedb, err := state.New(snapshot.root, c.db, nil)
+	if err != nil {
+		return false, err
+	}
+
+	for _, b := range c.blocks[snapshot.blocks:] {
+		delete(c.hashes, b.block.Hash())
+		for _, tx := range b.block.Transactions() {
+			delete(c.txs, tx.Hash())
+			delete(c.senders, tx.Hash())
+		}
+	}
+	c.blocks = c.blocks[:snapshot.blocks]
+	c.roots = c.roots[:snapshot.blocks]
+	c.roots[len(c.roots)-1] = snapshot.root
+	c.statedb = statedb
+	c.pending = snapshot.pending
+	c.timeOffset = snapshot.timeOffset
+	c.nextTimestamp = snapshot.nextTimestamp
+	c.snapshots = c.snapshots[:index-1]
+	return true, nil
+}
+
+// IncreaseTime moves the clock of future blocks forward and returns the
+// total offset in seconds.
+func (c *DevChain) IncreaseTime(seconds uint64) (uint64, error) {
+	c.chainGuard.Lock()
+	defer c.chainGuard.Unlock()
+
+	c.timeOffset += seconds
+	return c.timeOffset, nil
+}
+
+func (c *DevChain) SetNextBlockTimestamp(timestamp uint64) error {
+	c.chainGuard.Lock()
+	defer c.chainGuard.Unlock()
+
+	if timestamp <= c.blocks[len(c.blocks)-1].block.Time() {
+		return errors.New("timestamp must be later than the latest block")
+	}
+	c.nextTimestamp = timestamp
+	return nil
+}
+
+// editState applies a direct state change and makes it visible at the
+// latest block.
+func (c *DevChain) editState(edit func(statedb *state.StateDB)) error {
+	c.chainGuard.Lock()
+	defer c.chainGuard.Unlock()
+
+	edit(c.statedb)
+	root, err := c.statedb.Commit(true)
+	if err != nil {
+		return err
+	}
+	c.roots[len(c.roots)-1] = root
+	return nil
+}
+
+func (c *DevChain) SetBalance(address ethcmn.Address, balance *big.Int) error {
+	return c.editState(func(statedb *state.StateDB) {
+		statedb.SetBalance(address, balance)
+	})
+}
+
+func (c *DevChain) SetCode(address ethcmn.Address, code []byte) error {
+	return c.editState(func(statedb *state.StateDB) {
+		statedb.SetCode(address, code)
+	})
+}
+
+func (c *DevChain) SetStorageAt(address ethcmn.Address, key ethcmn.Hash, value ethcmn.Hash) error {
+	return c.editState(func(statedb *state.StateDB) {
+		statedb.SetState(address, key, value)
+	})
+}
+
+func (c *DevChain) SetNonce(address ethcmn.Address, nonce uint64) error {
+	return c.editState(func(statedb *state.StateDB) {
+		statedb.SetNonce(address, nonce)
+	})
+}
+
+func (c *DevChain) ImpersonateAccount(address ethcmn.Address) error {
+	c.chainGuard.Lock()
+	defer c.chainGuard.Unlock()
+
+	c.impersonated[address] = true
+	return nil
+}
+
+func (c *DevChain) StopImpersonatingAccount(address ethcmn.Address) error {
+	c.chainGuard.Lock()
+	defer c.chainGuard.Unlock()
+
+	delete(c.impersonated, address)
+	return nil
+}
+
+func (c *DevChain) IsImpersonated(address ethcmn.Address) bool {
+	c.chainGuard.RLock()
+	defer c.chainGuard.RUnlock()
+
+	return c.impersonated[address]
+}
+
+// SendImpersonatedTransaction submits an unsigned transaction on behalf of an
+// impersonated account, using the account's current nonce.
+func (c *DevChain) SendImpersonatedTransaction(msg eth.CallMsg) (ethcmn.Hash, error) {
+	c.chainGuard.Lock()
+	defer c.chainGuard.Unlock()
+
+	if !c.impersonated[msg.From] {
+		return ethcmn.Hash{}, fmt.Errorf("account %s is not impersonated", msg.From.Hex())
+	}
+	value := msg.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	gasPrice := msg.GasPrice
+	if gasPrice == nil {
+		gasPrice = big.NewInt(devGasPrice)
+	}
+	gas := msg.Gas
+	if gas == 0 {
+		gas = devGasLimit
+	}
+
+	// Unsigned transactions with the same fields would share a hash, so the
+	// nonce also has to account for ones still waiting to be mined.
+	nonce := c.statedb.GetNonce(msg.From)
+	for _, tx := range c.pending {
+		if from, ok := c.senders[tx.Hash()]; ok && from == msg.From {
+			nonce++
+		}
+	}
+	tx := ethtyp.NewTx(&ethtyp.LegacyTx{
+		Nonce:    nonce,
+		To:       msg.To,
+		Value:    value,
+		Gas:      gas,
+		GasPrice: gasPrice,
+		Data:     msg.Data,
+	})
+	c.senders[tx.Hash()] = msg.From
+
+	if c.blockTime > 0 {
+		c.addPending(tx)
+		return tx.Hash(), nil
+	}
+	if _, err := c.mine([]*ethtyp.Transaction{tx}); err != nil {
+		delete(c.senders, tx.Hash())
+		return ethcmn.Hash{}, err
+	}
+	return tx.Hash(), nil
+}
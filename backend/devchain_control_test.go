@@ -0,0 +1,76 @@
+package backend
+
+import (
+	"math/big"
+	"testing"
+
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+)
+
+func TestDevChainControl(t *testing.T) {
+	chain := NewDevChain(big.NewInt(1), nil, 0, nil)
+	account, to := ethcmn.Address{1}, ethcmn.Address{2}
+	chain.SetBalance(account, big.NewInt(1e18))
+	chain.SetNonce(account, 5)
+	chain.SetCode(to, []byte{0x00})
+	chain.SetStorageAt(to, ethcmn.Hash{1}, ethcmn.Hash{2})
+	if nonce, _ := chain.GetTransactionCount(account, -1); nonce != 5 {
+		t.Fatalf("expected nonce 5, got %d", nonce)
+	}
+	if value, _ := chain.GetStorageAt(to, ethcmn.Hash{1}.Hex(), -1); ethcmn.BytesToHash(value) != (ethcmn.Hash{2}) {
+		t.Fatalf("unexpected storage %x", value)
+	}
+
+	id, err := chain.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chain.SendImpersonatedTransaction(eth.CallMsg{From: account, To: &to}); err == nil {
+		t.Fatal("expected a transaction from an account not impersonated to fail")
+	}
+	chain.ImpersonateAccount(account)
+	hash, err := chain.SendImpersonatedTransaction(eth.CallMsg{From: account, To: &to, Value: big.NewInt(7)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx, _ := chain.GetTransactionByHash(hash); tx == nil || tx.From != account || tx.Nonce != 5 {
+		t.Fatalf("unexpected impersonated transaction %+v", tx)
+	}
+	chain.StopImpersonatingAccount(account)
+	if chain.IsImpersonated(account) {
+		t.Fatal("expected the impersonation to stop")
+	}
+
+	head, _ := chain.BlockNumber()
+	block, _ := chain.GetBlockByNumber(int64(head), false)
+	if err := chain.SetNextBlockTimestamp(block.Header.Time); err == nil {
+		t.Fatal("expected a timestamp not after the head to be rejected")
+	}
+	if _, err := chain.IncreaseTime(100); err != nil {
+		t.Fatal(err)
+	}
+	number, err := chain.Mine(block.Header.Time + 1000)
+	if err != nil || number != head+1 {
+		t.Fatalf("expected block %d, got %d %v", head+1, number, err)
+	}
+	if mined, _ := chain.GetBlockByNumber(int64(number), false); mined.Header.Time != block.Header.Time+1000 {
+		t.Fatalf("expected the given timestamp, got %d", mined.Header.Time)
+	}
+
+	if reverted, err := chain.Revert(id); err != nil || !reverted {
+		t.Fatalf("expected the snapshot to be reverted, %v", err)
+	}
+	if reverted, _ := chain.Revert(id); reverted {
+		t.Fatal("expected a snapshot to be consumed")
+	}
+	if number, _ := chain.BlockNumber(); number != head-1 {
+		t.Fatalf("expected the head back at %d, got %d", head-1, number)
+	}
+	if _, err := chain.GetTransactionByHash(hash); err == nil {
+		t.Fatal("expected the transaction to be rolled back")
+	}
+	if balance, _ := chain.GetBalance(to, -1); balance.Sign() != 0 {
+		t.Fatalf("expected the transfer to be rolled back, got %v", balance)
+	}
+}
@@ -0,0 +1,23 @@
+package backend
+
+import (
+	"github.com/arcology-network/evm/core/state"
+)
+
+// Simulate runs the calls of each simulated block on the state of the given
+// block, every block on the state the previous one left. Without validation
+// nonces are not checked and calls are free unless they name a gas price.
+func (c *DevChain) Simulate(number int64, blocks []*SimBlock, validation bool) ([]*SimBlockResult, error) {
+	c.chainGuard.RLock()
+	defer c.chainGuard.RUnlock()
+
+	b, err := c.blockAt(number)
+	if err != nil {
+		return nil, err
+	}
+	statedb, err := state.New(c.roots[b.block.NumberU64()], c.db, nil)
+	if err != nil {
+		return nil, err
+	}
+	return simulateBlocks(c.config, c.blockContext, statedb, b.block.Header(), blocks, validation)
+}
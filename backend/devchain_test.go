@@ -0,0 +1,92 @@
+package backend
+
+import (
+	"math/big"
+	"testing"
+
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	ethcrp "github.com/arcology-network/evm/crypto"
+	ethrlp "github.com/arcology-network/evm/rlp"
+)
+
+// logCode is init code emitting n LOG1 with topic 1 over 32 bytes of memory.
+func logCode(n int) []byte {
+	code := []byte{0x60, 0x2a, 0x60, 0, 0x52}
+	for i := 0; i < n; i++ {
+		code = append(code, 0x60, 1, 0x60, 0x20, 0x60, 0, 0xa1)
+	}
+	return append(code, 0x00)
+}
+
+func TestDevChainMining(t *testing.T) {
+	key, _ := ethcrp.GenerateKey()
+	sender := ethcrp.PubkeyToAddress(key.PublicKey)
+	chain := NewDevChain(big.NewInt(1), []ethcmn.Address{sender}, 0, nil)
+	signer := ethtyp.NewEIP155Signer(big.NewInt(1))
+
+	send := func(nonce uint64, data []byte) (ethcmn.Hash, error) {
+		tx, err := ethtyp.SignTx(ethtyp.NewContractCreation(nonce, new(big.Int), 100000, big.NewInt(devGasPrice), data), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, err := ethrlp.EncodeToBytes(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return chain.SendRawTransaction(raw)
+	}
+
+	var hashes []ethcmn.Hash
+	for nonce, logs := range []int{1, 2} {
+		hash, err := send(uint64(nonce), logCode(logs))
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, hash)
+	}
+	if _, err := send(0, logCode(1)); err == nil {
+		t.Fatal("expected a reused nonce to be rejected")
+	}
+	if head, _ := chain.BlockNumber(); head != 2 {
+		t.Fatalf("expected a block per transaction, head is %d", head)
+	}
+
+	for i, hash := range hashes {
+		number := uint64(i + 1)
+		receipt, err := chain.GetTransactionReceipt(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, err := chain.GetBlockByNumber(int64(number), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if receipt.Status != ethtyp.ReceiptStatusSuccessful || receipt.BlockNumber.Uint64() != number || receipt.BlockHash != block.Header.Hash() {
+			t.Fatalf("unexpected receipt %+v", receipt)
+		}
+		if receipt.ContractAddress != ethcrp.CreateAddress(sender, uint64(i)) {
+			t.Fatalf("unexpected contract address %x", receipt.ContractAddress)
+		}
+		if len(receipt.Logs) != i+1 {
+			t.Fatalf("expected %d logs, got %d", i+1, len(receipt.Logs))
+		}
+		for index, log := range receipt.Logs {
+			if log.BlockNumber != number || log.BlockHash != receipt.BlockHash || log.TxHash != hash || log.Index != uint(index) {
+				t.Fatalf("unexpected log %+v", log)
+			}
+		}
+	}
+
+	logs, err := chain.GetLogs(eth.FilterQuery{FromBlock: big.NewInt(2), Topics: [][]ethcmn.Hash{{ethcmn.BigToHash(big.NewInt(1))}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 || logs[0].BlockNumber != 2 || logs[1].Index != 1 {
+		t.Fatalf("unexpected logs %+v", logs)
+	}
+	if balance, _ := chain.GetBalance(sender, 0); balance.Cmp(devBalance) != 0 {
+		t.Fatalf("expected the genesis balance at block 0, got %v", balance)
+	}
+}
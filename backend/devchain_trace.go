@@ -0,0 +1,83 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/core"
+	"github.com/arcology-network/evm/core/state"
+	ethtyp "github.com/arcology-network/evm/core/types"
+)
+
+// TraceTransaction replays the block of a transaction up to it on the state
+// of the previous block and traces the transaction itself.
+func (c *DevChain) TraceTransaction(hash ethcmn.Hash, config *TraceConfig) (json.RawMessage, error) {
+	c.chainGuard.RLock()
+	defer c.chainGuard.RUnlock()
+
+	lookup, ok := c.txs[hash]
+	if !ok {
+		return nil, fmt.Errorf("transaction %x not found", hash)
+	}
+	traces, err := c.replayBlock(c.blocks[lookup.number].block, lookup.index, config)
+	if err != nil {
+		return nil, err
+	}
+	return traces[lookup.index].Result, nil
+}
+
+// TraceBlock replays a block on the state of the previous block and traces
+// each of its transactions.
+func (c *DevChain) TraceBlock(number int64, config *TraceConfig) ([]*TxTrace, error) {
+	c.chainGuard.RLock()
+	defer c.chainGuard.RUnlock()
+
+	b, err := c.blockAt(number)
+	if err != nil {
+		return nil, err
+	}
+	if b.block.NumberU64() == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	return c.replayBlock(b.block, -1, config)
+}
+
+// replayBlock applies the transactions of block up to the one at index last,
+// or all of them if last is negative, on the state of the previous block and
+// traces them. Only the transaction at last is traced when it is given.
+func (c *DevChain) replayBlock(block *ethtyp.Block, last int, config *TraceConfig) ([]*TxTrace, error) {
+	statedb, err := state.New(c.roots[block.NumberU64()-1], c.db, nil)
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	msgs, hashes := make([]ethtyp.Message, len(txs)), make([]ethcmn.Hash, len(txs))
+	for i, tx := range txs {
+		if msgs[i], err = c.asMessage(tx); err != nil {
+			return nil, err
+		}
+		hashes[i] = tx.Hash()
+	}
+	return replayMessages(c.config, c.blockContext(block.Header()), statedb, msgs, hashes, last, config)
+}
+
+// TraceCall traces a call on the state of the given block, with the same
+// defaults as Call.
+func (c *DevChain) TraceCall(msg eth.CallMsg, number int64, config *TraceConfig) (json.RawMessage, error) {
+	c.chainGuard.RLock()
+	defer c.chainGuard.RUnlock()
+
+	b, err := c.blockAt(number)
+	if err != nil {
+		return nil, err
+	}
+	statedb, err := state.New(c.roots[b.block.NumberU64()], c.db, nil)
+	if err != nil {
+		return nil, err
+	}
+	message := callAsMessage(msg, statedb.GetNonce(msg.From), devGasLimit)
+	return traceMessage(c.config, c.blockContext(b.block.Header()), statedb, message, new(core.GasPool).AddGas(message.Gas()), config)
+}
@@ -8,19 +8,30 @@ import (
 	"github.com/arcology-network/component-lib/ethrpc"
 	eth "github.com/arcology-network/evm"
 	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/common/hexutil"
 	ethtyp "github.com/arcology-network/evm/core/types"
+	ethcrp "github.com/arcology-network/evm/crypto"
 	ethrlp "github.com/arcology-network/evm/rlp"
 )
 
+// EthereumAPIMock answers with canned values, except for the accounts the
+// test control methods wrote to: those are kept on a dev chain of the mock's
+// own and read back from it.
 type EthereumAPIMock struct {
 	chainID     *big.Int
 	blockHeader *ethtyp.Header
 	blockGuard  sync.RWMutex
+
+	state     *DevChain
+	kept      map[ethcmn.Address]bool // accounts read from state
+	keptGuard sync.RWMutex
 }
 
 func NewEthereumAPIMock(chainID *big.Int) EthereumAPI {
 	return &EthereumAPIMock{
 		chainID: chainID,
+		state:   NewDevChain(chainID, nil, 0, nil),
+		kept:    make(map[ethcmn.Address]bool),
 		blockHeader: &ethtyp.Header{
 			ParentHash: ethcmn.HexToHash("1234567890123456789012345678901234567890123456789012345678901234"),
 			Coinbase:   ethcmn.HexToAddress("0000000000000000000000000000000000000001"),
@@ -65,6 +76,9 @@ func (mock *EthereumAPIMock) GetBlockByHash(hash ethcmn.Hash, fullTx bool) (*eth
 }
 
 func (mock *EthereumAPIMock) GetCode(address ethcmn.Address, number int64) ([]byte, error) {
+	if mock.isKept(address) {
+		return mock.state.GetCode(address, ethrpc.BlockNumberLatest)
+	}
 	if bytes.Equal(address.Bytes(), ethcmn.HexToAddress("0x608060405234801561001057600080fd5b503360").Bytes()) {
 		return []byte{0xff, 0xff}, nil
 	} else if bytes.Equal(address.Bytes(), ethcmn.HexToAddress("0x60c3610025600b82828239805160001a60731461").Bytes()) {
@@ -76,18 +90,42 @@ func (mock *EthereumAPIMock) GetCode(address ethcmn.Address, number int64) ([]by
 }
 
 func (mock *EthereumAPIMock) GetBalance(address ethcmn.Address, number int64) (*big.Int, error) {
+	if mock.isKept(address) {
+		return mock.state.GetBalance(address, ethrpc.BlockNumberLatest)
+	}
 	balance, _ := new(big.Int).SetString("10000000000000000", 0)
 	return balance, nil
 }
 
 func (mock *EthereumAPIMock) GetTransactionCount(address ethcmn.Address, number int64) (uint64, error) {
+	if mock.isKept(address) {
+		return mock.state.GetTransactionCount(address, ethrpc.BlockNumberLatest)
+	}
 	return 0, nil
 }
 
 func (mock *EthereumAPIMock) GetStorageAt(address ethcmn.Address, key string, number int64) ([]byte, error) {
+	if mock.isKept(address) {
+		return mock.state.GetStorageAt(address, key, ethrpc.BlockNumberLatest)
+	}
 	return ethcmn.HexToHash("0x0000000000000000000000000000000000000000000000000000000000000000").Bytes(), nil
 }
 
+func (mock *EthereumAPIMock) GetProof(address ethcmn.Address, keys []string, number int64) (*AccountResult, error) {
+	result := &AccountResult{
+		Address:      address,
+		AccountProof: []string{},
+		Balance:      (*hexutil.Big)(new(big.Int)),
+		CodeHash:     ethcrp.Keccak256Hash(nil),
+		StorageHash:  ethtyp.EmptyRootHash,
+		StorageProof: make([]StorageResult, len(keys)),
+	}
+	for i, key := range keys {
+		result.StorageProof[i] = StorageResult{Key: key, Value: (*hexutil.Big)(new(big.Int)), Proof: []string{}}
+	}
+	return result, nil
+}
+
 func (mock *EthereumAPIMock) EstimateGas(msg eth.CallMsg) (uint64, error) {
 	return 0x1000, nil
 }
@@ -143,6 +181,14 @@ func (mock *EthereumAPIMock) GetTransactionReceipt(hash ethcmn.Hash) (*ethtyp.Re
 	}, nil
 }
 
+func (mock *EthereumAPIMock) GetBlockReceipts(number int64) ([]*BlockReceipt, error) {
+	return []*BlockReceipt{}, nil
+}
+
+func (mock *EthereumAPIMock) GetBlockReceiptsByHash(hash ethcmn.Hash) ([]*BlockReceipt, error) {
+	return []*BlockReceipt{}, nil
+}
+
 func (mock *EthereumAPIMock) GetLogs(filter eth.FilterQuery) ([]*ethtyp.Log, error) {
 	return []*ethtyp.Log{}, nil
 }
@@ -0,0 +1,88 @@
+package backend
+
+import (
+	"math/big"
+
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+)
+
+// The test control methods of the mock act on its dev chain. The accounts
+// they write to, and those an impersonated transaction touches, are read
+// from the chain from then on.
+
+func (mock *EthereumAPIMock) keep(addresses ...ethcmn.Address) {
+	mock.keptGuard.Lock()
+	defer mock.keptGuard.Unlock()
+
+	for _, address := range addresses {
+		mock.kept[address] = true
+	}
+}
+
+func (mock *EthereumAPIMock) isKept(address ethcmn.Address) bool {
+	mock.keptGuard.RLock()
+	defer mock.keptGuard.RUnlock()
+
+	return mock.kept[address]
+}
+
+func (mock *EthereumAPIMock) Mine(timestamp uint64) (uint64, error) {
+	return mock.state.Mine(timestamp)
+}
+
+func (mock *EthereumAPIMock) Snapshot() (string, error) {
+	return mock.state.Snapshot()
+}
+
+func (mock *EthereumAPIMock) Revert(id string) (bool, error) {
+	return mock.state.Revert(id)
+}
+
+func (mock *EthereumAPIMock) IncreaseTime(seconds uint64) (uint64, error) {
+	return mock.state.IncreaseTime(seconds)
+}
+
+func (mock *EthereumAPIMock) SetNextBlockTimestamp(timestamp uint64) error {
+	return mock.state.SetNextBlockTimestamp(timestamp)
+}
+
+func (mock *EthereumAPIMock) SetBalance(address ethcmn.Address, balance *big.Int) error {
+	mock.keep(address)
+	return mock.state.SetBalance(address, balance)
+}
+
+func (mock *EthereumAPIMock) SetCode(address ethcmn.Address, code []byte) error {
+	mock.keep(address)
+	return mock.state.SetCode(address, code)
+}
+
+func (mock *EthereumAPIMock) SetStorageAt(address ethcmn.Address, key ethcmn.Hash, value ethcmn.Hash) error {
+	mock.keep(address)
+	return mock.state.SetStorageAt(address, key, value)
+}
+
+func (mock *EthereumAPIMock) SetNonce(address ethcmn.Address, nonce uint64) error {
+	mock.keep(address)
+	return mock.state.SetNonce(address, nonce)
+}
+
+func (mock *EthereumAPIMock) ImpersonateAccount(address ethcmn.Address) error {
+	return mock.state.ImpersonateAccount(address)
+}
+
+func (mock *EthereumAPIMock) StopImpersonatingAccount(address ethcmn.Address) error {
+	return mock.state.StopImpersonatingAccount(address)
+}
+
+func (mock *EthereumAPIMock) IsImpersonated(address ethcmn.Address) bool {
+	return mock.state.IsImpersonated(address)
+}
+
+func (mock *EthereumAPIMock) SendImpersonatedTransaction(msg eth.CallMsg) (ethcmn.Hash, error) {
+	mock.keep(msg.From)
+	if msg.To != nil {
+		mock.keep(*msg.To)
+	}
+	return mock.state.SendImpersonatedTransaction(msg)
+}
@@ -0,0 +1,44 @@
+package backend
+
+import (
+	"math/big"
+	"testing"
+
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+)
+
+func TestEthereumAPIMockControl(t *testing.T) {
+	mock := NewEthereumAPIMock(big.NewInt(1)).(*EthereumAPIMock)
+	var _ DevControl = mock
+	sender, to, untouched := ethcmn.Address{1}, ethcmn.Address{2}, ethcmn.Address{3}
+
+	canned, _ := mock.GetBalance(untouched, -1)
+	if err := mock.SetBalance(sender, big.NewInt(1e18)); err != nil {
+		t.Fatal(err)
+	}
+	if balance, _ := mock.GetBalance(sender, -1); balance.Cmp(big.NewInt(1e18)) != 0 {
+		t.Fatalf("expected the balance set, got %v", balance)
+	}
+	if balance, _ := mock.GetBalance(untouched, -1); balance.Cmp(canned) != 0 {
+		t.Fatalf("expected the canned balance of an untouched account, got %v", balance)
+	}
+
+	id, err := mock.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ImpersonateAccount(sender)
+	if _, err := mock.SendImpersonatedTransaction(eth.CallMsg{From: sender, To: &to, Value: big.NewInt(7)}); err != nil {
+		t.Fatal(err)
+	}
+	if balance, _ := mock.GetBalance(to, -1); balance.Int64() != 7 {
+		t.Fatalf("expected the transfer to be kept, got %v", balance)
+	}
+	if reverted, err := mock.Revert(id); err != nil || !reverted {
+		t.Fatalf("expected the snapshot to be reverted, %v", err)
+	}
+	if balance, _ := mock.GetBalance(to, -1); balance.Sign() != 0 {
+		t.Fatalf("expected the transfer to be rolled back, got %v", balance)
+	}
+}
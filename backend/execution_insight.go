@@ -0,0 +1,162 @@
+package backend
+
+import (
+	"sync"
+
+	ethcmn "github.com/arcology-network/evm/common"
+)
+
+// Reasons a transaction did not land as executed.
+const (
+	ExecutionConflict = "conflict"
+	ExecutionReverted = "reverted"
+)
+
+// ExecutionBatch is a group of transactions whose receipts an executor
+// published together in one round of a block. It is not the executing
+// sequence the scheduler put them in: the sequence layout is never published
+// to this service, so the rounds of receipts are the nearest we see of it.
+type ExecutionBatch struct {
+	Round uint64
+	Txs   []ethcmn.Hash
+}
+
+// TxExecution is how a transaction fared in the parallel execution of its
+// block. Batches indexes BlockExecution.Batches, more than one means it was
+// re-executed. Included is true unless the inclusive list dropped it.
+type TxExecution struct {
+	Hash     ethcmn.Hash
+	Batches  []int
+	Status   uint64
+	Included bool
+	Reason   string
+}
+
+// BlockExecution is the execution metadata of a block, as seen on the
+// receipts and the inclusive list published for it.
+type BlockExecution struct {
+	Height  uint64
+	Batches []*ExecutionBatch
+	Txs     []*TxExecution
+
+	txs map[ethcmn.Hash]*TxExecution
+}
+
+func NewBlockExecution(height uint64) *BlockExecution {
+	return &BlockExecution{Height: height, txs: make(map[ethcmn.Hash]*TxExecution)}
+}
+
+func (b *BlockExecution) tx(hash ethcmn.Hash) *TxExecution {
+	tx, ok := b.txs[hash]
+	if !ok {
+		tx = &TxExecution{Hash: hash, Included: true}
+		b.txs[hash] = tx
+		b.Txs = append(b.Txs, tx)
+	}
+	return tx
+}
+
+// AddBatch records a batch of receipts, statuses[i] being the status of
+// hashes[i].
+func (b *BlockExecution) AddBatch(round uint64, hashes []ethcmn.Hash, statuses []uint64) {
+	batch := len(b.Batches)
+	b.Batches = append(b.Batches, &ExecutionBatch{Round: round, Txs: hashes})
+	for i, hash := range hashes {
+		tx := b.tx(hash)
+		tx.Batches = append(tx.Batches, batch)
+		tx.Status = statuses[i]
+	}
+}
+
+// SetInclusive records which transactions the arbitration kept, a hash
+// without a flag is taken as kept.
+func (b *BlockExecution) SetInclusive(hashes []ethcmn.Hash, included []bool) {
+	for i, hash := range hashes {
+		tx := b.tx(hash)
+		if i < len(included) {
+			tx.Included = included[i]
+		}
+	}
+}
+
+// Finish explains the transactions that did not land as executed.
+func (b *BlockExecution) Finish() {
+	for _, tx := range b.Txs {
+		switch {
+		case !tx.Included:
+			tx.Reason = ExecutionConflict
+		case tx.Status == 0:
+			tx.Reason = ExecutionReverted
+		default:
+			tx.Reason = ""
+		}
+	}
+}
+
+// ExecutionInsights keeps the execution metadata of the latest blocks.
+type ExecutionInsights struct {
+	mu      sync.RWMutex
+	horizon uint64
+	blocks  map[uint64]*BlockExecution
+	txs     map[ethcmn.Hash]uint64
+}
+
+func NewExecutionInsights(horizon uint64) *ExecutionInsights {
+	return &ExecutionInsights{
+		horizon: horizon,
+		blocks:  make(map[uint64]*BlockExecution),
+		txs:     make(map[ethcmn.Hash]uint64),
+	}
+}
+
+// Add keeps a finished block and forgets the blocks past the horizon.
+func (e *ExecutionInsights) Add(block *BlockExecution) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if old, ok := e.blocks[block.Height]; ok {
+		e.forget(old)
+	}
+	e.blocks[block.Height] = block
+	for _, tx := range block.Txs {
+		e.txs[tx.Hash] = block.Height
+	}
+	if block.Height < e.horizon {
+		return
+	}
+	for height, old := range e.blocks {
+		if height <= block.Height-e.horizon {
+			e.forget(old)
+		}
+	}
+}
+
+func (e *ExecutionInsights) forget(block *BlockExecution) {
+	delete(e.blocks, block.Height)
+	for _, tx := range block.Txs {
+		if e.txs[tx.Hash] == block.Height {
+			delete(e.txs, tx.Hash)
+		}
+	}
+}
+
+// Block returns the execution of a block, nil if it is not kept.
+func (e *ExecutionInsights) Block(height uint64) *BlockExecution {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.blocks[height]
+}
+
+// Transaction returns the execution of a transaction and its block, nil if
+// the block is not kept.
+func (e *ExecutionInsights) Transaction(hash ethcmn.Hash) (*BlockExecution, *TxExecution) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	height, ok := e.txs[hash]
+	if !ok {
+		return nil, nil
+	}
+	block := e.blocks[height]
+	return block, block.txs[hash]
+}
@@ -0,0 +1,50 @@
+package backend
+
+import (
+	"testing"
+
+	ethcmn "github.com/arcology-network/evm/common"
+)
+
+func TestExecutionInsights(t *testing.T) {
+	insights := NewExecutionInsights(2)
+	a, b, c := ethcmn.Hash{1}, ethcmn.Hash{2}, ethcmn.Hash{3}
+	for height := uint64(1); height <= 3; height++ {
+		block := NewBlockExecution(height)
+		block.AddBatch(0, []ethcmn.Hash{a, b}, []uint64{1, 1})
+		block.AddBatch(1, []ethcmn.Hash{b, c}, []uint64{1, 0})
+		block.SetInclusive([]ethcmn.Hash{a, b, c}, []bool{false, true, true})
+		block.Finish()
+		insights.Add(block)
+		a, b, c = ethcmn.Hash{a[0] + 3}, ethcmn.Hash{b[0] + 3}, ethcmn.Hash{c[0] + 3}
+	}
+
+	if insights.Block(1) != nil {
+		t.Fatal("expected block 1 to be past the horizon")
+	}
+	if block, tx := insights.Transaction(ethcmn.Hash{1}); block != nil || tx != nil {
+		t.Fatal("expected the transactions of block 1 to be forgotten")
+	}
+	block := insights.Block(3)
+	if block == nil || len(block.Batches) != 2 || len(block.Txs) != 3 {
+		t.Fatalf("unexpected block 3 %+v", block)
+	}
+	reasons := []string{ExecutionConflict, "", ExecutionReverted}
+	for i, tx := range block.Txs {
+		if tx.Reason != reasons[i] {
+			t.Errorf("tx %d: expected reason %q, got %q", i, reasons[i], tx.Reason)
+		}
+	}
+	if _, tx := insights.Transaction(ethcmn.Hash{8}); tx == nil || len(tx.Batches) != 2 || !tx.Included {
+		t.Fatalf("expected the re-executed transaction of block 3, got %+v", tx)
+	}
+
+	// an inclusive list short of flags keeps the transactions it says nothing of
+	block = NewBlockExecution(4)
+	block.AddBatch(0, []ethcmn.Hash{{0x10}, {0x11}}, []uint64{1, 1})
+	block.SetInclusive([]ethcmn.Hash{{0x10}, {0x11}}, []bool{false})
+	block.Finish()
+	if !block.Txs[1].Included || block.Txs[1].Reason != "" || block.Txs[0].Reason != ExecutionConflict {
+		t.Fatalf("unexpected block 4 %+v %+v", block.Txs[0], block.Txs[1])
+	}
+}
@@ -0,0 +1,165 @@
+package backend
+
+import (
+	"math/big"
+
+	ethcmn "github.com/arcology-network/evm/common"
+	ethtyp "github.com/arcology-network/evm/core/types"
+)
+
+// filterIndex files every log filter under its most selective criterion, so
+// that a log is only matched against the filters that can possibly want it.
+// A filter with addresses is filed under each of them, otherwise under each
+// of its topic0 values, otherwise it is a wildcard.
+type filterIndex struct {
+	byAddress map[ethcmn.Address]map[ID]*Filter
+	byTopic0  map[ethcmn.Hash]map[ID]*Filter
+	wildcard  map[ID]*Filter
+	hashes    map[ID]*Filter // block filters
+	pending   map[ID]*Filter // pending transaction filters
+}
+
+func newFilterIndex() *filterIndex {
+	return &filterIndex{
+		byAddress: make(map[ethcmn.Address]map[ID]*Filter),
+		byTopic0:  make(map[ethcmn.Hash]map[ID]*Filter),
+		wildcard:  make(map[ID]*Filter),
+		hashes:    make(map[ID]*Filter),
+		pending:   make(map[ID]*Filter),
+	}
+}
+
+func (idx *filterIndex) add(id ID, f *Filter) {
+	switch f.Typ {
+	case FilterTypeBlock:
+		idx.hashes[id] = f
+	case FilterTypePendingTransaction:
+		idx.pending[id] = f
+	case FilterTypeLogs:
+		if len(f.Crit.Addresses) > 0 {
+			for _, address := range f.Crit.Addresses {
+				if idx.byAddress[address] == nil {
+					idx.byAddress[address] = make(map[ID]*Filter)
+				}
+				idx.byAddress[address][id] = f
+			}
+		} else if len(f.Crit.Topics) > 0 && len(f.Crit.Topics[0]) > 0 {
+			for _, topic := range f.Crit.Topics[0] {
+				if idx.byTopic0[topic] == nil {
+					idx.byTopic0[topic] = make(map[ID]*Filter)
+				}
+				idx.byTopic0[topic][id] = f
+			}
+		} else {
+			idx.wildcard[id] = f
+		}
+	}
+}
+
+func (idx *filterIndex) remove(id ID, f *Filter) {
+	delete(idx.hashes, id)
+	delete(idx.pending, id)
+	delete(idx.wildcard, id)
+	if f.Typ != FilterTypeLogs {
+		return
+	}
+	for _, address := range f.Crit.Addresses {
+		if set := idx.byAddress[address]; set != nil {
+			delete(set, id)
+			if len(set) == 0 {
+				delete(idx.byAddress, address)
+			}
+		}
+	}
+	if len(f.Crit.Topics) > 0 {
+		for _, topic := range f.Crit.Topics[0] {
+			if set := idx.byTopic0[topic]; set != nil {
+				delete(set, id)
+				if len(set) == 0 {
+					delete(idx.byTopic0, topic)
+				}
+			}
+		}
+	}
+}
+
+// match returns the logs of one block grouped by the filters they belong to,
+// each group in block order.
+func (idx *filterIndex) match(height uint64, blockhash ethcmn.Hash, logs []*ethtyp.Log) map[*Filter][]*ethtyp.Log {
+	matched := make(map[*Filter][]*ethtyp.Log)
+	inRange := make(map[*Filter]bool)
+	deliver := func(set map[ID]*Filter, log *ethtyp.Log) {
+		for _, f := range set {
+			ok, checked := inRange[f]
+			if !checked {
+				ok = f.coversBlock(height, blockhash)
+				inRange[f] = ok
+			}
+			if ok && f.matchesLog(log) {
+				matched[f] = append(matched[f], log)
+			}
+		}
+	}
+
+	for _, log := range logs {
+		deliver(idx.byAddress[log.Address], log)
+		if len(log.Topics) > 0 {
+			deliver(idx.byTopic0[log.Topics[0]], log)
+		}
+		deliver(idx.wildcard, log)
+	}
+	return matched
+}
+
+// resolveBound returns the block a range bound stands for. An open bound and
+// the latest or pending tags stand for the latest block.
+func resolveBound(bound *big.Int, latest uint64) uint64 {
+	if bound == nil || bound.Sign() < 0 {
+		return latest
+	}
+	return bound.Uint64()
+}
+
+// coversBlock reports whether the block lies within the filter's range, tags
+// are resolved relative to the arriving block.
+func (f *Filter) coversBlock(height uint64, blockhash ethcmn.Hash) bool {
+	if f.Crit.BlockHash != nil {
+		return *f.Crit.BlockHash == blockhash
+	}
+	return resolveBound(f.Crit.FromBlock, height) <= height && height <= resolveBound(f.Crit.ToBlock, height)
+}
+
+// matchesLog checks the address and topic criteria of a log filter.
+func (f *Filter) matchesLog(log *ethtyp.Log) bool {
+	if len(f.Crit.Addresses) > 0 {
+		found := false
+		for _, address := range f.Crit.Addresses {
+			if address == log.Address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.Crit.Topics) > len(log.Topics) {
+		return false
+	}
+	for i, sub := range f.Crit.Topics {
+		if len(sub) == 0 {
+			continue
+		}
+		found := false
+		for _, topic := range sub {
+			if topic == log.Topics[i] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
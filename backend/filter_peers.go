@@ -0,0 +1,87 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var peerClient = &http.Client{Timeout: 5 * time.Second}
+
+// FilterHopHeader marks a filter call forwarded by a peer. The instance
+// receiving it answers from its own filters and never forwards it again, so
+// peers configured inconsistently cannot bounce a call between them.
+const FilterHopHeader = "X-Filter-Hop"
+
+// FilterClientHeader carries the identity of the client a peer forwards a
+// filter call for.
+const FilterClientHeader = "X-Filter-Client"
+
+// SetPeers configures the rpc urls of the replicated instances, indexed by
+// their instance hint. Calls for filters issued by another instance are
+// forwarded to it.
+func (fs *Filters) SetPeers(peers []string) {
+	fs.filtersMu.Lock()
+	defer fs.filtersMu.Unlock()
+
+	fs.peers = peers
+}
+
+// Forward sends a filter call on behalf of client to the instance that
+// issued the filter. It reports false if the filter is not owned by a known
+// peer.
+func (fs *Filters) Forward(id ID, method, client string, result interface{}) (bool, error) {
+	hint, ok := InstanceOf(id)
+	if !ok || hint == instanceHint {
+		return false, nil
+	}
+	fs.filtersMu.Lock()
+	var url string
+	if int(hint) < len(fs.peers) {
+		url = fs.peers[hint]
+	}
+	fs.filtersMu.Unlock()
+	if url == "" {
+		return false, nil
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  method,
+		"params":  []interface{}{id},
+	})
+	if err != nil {
+		return true, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return true, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(FilterHopHeader, "1")
+	if client != "" {
+		req.Header.Set(FilterClientHeader, client)
+	}
+	resp, err := peerClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	var reply struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return true, err
+	}
+	if reply.Error != nil {
+		return true, errors.New(reply.Error.Message)
+	}
+	return true, json.Unmarshal(reply.Result, result)
+}
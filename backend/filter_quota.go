@@ -0,0 +1,181 @@
+package backend
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/common/hexutil"
+)
+
+// FilterQuotas bounds what a single client, identified by api key or remote
+// ip, may ask of the filters. Zero values mean unlimited.
+type FilterQuotas struct {
+	MaxFilters   int    // installed filters per client
+	MaxAddresses int    // addresses of a log filter
+	MaxTopics    int    // topics of a log filter, summed over all positions
+	MaxLogRange  uint64 // blocks covered by eth_getFilterLogs
+}
+
+// ErrQuotaExceeded is wrapped by the errors of requests over a client quota.
+type ErrQuotaExceeded struct {
+	msg string
+}
+
+func (e *ErrQuotaExceeded) Error() string { return e.msg }
+
+func quotaExceeded(format string, args ...interface{}) error {
+	return &ErrQuotaExceeded{msg: fmt.Sprintf(format, args...)}
+}
+
+// SetQuotas sets the per client quotas checked by Admit and CheckLogRange.
+func (fs *Filters) SetQuotas(quotas FilterQuotas) {
+	fs.filtersMu.Lock()
+	defer fs.filtersMu.Unlock()
+
+	fs.quotas = quotas
+}
+
+// Admit reserves a filter for owner if it is within its quotas, crit is nil
+// for block and pending transaction filters. The reservation is bound to the
+// new filter with SetOwner, or given back with Release if it could not be
+// created.
+func (fs *Filters) Admit(owner string, crit *eth.FilterQuery) error {
+	fs.filtersMu.Lock()
+	defer fs.filtersMu.Unlock()
+
+	quotas := fs.quotas
+	if quotas.MaxFilters > 0 && fs.owned[owner] >= quotas.MaxFilters {
+		return quotaExceeded("too many filters, at most %d may be installed", quotas.MaxFilters)
+	}
+	if crit != nil {
+		if quotas.MaxAddresses > 0 && len(crit.Addresses) > quotas.MaxAddresses {
+			return quotaExceeded("too many addresses in filter, at most %d are allowed", quotas.MaxAddresses)
+		}
+		topics := 0
+		for _, sub := range crit.Topics {
+			topics += len(sub)
+		}
+		if quotas.MaxTopics > 0 && topics > quotas.MaxTopics {
+			return quotaExceeded("too many topics in filter, at most %d are allowed", quotas.MaxTopics)
+		}
+	}
+	fs.owned[owner]++
+	return nil
+}
+
+// Release gives back a reservation made by Admit.
+func (fs *Filters) Release(owner string) {
+	fs.filtersMu.Lock()
+	defer fs.filtersMu.Unlock()
+
+	fs.release(owner)
+}
+
+func (fs *Filters) release(owner string) {
+	if fs.owned[owner] <= 1 {
+		delete(fs.owned, owner)
+	} else {
+		fs.owned[owner]--
+	}
+}
+
+// SetOwner binds a reservation made by Admit to the filter created for it.
+func (fs *Filters) SetOwner(id ID, owner string) {
+	fs.filtersMu.Lock()
+	defer fs.filtersMu.Unlock()
+
+	f, found := fs.filters[id]
+	if !found || f.owner != "" {
+		// the filter lives elsewhere, the reservation cannot be tracked
+		fs.release(owner)
+		return
+	}
+	f.owner = owner
+	fs.persist(id, f)
+}
+
+// CheckLogRange checks the blocks a eth_getFilterLogs call would scan against
+// the quota, open ends are taken as the head.
+func (fs *Filters) CheckLogRange(crit *eth.FilterQuery, head uint64) error {
+	fs.filtersMu.Lock()
+	maxRange := fs.quotas.MaxLogRange
+	fs.filtersMu.Unlock()
+
+	if maxRange == 0 || crit.BlockHash != nil {
+		return nil
+	}
+	from, to := head, head
+	if crit.FromBlock != nil && crit.FromBlock.Sign() >= 0 {
+		from = crit.FromBlock.Uint64()
+	}
+	if crit.ToBlock != nil && crit.ToBlock.Sign() >= 0 {
+		to = crit.ToBlock.Uint64()
+	}
+	if to > from && to-from+1 > maxRange {
+		return quotaExceeded("block range too large, at most %d blocks are allowed", maxRange)
+	}
+	return nil
+}
+
+// FilterCriteria is the rpc form of a log filter's criteria.
+type FilterCriteria struct {
+	BlockHash *ethcmn.Hash     `json:"blockHash,omitempty"`
+	FromBlock *hexutil.Big     `json:"fromBlock,omitempty"`
+	ToBlock   *hexutil.Big     `json:"toBlock,omitempty"`
+	Addresses []ethcmn.Address `json:"address,omitempty"`
+	Topics    [][]ethcmn.Hash  `json:"topics,omitempty"`
+}
+
+// FilterInfo describes an installed filter for the admin api.
+type FilterInfo struct {
+	ID             ID              `json:"id"`
+	Owner          string          `json:"owner"`
+	Type           string          `json:"type"`
+	Criteria       *FilterCriteria `json:"criteria,omitempty"`
+	BufferedLogs   int             `json:"bufferedLogs"`
+	BufferedHashes int             `json:"bufferedHashes"`
+	BufferedBytes  int             `json:"bufferedBytes"`
+	LastPoll       time.Time       `json:"lastPoll"`
+}
+
+var filterTypeNames = map[byte]string{
+	FilterTypeLogs:               "logs",
+	FilterTypeBlock:              "block",
+	FilterTypePendingTransaction: "pendingTransaction",
+}
+
+// List describes the filters installed on this instance, ordered by id.
+func (fs *Filters) List() []*FilterInfo {
+	fs.filtersMu.Lock()
+	defer fs.filtersMu.Unlock()
+
+	infos := make([]*FilterInfo, 0, len(fs.filters))
+	for id, f := range fs.filters {
+		f.lock.Lock()
+		info := &FilterInfo{
+			ID:             id,
+			Owner:          f.owner,
+			Type:           filterTypeNames[f.Typ],
+			BufferedLogs:   len(f.Logs),
+			BufferedHashes: len(f.Hashes),
+			BufferedBytes:  f.bytes,
+			LastPoll:       f.lastPoll,
+		}
+		f.lock.Unlock()
+		if f.Typ == FilterTypeLogs {
+			info.Criteria = &FilterCriteria{
+				BlockHash: f.Crit.BlockHash,
+				FromBlock: (*hexutil.Big)(f.Crit.FromBlock),
+				ToBlock:   (*hexutil.Big)(f.Crit.ToBlock),
+				Addresses: f.Crit.Addresses,
+				Topics:    f.Crit.Topics,
+			}
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
@@ -0,0 +1,93 @@
+package backend
+
+import (
+	ethcmn "github.com/arcology-network/evm/common"
+	ethtyp "github.com/arcology-network/evm/core/types"
+)
+
+// reorgDepth is how many delivered blocks are kept to undo a reorganization.
+const reorgDepth = 64
+
+type deliveredBlock struct {
+	height uint64
+	hash   ethcmn.Hash
+	parent ethcmn.Hash
+	logs   []*ethtyp.Log
+}
+
+// rewind pops the delivered blocks that a new block at height replaces,
+// newest first: those at or above its height and, when the parent is one of
+// the delivered blocks, those above the parent. A zero parent is treated as
+// unknown, and so is a parent that was never delivered, which leaves a gap
+// rather than reverting every head. The caller must hold filtersMu.
+func (fs *Filters) rewind(height uint64, parent ethcmn.Hash) []*deliveredBlock {
+	known := false
+	if parent != (ethcmn.Hash{}) {
+		for _, head := range fs.heads {
+			if head.hash == parent {
+				known = true
+				break
+			}
+		}
+	}
+
+	var removed []*deliveredBlock
+	for len(fs.heads) > 0 {
+		head := fs.heads[len(fs.heads)-1]
+		if head.height < height && (!known || head.hash == parent) {
+			break
+		}
+		removed = append(removed, head)
+		fs.heads = fs.heads[:len(fs.heads)-1]
+	}
+	return removed
+}
+
+// revertBlocks delivers the logs of blocks dropped by a reorganization again,
+// marked as removed, and takes their hashes out of the block filters that
+// have not been polled yet. The caller must hold filtersMu.
+func (fs *Filters) revertBlocks(blocks []*deliveredBlock) {
+	dropped := make(map[ethcmn.Hash]bool, len(blocks))
+	for _, b := range blocks {
+		dropped[b.hash] = true
+
+		logs := make([]*ethtyp.Log, len(b.logs))
+		for i, log := range b.logs {
+			removed := *log
+			removed.Removed = true
+			logs[i] = &removed
+		}
+		for f, matched := range fs.index.match(b.height, b.hash, logs) {
+			f.appendLogs(matched)
+		}
+	}
+	for _, f := range fs.index.hashes {
+		f.dropHashes(dropped)
+	}
+}
+
+// pushHead records a delivered block for reorganization handling. The caller
+// must hold filtersMu.
+func (fs *Filters) pushHead(b *deliveredBlock) {
+	if len(fs.heads) == reorgDepth {
+		fs.heads[0] = nil
+		fs.heads = fs.heads[1:]
+	}
+	fs.heads = append(fs.heads, b)
+}
+
+// dropHashes removes buffered block hashes that are no longer canonical.
+func (f *Filter) dropHashes(dropped map[ethcmn.Hash]bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	kept := f.Hashes[:0]
+	for _, hash := range f.Hashes {
+		if dropped[hash] {
+			f.release(hashSize)
+			continue
+		}
+		kept = append(kept, hash)
+	}
+	f.Hashes = kept
+}
@@ -0,0 +1,232 @@
+package backend
+
+import (
+	"encoding/json"
+	"math/big"
+	"time"
+
+	eth "github.com/arcology-network/evm"
+	"github.com/arcology-network/evm/ethdb"
+	"go.uber.org/zap"
+)
+
+var filterKeyPrefix = []byte("filter-")
+
+// filterRecord is what is kept of a filter across restarts: its definition
+// and the height of the last block the client has polled.
+type filterRecord struct {
+	Typ    byte            `json:"type"`
+	Crit   eth.FilterQuery `json:"crit"`
+	Polled uint64          `json:"polled"`
+	Owner  string          `json:"owner,omitempty"`
+}
+
+// FilterState keeps filter definitions outside the process memory, so that
+// they survive restarts or are seen by every replicated instance.
+type FilterState interface {
+	Put(key, value []byte) error
+	Get(key []byte) ([]byte, error) // nil without an error for missing keys
+	Delete(key []byte) error
+	Keys(prefix []byte) ([][]byte, error)
+}
+
+type dbFilterState struct {
+	db ethdb.KeyValueStore
+}
+
+// NewDatabaseFilterState keeps the filter state in a local key value store.
+func NewDatabaseFilterState(db ethdb.KeyValueStore) FilterState {
+	return &dbFilterState{db: db}
+}
+
+func (s *dbFilterState) Put(key, value []byte) error {
+	return s.db.Put(key, value)
+}
+
+func (s *dbFilterState) Get(key []byte) ([]byte, error) {
+	if ok, err := s.db.Has(key); !ok || err != nil {
+		return nil, err
+	}
+	return s.db.Get(key)
+}
+
+func (s *dbFilterState) Delete(key []byte) error {
+	return s.db.Delete(key)
+}
+
+func (s *dbFilterState) Keys(prefix []byte) ([][]byte, error) {
+	var keys [][]byte
+	it := s.db.NewIterator(prefix, nil)
+	defer it.Release()
+	for it.Next() {
+		keys = append(keys, append([]byte{}, it.Key()...))
+	}
+	return keys, it.Error()
+}
+
+type filterStore struct {
+	state FilterState
+}
+
+func filterKey(id ID) []byte {
+	return append(append([]byte{}, filterKeyPrefix...), id...)
+}
+
+func (s *filterStore) put(id ID, f *Filter) error {
+	data, err := json.Marshal(&filterRecord{
+		Typ:    f.Typ,
+		Crit:   f.Crit,
+		Polled: f.polled,
+		Owner:  f.owner,
+	})
+	if err != nil {
+		return err
+	}
+	return s.state.Put(filterKey(id), data)
+}
+
+func (s *filterStore) delete(id ID) error {
+	return s.state.Delete(filterKey(id))
+}
+
+// get returns the record of a filter, nil if there is none.
+func (s *filterStore) get(id ID) (*filterRecord, error) {
+	data, err := s.state.Get(filterKey(id))
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var rec filterRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// load returns the records of the filters issued by this instance.
+func (s *filterStore) load() (map[ID]*filterRecord, error) {
+	keys, err := s.state.Keys(filterKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[ID]*filterRecord)
+	for _, key := range keys {
+		id := ID(key[len(filterKeyPrefix):])
+		if hint, ok := InstanceOf(id); !ok || hint != instanceHint {
+			continue
+		}
+		rec, err := s.get(id)
+		if err != nil {
+			return nil, err
+		}
+		if rec != nil {
+			records[id] = rec
+		}
+	}
+	return records, nil
+}
+
+// Restore reinstalls the filters this instance saved in state under their
+// old ids and keeps every later filter change there. Changes the clients
+// missed while the service was down are backfilled from the backend. Filters
+// that fell more than horizon blocks behind the head are dropped, their
+// clients get "filter not found" and have to create new ones.
+func (fs *Filters) Restore(state FilterState, backend EthereumAPI, horizon uint64) error {
+	store := &filterStore{state: state}
+	records, err := store.load()
+	if err != nil {
+		return err
+	}
+	head, err := backend.BlockNumber()
+	if err != nil {
+		return err
+	}
+
+	restored := make(map[ID]*Filter, len(records))
+	for id, rec := range records {
+		if rec.Polled > head || head-rec.Polled > horizon {
+			if err := store.delete(id); err != nil {
+				return err
+			}
+			continue
+		}
+		f := &Filter{
+			Typ:      rec.Typ,
+			Crit:     rec.Crit,
+			Deadline: time.NewTimer(fs.timeout),
+			fs:       fs,
+			polled:   rec.Polled,
+			owner:    rec.Owner,
+			lastPoll: time.Now(),
+		}
+		if err := f.backfill(backend, rec.Polled+1, head); err != nil {
+			return err
+		}
+		restored[id] = f
+	}
+
+	fs.filtersMu.Lock()
+	defer fs.filtersMu.Unlock()
+
+	fs.store = store
+	if head > fs.head {
+		fs.head = head
+	}
+	for id, f := range restored {
+		fs.filters[id] = f
+		fs.index.add(id, f)
+		if f.owner != "" {
+			fs.owned[f.owner]++
+		}
+	}
+	fs.enforceBudget()
+	return nil
+}
+
+// backfill buffers the changes of the blocks from..to.
+func (f *Filter) backfill(backend EthereumAPI, from, to uint64) error {
+	switch f.Typ {
+	case FilterTypeBlock:
+		for number := from; number <= to; number++ {
+			block, err := backend.GetBlockByNumber(int64(number), false)
+			if err != nil {
+				return err
+			}
+			f.appendHashes(block.Header.Hash())
+		}
+	case FilterTypeLogs:
+		if f.Crit.BlockHash != nil {
+			return nil
+		}
+		if bound := resolveBound(f.Crit.FromBlock, from); bound > from {
+			from = bound
+		}
+		if bound := resolveBound(f.Crit.ToBlock, to); bound < to {
+			to = bound
+		}
+		if from > to {
+			return nil
+		}
+		logs, err := backend.GetLogs(eth.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(from),
+			ToBlock:   new(big.Int).SetUint64(to),
+			Addresses: f.Crit.Addresses,
+			Topics:    f.Crit.Topics,
+		})
+		if err != nil {
+			return err
+		}
+		f.appendLogs(logs)
+	}
+	return nil
+}
+
+// persist saves a filter if the filters are persistent, the caller must
+// hold filtersMu.
+func (fs *Filters) persist(id ID, f *Filter) {
+	if fs.store == nil {
+		return
+	}
+	if err := fs.store.put(id, f); err != nil {
+		fs.logError("persist filter failed", zap.String("id", string(id)), zap.Error(err))
+	}
+}
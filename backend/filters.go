@@ -1,8 +1,11 @@
 package backend
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	ethCommon "github.com/arcology-network/3rd-party/eth/common"
@@ -13,6 +16,7 @@ import (
 	eth "github.com/arcology-network/evm"
 	ethcmn "github.com/arcology-network/evm/common"
 	ethtyp "github.com/arcology-network/evm/core/types"
+	"go.uber.org/zap"
 )
 
 const (
@@ -21,6 +25,33 @@ const (
 	FilterTypePendingTransaction
 )
 
+const (
+	// OverflowDropOldest discards the oldest buffered changes of a filter
+	// that goes over its limits, the next poll reports how many were lost.
+	OverflowDropOldest = "drop"
+	// OverflowEvict removes a filter that goes over its limits, the next
+	// poll reports the eviction.
+	OverflowEvict = "evict"
+)
+
+const hashSize = 32
+
+// seenPendingTxs is how many recent pending transaction hashes are remembered
+// to drop duplicate announcements.
+const seenPendingTxs = 1 << 16
+
+var errFilterEvicted = errors.New("filter evicted: buffered changes exceeded the limit, create a new filter and poll more often")
+
+// FilterLimits bounds what filters buffer between two polls. Zero values
+// mean unlimited.
+type FilterLimits struct {
+	MaxLogs      int    // buffered logs per filter
+	MaxHashes    int    // buffered block or transaction hashes per filter
+	MaxBytes     int    // estimated buffered bytes per filter
+	MemoryBudget int64  // estimated buffered bytes across all filters
+	Overflow     string // OverflowDropOldest or OverflowEvict
+}
+
 type Filter struct {
 	Typ      byte
 	Deadline *time.Timer // filter is inactiv when deadline triggers
@@ -28,6 +59,20 @@ type Filter struct {
 	Crit     eth.FilterQuery
 	Logs     []*ethtyp.Log
 	lock     sync.Mutex
+
+	fs      *Filters
+	bytes   int // estimated size of Logs and Hashes
+	dropped int // changes dropped since the last poll
+	evicted bool
+	polled  uint64 // head height at the last poll
+
+	owner    string // client identity the filter counts against
+	lastPoll time.Time
+}
+
+// logSize estimates the memory held by a buffered log.
+func logSize(log *ethtyp.Log) int {
+	return 20 + len(log.Topics)*hashSize + len(log.Data) + 3*hashSize + 24
 }
 
 func (f *Filter) getHashes() []ethcmn.Hash {
@@ -35,6 +80,7 @@ func (f *Filter) getHashes() []ethcmn.Hash {
 	defer f.lock.Unlock()
 	hashes := f.Hashes
 	f.Hashes = nil
+	f.release(f.bytes)
 	return hashes
 }
 func (f *Filter) getLogs() []*ethtyp.Log {
@@ -42,59 +88,299 @@ func (f *Filter) getLogs() []*ethtyp.Log {
 	defer f.lock.Unlock()
 	logs := f.Logs
 	f.Logs = nil
+	f.release(f.bytes)
 	return logs
 }
-func (f *Filter) append(height uint64, logs []*ethtyp.Log, blockhash ethCommon.Hash) {
+
+// overflow reports and clears a pending overflow condition.
+func (f *Filter) overflow() error {
 	f.lock.Lock()
 	defer f.lock.Unlock()
+	if f.evicted {
+		return errFilterEvicted
+	}
+	if f.dropped > 0 {
+		dropped := f.dropped
+		f.dropped = 0
+		return fmt.Errorf("filter overflow: %d changes dropped since the last poll", dropped)
+	}
+	return nil
+}
 
-	switch f.Typ {
-	case FilterTypeLogs:
-		filteredLogs := make([]*ethtyp.Log, 0, len(logs))
-		if f.Crit.BlockHash != nil {
-			if *f.Crit.BlockHash == ethcmn.Hash(blockhash) {
-				filteredLogs = logs
-			}
-		} else {
-			found := true
-			if f.Crit.FromBlock != nil && height < f.Crit.FromBlock.Uint64() {
-				found = false
-			}
-			if found && f.Crit.ToBlock != nil && height > f.Crit.ToBlock.Uint64() {
-				found = false
-			}
-			if found {
-				filteredLogs = logs
-			}
-		}
-		finalLogs := ethrpc.FilteLogs(filteredLogs, f.Crit)
-		f.Logs = append(f.Logs, finalLogs...)
-	case FilterTypeBlock:
-		f.Hashes = append(f.Hashes, ethcmn.BytesToHash(blockhash[:]))
+func (f *Filter) reserve(n int) {
+	f.bytes += n
+	atomic.AddInt64(&f.fs.usedBytes, int64(n))
+}
+
+func (f *Filter) release(n int) {
+	f.bytes -= n
+	atomic.AddInt64(&f.fs.usedBytes, -int64(n))
+}
+
+// enforceLimits applies the overflow policy once the filter goes over its
+// own limits. The caller must hold f.lock.
+func (f *Filter) enforceLimits() {
+	limits := f.fs.limits
+	f.shed(func() bool {
+		return (limits.MaxLogs > 0 && len(f.Logs) > limits.MaxLogs) ||
+			(limits.MaxHashes > 0 && len(f.Hashes) > limits.MaxHashes) ||
+			(limits.MaxBytes > 0 && f.bytes > limits.MaxBytes)
+	})
+}
+
+// shed applies the overflow policy while over reports a limit exceeded. The
+// caller must hold f.lock.
+func (f *Filter) shed(over func() bool) {
+	if !over() {
+		return
+	}
+
+	if f.fs.limits.Overflow == OverflowEvict {
+		f.Logs = nil
+		f.Hashes = nil
+		f.release(f.bytes)
+		f.evicted = true
+		return
+	}
+	for over() && len(f.Logs) > 0 {
+		f.release(logSize(f.Logs[0]))
+		f.Logs[0] = nil
+		f.Logs = f.Logs[1:]
+		f.dropped++
+	}
+	for over() && len(f.Hashes) > 0 {
+		f.release(hashSize)
+		f.Hashes = f.Hashes[1:]
+		f.dropped++
 	}
 }
 
+func (f *Filter) appendLogs(logs []*ethtyp.Log) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.evicted {
+		return
+	}
+	for _, log := range logs {
+		f.reserve(logSize(log))
+	}
+	f.Logs = append(f.Logs, logs...)
+	f.enforceLimits()
+}
+
+func (f *Filter) appendHashes(hashes ...ethcmn.Hash) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.evicted {
+		return
+	}
+	f.reserve(hashSize * len(hashes))
+	f.Hashes = append(f.Hashes, hashes...)
+	f.enforceLimits()
+}
+
 type Filters struct {
 	filtersMu sync.Mutex
 	filters   map[ID]*Filter
+	index     *filterIndex
 	timeout   time.Duration
+	limits    FilterLimits
+	usedBytes int64 // estimated bytes buffered by all filters, accessed atomically
 	//backend   internal.EthereumAPI
+
+	heads []*deliveredBlock // recently delivered blocks, oldest first
+	head  uint64            // height of the latest block
+	store *filterStore      // nil unless filters are persistent
+	peers []string          // rpc urls of the replicated instances by hint
+
+	quotas FilterQuotas
+	owned  map[string]int // filters installed or reserved per client
+
+	logError func(msg string, fields ...zap.Field)
+
+	pendingMu   sync.Mutex
+	seen        map[ethcmn.Hash]struct{}
+	seenOrder   []ethcmn.Hash // ring buffer of the hashes in seen
+	seenNext    int
+	pendingSubs map[chan<- []ethcmn.Hash]struct{}
 }
 
-func NewFilters(timeout time.Duration) *Filters {
+func NewFilters(timeout time.Duration, limits FilterLimits) *Filters {
 	fs := &Filters{
-		filters: make(map[ID]*Filter),
-		timeout: timeout,
+		filters:     make(map[ID]*Filter),
+		index:       newFilterIndex(),
+		timeout:     timeout,
+		limits:      limits,
+		owned:       make(map[string]int),
+		seen:        make(map[ethcmn.Hash]struct{}),
+		seenOrder:   make([]ethcmn.Hash, 0, seenPendingTxs),
+		pendingSubs: make(map[chan<- []ethcmn.Hash]struct{}),
+		logError:    func(string, ...zap.Field) {},
 	}
 	go fs.timeoutLoop(timeout)
 
 	return fs
 }
 
-func (fs *Filters) OnResultsArrived(height uint64, receipts []*ethTypes.Receipt, blockhash ethCommon.Hash) {
-	logs := ethrpc.ToLogs(receipts)
+// SetErrorLog sets where the errors that no caller can handle, such as a
+// failure to persist a filter, are logged.
+func (fs *Filters) SetErrorLog(logError func(msg string, fields ...zap.Field)) {
+	fs.logError = logError
+}
+
+func (fs *Filters) OnResultsArrived(height uint64, receipts []*ethTypes.Receipt, blockhash, parentHash ethCommon.Hash) {
+	fs.OnLogsArrived(height, ethrpc.ToLogs(receipts), ethcmn.Hash(blockhash), ethcmn.Hash(parentHash))
+}
+
+// OnLogsArrived feeds the logs of a newly produced block to the filters. The
+// logs are matched once against the filter index and delivered while holding
+// filtersMu, so blocks reach every filter in order and never a removed one.
+// A block that does not extend the last delivered head reverts the blocks of
+// the old branch first, the way geth's event system reports a reorganization.
+func (fs *Filters) OnLogsArrived(height uint64, logs []*ethtyp.Log, blockhash, parentHash ethcmn.Hash) {
+	fs.filtersMu.Lock()
+	defer fs.filtersMu.Unlock()
+
+	if len(fs.heads) > 0 && fs.heads[len(fs.heads)-1].hash == blockhash {
+		return
+	}
+	if removed := fs.rewind(height, parentHash); len(removed) > 0 {
+		fs.revertBlocks(removed)
+	}
+	fs.pushHead(&deliveredBlock{height: height, hash: blockhash, parent: parentHash, logs: logs})
+	fs.head = height
+
+	for f, matched := range fs.index.match(height, blockhash, logs) {
+		f.appendLogs(matched)
+	}
+	for _, f := range fs.index.hashes {
+		f.appendHashes(blockhash)
+	}
+	fs.enforceBudget()
+}
+
+// OnPendingTransactions feeds the hashes of transactions accepted into the
+// pool to the pending transaction filters and subscribers. Hashes announced
+// before are dropped.
+func (fs *Filters) OnPendingTransactions(hashes []ethcmn.Hash) {
+	fs.pendingMu.Lock()
+	fresh := make([]ethcmn.Hash, 0, len(hashes))
+	for _, hash := range hashes {
+		if _, ok := fs.seen[hash]; ok {
+			continue
+		}
+		if len(fs.seenOrder) < seenPendingTxs {
+			fs.seenOrder = append(fs.seenOrder, hash)
+		} else {
+			delete(fs.seen, fs.seenOrder[fs.seenNext])
+			fs.seenOrder[fs.seenNext] = hash
+			fs.seenNext = (fs.seenNext + 1) % seenPendingTxs
+		}
+		fs.seen[hash] = struct{}{}
+		fresh = append(fresh, hash)
+	}
+	if len(fresh) == 0 {
+		fs.pendingMu.Unlock()
+		return
+	}
+	for ch := range fs.pendingSubs {
+		select {
+		case ch <- fresh:
+		default:
+			// slow subscribers miss announcements rather than block the feed
+		}
+	}
+	fs.pendingMu.Unlock()
+
+	fs.filtersMu.Lock()
+	defer fs.filtersMu.Unlock()
+	for _, f := range fs.index.pending {
+		f.appendHashes(fresh...)
+	}
+	fs.enforceBudget()
+}
+
+// enforceBudget applies the overflow policy to the filters buffering the
+// most, the longest unpolled first among equals, until the buffers of all
+// filters fit the memory budget again. The caller must hold filtersMu.
+func (fs *Filters) enforceBudget() {
+	budget := fs.limits.MemoryBudget
+	over := func() bool {
+		return atomic.LoadInt64(&fs.usedBytes) > budget
+	}
+	if budget <= 0 || !over() {
+		return
+	}
+
+	type candidate struct {
+		f        *Filter
+		bytes    int
+		lastPoll time.Time
+	}
+	candidates := make([]candidate, 0, len(fs.filters))
 	for _, f := range fs.filters {
-		go f.append(height, logs, blockhash)
+		f.lock.Lock()
+		candidates = append(candidates, candidate{f, f.bytes, f.lastPoll})
+		f.lock.Unlock()
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].bytes != candidates[j].bytes {
+			return candidates[i].bytes > candidates[j].bytes
+		}
+		return candidates[i].lastPoll.Before(candidates[j].lastPoll)
+	})
+	for _, c := range candidates {
+		if !over() {
+			return
+		}
+		c.f.lock.Lock()
+		c.f.shed(over)
+		c.f.lock.Unlock()
+	}
+}
+
+// SubscribePendingTransactions registers ch for the deduplicated pending
+// transaction hashes, as needed by newPendingTransactions subscriptions. The
+// returned function cancels the subscription.
+func (fs *Filters) SubscribePendingTransactions(ch chan<- []ethcmn.Hash) func() {
+	fs.pendingMu.Lock()
+	defer fs.pendingMu.Unlock()
+
+	fs.pendingSubs[ch] = struct{}{}
+	return func() {
+		fs.pendingMu.Lock()
+		defer fs.pendingMu.Unlock()
+		delete(fs.pendingSubs, ch)
+	}
+}
+
+// install adds a new filter, the caller must hold filtersMu.
+func (fs *Filters) install(f *Filter) ID {
+	id := NewID()
+	f.fs = fs
+	f.polled = fs.head
+	f.lastPoll = time.Now()
+	fs.filters[id] = f
+	fs.index.add(id, f)
+	fs.persist(id, f)
+	return id
+}
+
+// remove deletes a filter and frees its buffers, the caller must hold
+// filtersMu.
+func (fs *Filters) remove(id ID, f *Filter) {
+	f.discard()
+	fs.index.remove(id, f)
+	delete(fs.filters, id)
+	if f.owner != "" {
+		fs.release(f.owner)
+	}
+	if fs.store != nil {
+		if err := fs.store.delete(id); err != nil {
+			fs.logError("delete filter failed", zap.String("id", string(id)), zap.Error(err))
+		}
 	}
 }
 
@@ -110,7 +396,7 @@ func (fs *Filters) timeoutLoop(timeout time.Duration) {
 		for id, f := range fs.filters {
 			select {
 			case <-f.Deadline.C:
-				delete(fs.filters, id)
+				fs.remove(id, f)
 			default:
 				continue
 			}
@@ -119,58 +405,64 @@ func (fs *Filters) timeoutLoop(timeout time.Duration) {
 	}
 }
 
-func (fs *Filters) UninstallFilter(id ID) bool {
+// UninstallFilter removes a filter installed here. A filter issued by a peer
+// is not found: the rpc handlers forward calls for it to the peer, as they
+// know the client they make them for.
+func (fs *Filters) UninstallFilter(id ID) (bool, error) {
 	fs.filtersMu.Lock()
 	defer fs.filtersMu.Unlock()
 
-	_, found := fs.filters[id]
+	f, found := fs.filters[id]
 	if found {
-		delete(fs.filters, id)
+		fs.remove(id, f)
 	}
-	return found
+	return found, nil
 }
 
 func (fs *Filters) NewPendingTransactionFilter() ID {
 	fs.filtersMu.Lock()
 	defer fs.filtersMu.Unlock()
 
-	id := NewID()
-	fs.filters[id] = &Filter{
+	return fs.install(&Filter{
 		Typ:      FilterTypePendingTransaction,
 		Deadline: time.NewTimer(fs.timeout),
 		Hashes:   make([]ethcmn.Hash, 0),
-	}
-	return id
+	})
 }
 
 func (fs *Filters) NewBlockFilter() ID {
 	fs.filtersMu.Lock()
 	defer fs.filtersMu.Unlock()
 
-	id := NewID()
-	fs.filters[id] = &Filter{
+	return fs.install(&Filter{
 		Typ:      FilterTypeBlock,
 		Deadline: time.NewTimer(fs.timeout),
 		Hashes:   make([]ethcmn.Hash, 0),
-	}
-
-	return id
+	})
 }
 
 func (fs *Filters) NewFilter(crit eth.FilterQuery) ID {
 	fs.filtersMu.Lock()
 	defer fs.filtersMu.Unlock()
 
-	id := NewID()
-	fs.filters[id] = &Filter{
+	return fs.install(&Filter{
 		Typ:      FilterTypeLogs,
 		Crit:     crit,
 		Deadline: time.NewTimer(fs.timeout),
 		Logs:     make([]*ethtyp.Log, 0),
-	}
-	return id
+	})
 }
+
+// GetFilterChanges polls a filter installed here, a filter issued by a peer
+// is not found.
 func (fs *Filters) GetFilterChanges(id ID) (interface{}, error) {
+	if changes, found, err := fs.localChanges(id); found {
+		return changes, err
+	}
+	return []interface{}{}, fmt.Errorf("filter not found")
+}
+
+func (fs *Filters) localChanges(id ID) (interface{}, bool, error) {
 	fs.filtersMu.Lock()
 	defer fs.filtersMu.Unlock()
 
@@ -182,24 +474,46 @@ func (fs *Filters) GetFilterChanges(id ID) (interface{}, error) {
 		}
 		f.Deadline.Reset(fs.timeout)
 
+		if err := f.overflow(); err != nil {
+			if err == errFilterEvicted {
+				fs.remove(id, f)
+			}
+			return []interface{}{}, true, err
+		}
+
+		f.lastPoll = time.Now()
+		if f.polled != fs.head {
+			f.polled = fs.head
+			fs.persist(id, f)
+		}
 		switch f.Typ {
 		case FilterTypePendingTransaction, FilterTypeBlock:
 			hashes := f.getHashes()
-			return returnHashes(hashes), nil
+			return returnHashes(hashes), true, nil
 		case FilterTypeLogs:
 			logs := f.getLogs()
-			return returnLogs(logs), nil
+			return returnLogs(logs), true, nil
 		}
 	}
-
-	return []interface{}{}, fmt.Errorf("filter not found")
+	return nil, false, nil
 }
 
 func (fs *Filters) GetFilterLogsCrit(id ID) (*eth.FilterQuery, error) {
 	fs.filtersMu.Lock()
 	f, found := fs.filters[id]
+	store := fs.store
 	fs.filtersMu.Unlock()
 
+	if !found && store != nil {
+		// filters issued by other instances are found in the shared state
+		rec, err := store.get(id)
+		if err != nil {
+			return nil, err
+		}
+		if rec != nil {
+			f, found = &Filter{Typ: rec.Typ, Crit: rec.Crit}, true
+		}
+	}
 	if !found || f.Typ != FilterTypeLogs {
 		return nil, fmt.Errorf("filter not found")
 	}
@@ -211,6 +525,15 @@ func (fs *Filters) GetFilterLogsCrit(id ID) (*eth.FilterQuery, error) {
 	// return returnLogs(logs), nil
 }
 
+// discard frees the buffers of a filter that is being removed.
+func (f *Filter) discard() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.Logs = nil
+	f.Hashes = nil
+	f.release(f.bytes)
+}
+
 func returnLogs(logs []*ethtyp.Log) []*ethtyp.Log {
 	if logs == nil {
 		return []*ethtyp.Log{}
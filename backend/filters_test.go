@@ -0,0 +1,299 @@
+package backend
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	"github.com/arcology-network/evm/ethdb/memorydb"
+)
+
+func testLogs(n int) []*ethtyp.Log {
+	logs := make([]*ethtyp.Log, n)
+	for i := range logs {
+		logs[i] = &ethtyp.Log{
+			Address: ethcmn.BytesToAddress([]byte{byte(i % 16)}),
+			Topics:  []ethcmn.Hash{ethcmn.BytesToHash([]byte{byte(i % 8)})},
+			Data:    make([]byte, 32),
+		}
+	}
+	return logs
+}
+
+func TestFilterDropOldest(t *testing.T) {
+	fs := NewFilters(time.Minute, FilterLimits{MaxLogs: 3, Overflow: OverflowDropOldest})
+	id := fs.NewFilter(eth.FilterQuery{})
+	logs := testLogs(5)
+	fs.OnLogsArrived(1, logs, ethcmn.Hash{1}, ethcmn.Hash{})
+
+	if _, err := fs.GetFilterChanges(id); err == nil {
+		t.Fatal("expected an overflow error")
+	}
+	changes, err := fs.GetFilterChanges(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := changes.([]*ethtyp.Log)
+	if len(kept) != 3 || kept[0] != logs[2] {
+		t.Fatalf("expected the 3 newest logs, got %d", len(kept))
+	}
+	if fs.usedBytes != 0 {
+		t.Fatalf("expected all buffered bytes released, got %d", fs.usedBytes)
+	}
+}
+
+func TestFilterEvict(t *testing.T) {
+	fs := NewFilters(time.Minute, FilterLimits{MemoryBudget: 100, Overflow: OverflowEvict})
+	id := fs.NewFilter(eth.FilterQuery{})
+	fs.OnLogsArrived(1, testLogs(5), ethcmn.Hash{1}, ethcmn.Hash{})
+
+	if _, err := fs.GetFilterChanges(id); err != errFilterEvicted {
+		t.Fatalf("expected the eviction error, got %v", err)
+	}
+	if _, err := fs.GetFilterChanges(id); err == nil {
+		t.Fatal("expected the evicted filter to be gone")
+	}
+}
+
+func TestFilterBudgetEvictsLargest(t *testing.T) {
+	logs := testLogs(11)
+	fs := NewFilters(time.Minute, FilterLimits{MemoryBudget: int64(10 * logSize(logs[0])), Overflow: OverflowEvict})
+	all := fs.NewFilter(eth.FilterQuery{})
+	address := ethcmn.Address{0xaa}
+	one := fs.NewFilter(eth.FilterQuery{Addresses: []ethcmn.Address{address}})
+	fs.OnLogsArrived(1, logs[:9], ethcmn.Hash{1}, ethcmn.Hash{})
+	for _, log := range logs[9:] {
+		log.Address = address
+	}
+	fs.OnLogsArrived(2, logs[9:], ethcmn.Hash{2}, ethcmn.Hash{1})
+
+	if _, err := fs.GetFilterChanges(all); err != errFilterEvicted {
+		t.Fatalf("expected the largest filter to be evicted, got %v", err)
+	}
+	changes, err := fs.GetFilterChanges(one)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.([]*ethtyp.Log)) != 2 {
+		t.Fatalf("expected the small filter to keep its logs, got %v", changes)
+	}
+}
+
+func TestFilterIndexMatch(t *testing.T) {
+	fs := NewFilters(time.Minute, FilterLimits{})
+	logs := testLogs(16)
+	byAddress := fs.NewFilter(eth.FilterQuery{Addresses: []ethcmn.Address{logs[3].Address}})
+	byTopic := fs.NewFilter(eth.FilterQuery{Topics: [][]ethcmn.Hash{{logs[2].Topics[0]}}})
+	both := fs.NewFilter(eth.FilterQuery{
+		Addresses: []ethcmn.Address{logs[3].Address},
+		Topics:    [][]ethcmn.Hash{{logs[2].Topics[0]}},
+	})
+	all := fs.NewFilter(eth.FilterQuery{})
+	blocks := fs.NewBlockFilter()
+	fs.OnLogsArrived(1, logs, ethcmn.Hash{1}, ethcmn.Hash{})
+
+	for id, want := range map[ID]int{byAddress: 1, byTopic: 2, both: 0, all: 16} {
+		changes, _ := fs.GetFilterChanges(id)
+		if got := len(changes.([]*ethtyp.Log)); got != want {
+			t.Errorf("filter %v: expected %d logs, got %d", id, want, got)
+		}
+	}
+	changes, _ := fs.GetFilterChanges(blocks)
+	if hashes := changes.([]ethcmn.Hash); len(hashes) != 1 || hashes[0] != (ethcmn.Hash{1}) {
+		t.Errorf("block filter: unexpected hashes %v", hashes)
+	}
+}
+
+func TestPendingTransactionFilter(t *testing.T) {
+	fs := NewFilters(time.Minute, FilterLimits{})
+	id := fs.NewPendingTransactionFilter()
+	fs.OnPendingTransactions([]ethcmn.Hash{{1}, {2}})
+	fs.OnPendingTransactions([]ethcmn.Hash{{2}, {3}})
+
+	changes, err := fs.GetFilterChanges(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashes := changes.([]ethcmn.Hash); len(hashes) != 3 || hashes[2] != (ethcmn.Hash{3}) {
+		t.Fatalf("expected 3 distinct hashes, got %v", hashes)
+	}
+
+	ch := make(chan []ethcmn.Hash, 1)
+	defer fs.SubscribePendingTransactions(ch)()
+	fs.OnPendingTransactions([]ethcmn.Hash{{3}})
+	select {
+	case hashes := <-ch:
+		t.Fatalf("expected no announcement without new hashes, got %v", hashes)
+	default:
+	}
+}
+
+func TestFilterLatestBound(t *testing.T) {
+	fs := NewFilters(time.Minute, FilterLimits{})
+	latest := fs.NewFilter(eth.FilterQuery{FromBlock: big.NewInt(-1), ToBlock: big.NewInt(-1)})
+	upTo := fs.NewFilter(eth.FilterQuery{FromBlock: big.NewInt(0), ToBlock: big.NewInt(2)})
+	for height := uint64(1); height <= 3; height++ {
+		fs.OnLogsArrived(height, testLogs(1), ethcmn.BytesToHash([]byte{byte(height)}), ethcmn.Hash{})
+	}
+	if logs, _ := fs.GetFilterChanges(latest); len(logs.([]*ethtyp.Log)) != 3 {
+		t.Fatalf("expected latest to follow every block, got %v", logs)
+	}
+	if logs, _ := fs.GetFilterChanges(upTo); len(logs.([]*ethtyp.Log)) != 2 {
+		t.Fatalf("expected the blocks up to 2, got %v", logs)
+	}
+}
+
+func TestFilterReorg(t *testing.T) {
+	fs := NewFilters(time.Minute, FilterLimits{})
+	logs := fs.NewFilter(eth.FilterQuery{})
+	blocks := fs.NewBlockFilter()
+	fs.OnLogsArrived(1, testLogs(1), ethcmn.Hash{1}, ethcmn.Hash{})
+	fs.GetFilterChanges(logs)
+	fs.GetFilterChanges(blocks)
+
+	// block 2a is replaced by 2b before the block filter is polled
+	fs.OnLogsArrived(2, testLogs(2), ethcmn.Hash{0x2a}, ethcmn.Hash{1})
+	fs.OnLogsArrived(2, testLogs(1), ethcmn.Hash{0x2b}, ethcmn.Hash{1})
+
+	changes, _ := fs.GetFilterChanges(logs)
+	got := changes.([]*ethtyp.Log)
+	if len(got) != 5 {
+		t.Fatalf("expected 2 added, 2 removed and 1 added logs, got %d", len(got))
+	}
+	for i, want := range []bool{false, false, true, true, false} {
+		if got[i].Removed != want {
+			t.Errorf("log %d: expected removed %v", i, want)
+		}
+	}
+	changes, _ = fs.GetFilterChanges(blocks)
+	if hashes := changes.([]ethcmn.Hash); len(hashes) != 1 || hashes[0] != (ethcmn.Hash{0x2b}) {
+		t.Fatalf("expected only the new head, got %v", hashes)
+	}
+
+	// block 4 follows a block 3 that was never delivered, a gap and not a reorg
+	fs.OnLogsArrived(4, testLogs(1), ethcmn.Hash{4}, ethcmn.Hash{3})
+	changes, _ = fs.GetFilterChanges(logs)
+	for _, log := range changes.([]*ethtyp.Log) {
+		if log.Removed {
+			t.Fatal("expected no removed logs after a gap")
+		}
+	}
+}
+
+func TestFilterRestore(t *testing.T) {
+	state := NewDatabaseFilterState(memorydb.New())
+	fs := NewFilters(time.Minute, FilterLimits{})
+	chain := NewDevChain(big.NewInt(1), nil, 0, fs)
+	if err := fs.Restore(state, chain, 100); err != nil {
+		t.Fatal(err)
+	}
+	id := fs.NewBlockFilter()
+	chain.Mine(0)
+	fs.GetFilterChanges(id)
+
+	// blocks 2 and 3 are produced while the service is down
+	chain.Mine(0)
+	chain.Mine(0)
+	restarted := NewFilters(time.Minute, FilterLimits{})
+	if err := restarted.Restore(state, chain, 100); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := restarted.GetFilterChanges(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes := changes.([]ethcmn.Hash)
+	if len(hashes) != 2 {
+		t.Fatalf("expected the 2 missed blocks, got %d", len(hashes))
+	}
+	for i, hash := range hashes {
+		block, _ := chain.GetBlockByNumber(int64(i+2), false)
+		if hash != block.Header.Hash() {
+			t.Errorf("block %d: expected %x, got %x", i+2, block.Header.Hash(), hash)
+		}
+	}
+}
+
+func TestFilterQuotas(t *testing.T) {
+	fs := NewFilters(time.Minute, FilterLimits{})
+	fs.SetQuotas(FilterQuotas{MaxFilters: 1, MaxAddresses: 1, MaxLogRange: 100})
+
+	wide := eth.FilterQuery{Addresses: []ethcmn.Address{{1}, {2}}}
+	if err := fs.Admit("ip:a", &wide); err == nil {
+		t.Fatal("expected the address quota to reject the filter")
+	}
+	if err := fs.Admit("ip:a", nil); err != nil {
+		t.Fatal(err)
+	}
+	id := fs.NewBlockFilter()
+	fs.SetOwner(id, "ip:a")
+	if err := fs.Admit("ip:a", nil); err == nil {
+		t.Fatal("expected the filter count quota to reject the filter")
+	}
+	if err := fs.Admit("ip:b", nil); err != nil {
+		t.Fatalf("expected quotas to be per client: %v", err)
+	}
+	fs.UninstallFilter(id)
+	if err := fs.Admit("ip:a", nil); err != nil {
+		t.Fatalf("expected the uninstalled filter to be given back: %v", err)
+	}
+
+	if infos := fs.List(); len(infos) != 0 {
+		t.Fatalf("expected no filters listed, got %d", len(infos))
+	}
+	if err := fs.CheckLogRange(&eth.FilterQuery{FromBlock: big.NewInt(0)}, 1000); err == nil {
+		t.Fatal("expected the log range quota to reject the query")
+	}
+}
+
+// benchmarkFilters installs n address filters spread over 1000 contracts,
+// the common shape of dapp subscriptions.
+func benchmarkFilters(n int) (*Filters, []*ethtyp.Log) {
+	fs := NewFilters(time.Hour, FilterLimits{})
+	for i := 0; i < n; i++ {
+		address := ethcmn.BytesToAddress([]byte(fmt.Sprintf("contract-%d", i%1000)))
+		fs.NewFilter(eth.FilterQuery{Addresses: []ethcmn.Address{address}})
+	}
+	logs := make([]*ethtyp.Log, 5000)
+	for i := range logs {
+		logs[i] = &ethtyp.Log{
+			Address: ethcmn.BytesToAddress([]byte(fmt.Sprintf("contract-%d", i%2000))),
+			Topics:  []ethcmn.Hash{ethcmn.BytesToHash([]byte{byte(i)})},
+		}
+	}
+	return fs, logs
+}
+
+func BenchmarkOnLogsArrivedIndexed(b *testing.B) {
+	fs, logs := benchmarkFilters(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fs.OnLogsArrived(uint64(i), logs, ethcmn.BytesToHash([]byte(fmt.Sprint(i))), ethcmn.Hash{})
+		for _, f := range fs.filters {
+			f.getLogs()
+		}
+	}
+}
+
+// BenchmarkOnLogsArrivedLinear matches every log against every filter, the
+// way blocks were dispatched before the index.
+func BenchmarkOnLogsArrivedLinear(b *testing.B) {
+	fs, logs := benchmarkFilters(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, f := range fs.filters {
+			var matched []*ethtyp.Log
+			for _, log := range logs {
+				if f.matchesLog(log) {
+					matched = append(matched, log)
+				}
+			}
+			f.appendLogs(matched)
+			f.getLogs()
+		}
+	}
+}
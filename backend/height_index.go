@@ -0,0 +1,128 @@
+package backend
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"sync"
+
+	"github.com/arcology-network/evm/ethdb"
+)
+
+var indexHeadKey = []byte("head")
+
+// heightIndex is what the account, token and contract indexes share: a
+// record of each indexed block under a prefix and the last indexed block.
+// Blocks are added in order, a block at an indexed height replaces it and
+// the blocks above it.
+type heightIndex struct {
+	db     ethdb.KeyValueStore
+	prefix []byte // block number -> record of the block
+
+	mu   sync.Mutex
+	head uint64 // last indexed block, 0 if none
+}
+
+func newHeightIndex(db ethdb.KeyValueStore, prefix []byte) (*heightIndex, error) {
+	h := &heightIndex{db: db, prefix: prefix}
+	if ok, err := db.Has(indexHeadKey); err != nil {
+		return nil, err
+	} else if ok {
+		data, err := db.Get(indexHeadKey)
+		if err != nil {
+			return nil, err
+		}
+		h.head = binary.BigEndian.Uint64(data)
+	}
+	return h, nil
+}
+
+// Head returns the last indexed block.
+func (h *heightIndex) Head() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.head
+}
+
+// record decodes the record of a block into v, ok is false if the block has
+// none.
+func (h *heightIndex) record(number uint64, v interface{}) (bool, error) {
+	key := indexKey(h.prefix, encodeNumber(number))
+	if ok, err := h.db.Has(key); !ok || err != nil {
+		return false, err
+	}
+	data, err := h.db.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(data, v)
+}
+
+// rewind removes the blocks from number on, newest first, undo deleting in
+// batch what a block added besides its record. The caller must hold mu.
+func (h *heightIndex) rewind(number uint64, undo func(batch ethdb.Batch, number uint64) error) error {
+	if number > h.head {
+		return nil
+	}
+	batch := h.db.NewBatch()
+	for n := h.head; n >= number && n > 0; n-- {
+		if err := undo(batch, n); err != nil {
+			return err
+		}
+		batch.Delete(indexKey(h.prefix, encodeNumber(n)))
+	}
+	head := uint64(0)
+	if number > 0 {
+		head = number - 1
+	}
+	batch.Put(indexHeadKey, encodeNumber(head))
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	h.head = head
+	return nil
+}
+
+// commit writes batch with the record of a block, which becomes the head.
+// The caller must hold mu.
+func (h *heightIndex) commit(batch ethdb.Batch, number uint64, record interface{}) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	batch.Put(indexKey(h.prefix, encodeNumber(number)), data)
+	batch.Put(indexHeadKey, encodeNumber(number))
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	h.head = number
+	return nil
+}
+
+// readPage reads up to limit values after the cursor, the cursor is the key
+// of the last value returned relative to the prefix.
+func readPage(db ethdb.KeyValueStore, prefix []byte, cursor []byte, limit int, keep func([]byte) (bool, error)) ([]byte, error) {
+	var start []byte
+	if cursor != nil {
+		start = append(append([]byte{}, cursor...), 0)
+	}
+	it := db.NewIterator(prefix, start)
+	defer it.Release()
+
+	var next []byte
+	n := 0
+	for it.Next() {
+		if n >= limit {
+			return next, nil
+		}
+		ok, err := keep(it.Value())
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			n++
+		}
+		next = append([]byte{}, it.Key()[len(prefix):]...)
+	}
+	return nil, nil
+}
@@ -10,16 +10,39 @@ import (
 	"time"
 )
 
-var globalGen = randomIDGenerator()
+var (
+	globalGen    = randomIDGenerator()
+	instanceHint byte
+)
 
 // ID defines a pseudo random number that is used to identify RPC subscriptions.
 type ID string
 
-// NewID returns a new, random ID.
+// NewID returns a new, random ID. Its last byte is the hint of the instance
+// that issued it.
 func NewID() ID {
 	return globalGen()
 }
 
+// SetInstanceHint sets the hint encoded in the IDs issued from now on, it
+// tells replicated instances which one of them owns an ID.
+func SetInstanceHint(hint byte) {
+	instanceHint = hint
+}
+
+// InstanceOf returns the instance hint encoded in an ID.
+func InstanceOf(id ID) (byte, bool) {
+	s := strings.TrimPrefix(string(id), "0x")
+	if len(s) < 2 {
+		return 0, false
+	}
+	b, err := hex.DecodeString(s[len(s)-2:])
+	if err != nil {
+		return 0, false
+	}
+	return b[0], true
+}
+
 // randomIDGenerator returns a function generates a random IDs.
 func randomIDGenerator() func() ID {
 	var buf = make([]byte, 8)
@@ -39,6 +62,7 @@ func randomIDGenerator() func() ID {
 		defer mu.Unlock()
 		id := make([]byte, 16)
 		rng.Read(id)
+		id[len(id)-1] = instanceHint
 		return encodeID(id)
 	}
 }
@@ -0,0 +1,62 @@
+package backend
+
+import (
+	"math/big"
+
+	eth "github.com/arcology-network/evm"
+	ethtyp "github.com/arcology-network/evm/core/types"
+)
+
+// Indexed answers log queries over the blocks of a LogIndex locally and
+// passes everything else on to the wrapped backend.
+type Indexed struct {
+	EthereumAPI
+	index   *LogIndex
+	filters *Filters
+}
+
+func NewIndexed(api EthereumAPI, index *LogIndex, filters *Filters) *Indexed {
+	return &Indexed{
+		EthereumAPI: api,
+		index:       index,
+		filters:     filters,
+	}
+}
+
+func (b *Indexed) GetLogs(filter eth.FilterQuery) ([]*ethtyp.Log, error) {
+	if filter.BlockHash != nil {
+		if number, ok := b.index.BlockNumber(*filter.BlockHash); ok {
+			return b.index.GetLogs(filter, number, number)
+		}
+		return b.EthereumAPI.GetLogs(filter)
+	}
+
+	explicit := func(n *big.Int) bool { return n != nil && n.Sign() >= 0 }
+	var from, to uint64
+	if !explicit(filter.FromBlock) || !explicit(filter.ToBlock) {
+		// open ends are only known to be indexed if the index is at the head
+		head, err := b.EthereumAPI.BlockNumber()
+		if err != nil {
+			return nil, err
+		}
+		from, to = head, head
+	}
+	if explicit(filter.FromBlock) {
+		from = filter.FromBlock.Uint64()
+	}
+	if explicit(filter.ToBlock) {
+		to = filter.ToBlock.Uint64()
+	}
+	if from <= to && b.index.Covers(from, to) {
+		return b.index.GetLogs(filter, from, to)
+	}
+	return b.EthereumAPI.GetLogs(filter)
+}
+
+func (b *Indexed) GetFilterLogs(id ID) ([]*ethtyp.Log, error) {
+	crit, err := b.filters.GetFilterLogsCrit(id)
+	if err != nil {
+		return nil, err
+	}
+	return b.GetLogs(*crit)
+}
@@ -0,0 +1,49 @@
+package backend
+
+import (
+	"math/big"
+	"testing"
+
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/ethdb/memorydb"
+)
+
+// headCounter counts the head lookups the indexed backend makes.
+type headCounter struct {
+	EthereumAPI
+	head  uint64
+	calls int
+}
+
+func (b *headCounter) BlockNumber() (uint64, error) {
+	b.calls++
+	return b.head, nil
+}
+
+func TestIndexedGetLogs(t *testing.T) {
+	idx, err := NewLogIndex(memorydb.New(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for number := uint64(1); number <= 3; number++ {
+		logs := testLogs(2)
+		for _, log := range logs {
+			log.BlockNumber = number
+		}
+		if err := idx.Add(number, ethcmn.Hash{byte(number)}, logs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	api := &headCounter{head: 3}
+	indexed := NewIndexed(api, idx, nil)
+
+	logs, err := indexed.GetLogs(eth.FilterQuery{FromBlock: big.NewInt(1), ToBlock: big.NewInt(2)})
+	if err != nil || len(logs) != 4 || api.calls != 0 {
+		t.Fatalf("expected blocks 1 to 2 without a head lookup, got %d logs, %d lookups, %v", len(logs), api.calls, err)
+	}
+	logs, err = indexed.GetLogs(eth.FilterQuery{FromBlock: big.NewInt(2)})
+	if err != nil || len(logs) != 4 || api.calls != 1 {
+		t.Fatalf("expected blocks 2 to the head with one lookup, got %d logs, %d lookups, %v", len(logs), api.calls, err)
+	}
+}
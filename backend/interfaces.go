@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"encoding/json"
 	"math/big"
 
 	"github.com/arcology-network/component-lib/ethrpc"
@@ -19,6 +20,7 @@ type EthereumAPI interface {
 	GetBalance(address ethcmn.Address, number int64) (*big.Int, error)
 	GetTransactionCount(address ethcmn.Address, number int64) (uint64, error)
 	GetStorageAt(address ethcmn.Address, key string, number int64) ([]byte, error)
+	GetProof(address ethcmn.Address, keys []string, number int64) (*AccountResult, error)
 
 	EstimateGas(msg eth.CallMsg) (uint64, error)
 	GasPrice() (*big.Int, error)
@@ -28,6 +30,8 @@ type EthereumAPI interface {
 	Call(msg eth.CallMsg) ([]byte, error)
 	SendRawTransaction(rawTx []byte) (ethcmn.Hash, error)
 	GetTransactionReceipt(hash ethcmn.Hash) (*ethtyp.Receipt, error)
+	GetBlockReceipts(number int64) ([]*BlockReceipt, error)
+	GetBlockReceiptsByHash(hash ethcmn.Hash) ([]*BlockReceipt, error)
 	GetLogs(filter eth.FilterQuery) ([]*ethtyp.Log, error)
 
 	GetBlockTransactionCountByHash(hash ethcmn.Hash) (int, error)
@@ -57,3 +61,56 @@ type EthereumAPI interface {
 	GetFilterChanges(id ID) (interface{}, error)
 	GetFilterLogs(id ID) ([]*ethtyp.Log, error)
 }
+
+// DevControl is implemented by backends that keep their own chain, it backs
+// the evm_/hardhat_/anvil_ test control methods.
+type DevControl interface {
+	Mine(timestamp uint64) (uint64, error)
+	Snapshot() (string, error)
+	Revert(id string) (bool, error)
+	IncreaseTime(seconds uint64) (uint64, error)
+	SetNextBlockTimestamp(timestamp uint64) error
+
+	SetBalance(address ethcmn.Address, balance *big.Int) error
+	SetCode(address ethcmn.Address, code []byte) error
+	SetStorageAt(address ethcmn.Address, key ethcmn.Hash, value ethcmn.Hash) error
+	SetNonce(address ethcmn.Address, nonce uint64) error
+
+	ImpersonateAccount(address ethcmn.Address) error
+	StopImpersonatingAccount(address ethcmn.Address) error
+	IsImpersonated(address ethcmn.Address) bool
+	SendImpersonatedTransaction(msg eth.CallMsg) (ethcmn.Hash, error)
+}
+
+// TraceConfig selects the tracer a transaction is re-executed with, geth's
+// struct logger when no tracer is named. The logger options and the tracer
+// config are those of geth's debug_trace* methods.
+type TraceConfig struct {
+	Tracer           string          `json:"tracer,omitempty"`
+	TracerConfig     json.RawMessage `json:"tracerConfig,omitempty"`
+	DisableStack     bool            `json:"disableStack,omitempty"`
+	DisableStorage   bool            `json:"disableStorage,omitempty"`
+	EnableMemory     bool            `json:"enableMemory,omitempty"`
+	EnableReturnData bool            `json:"enableReturnData,omitempty"`
+	Limit            int             `json:"limit,omitempty"`
+}
+
+// TxTrace is the trace of a transaction in a traced block.
+type TxTrace struct {
+	TxHash ethcmn.Hash     `json:"txHash"`
+	Result json.RawMessage `json:"result"`
+}
+
+// Tracer is implemented by backends that can re-execute past transactions
+// with a tracer attached, the trace is in geth's JSON format.
+type Tracer interface {
+	TraceTransaction(hash ethcmn.Hash, config *TraceConfig) (json.RawMessage, error)
+	TraceCall(msg eth.CallMsg, number int64, config *TraceConfig) (json.RawMessage, error)
+	TraceBlock(number int64, config *TraceConfig) ([]*TxTrace, error)
+}
+
+// Simulator is implemented by backends that can run calls in sequence over
+// shared state across simulated blocks, backing eth_simulateV1.
+type Simulator interface {
+	Simulate(number int64, blocks []*SimBlock, validation bool) ([]*SimBlockResult, error)
+}
@@ -0,0 +1,248 @@
+package backend
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/arcology-network/component-lib/ethrpc"
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	"github.com/arcology-network/evm/ethdb"
+)
+
+var (
+	logsPrefix    = []byte("l") // block number -> logs of the block
+	addressPrefix = []byte("a") // address + block number -> nil
+	topicPrefix   = []byte("t") // position + topic + block number -> nil
+	blockPrefix   = []byte("b") // block hash -> block number
+	hashPrefix    = []byte("h") // block number -> block hash
+	indexRangeKey = []byte("range")
+)
+
+// LogIndex keeps the logs of the most recent blocks on disk with an inverted
+// address and topic index, so that log queries over them are answered
+// without the storage service. Only a contiguous range of blocks is indexed,
+// at most horizon blocks long.
+type LogIndex struct {
+	db      ethdb.KeyValueStore
+	horizon uint64
+
+	rangeMu sync.RWMutex
+	lo, hi  uint64 // indexed blocks, empty if hi < lo
+}
+
+func NewLogIndex(db ethdb.KeyValueStore, horizon uint64) (*LogIndex, error) {
+	idx := &LogIndex{db: db, horizon: horizon, lo: 1}
+	if ok, err := db.Has(indexRangeKey); err != nil {
+		return nil, err
+	} else if ok {
+		data, err := db.Get(indexRangeKey)
+		if err != nil {
+			return nil, err
+		}
+		idx.lo, idx.hi = binary.BigEndian.Uint64(data), binary.BigEndian.Uint64(data[8:])
+	}
+	return idx, nil
+}
+
+func encodeNumber(number uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, number)
+	return b
+}
+
+func indexKey(parts ...[]byte) []byte {
+	var key []byte
+	for _, part := range parts {
+		key = append(key, part...)
+	}
+	return key
+}
+
+// Range returns the indexed blocks, ok is false if there are none.
+func (idx *LogIndex) Range() (lo, hi uint64, ok bool) {
+	idx.rangeMu.RLock()
+	defer idx.rangeMu.RUnlock()
+
+	return idx.lo, idx.hi, idx.hi >= idx.lo
+}
+
+// Covers reports whether all the blocks from..to are indexed.
+func (idx *LogIndex) Covers(from, to uint64) bool {
+	lo, hi, ok := idx.Range()
+	return ok && lo <= from && to <= hi
+}
+
+// Add indexes the logs of a block. A block right below the indexed ones
+// extends the index downwards, as long as the horizon allows it, the way
+// backfilling fills it. A block at an indexed height replaces it and the
+// blocks above it, a block after a gap restarts the index. A block further
+// below the indexed ones would leave a hole in the range and is rejected.
+func (idx *LogIndex) Add(number uint64, hash ethcmn.Hash, logs []*ethtyp.Log) error {
+	idx.rangeMu.Lock()
+	defer idx.rangeMu.Unlock()
+
+	batch := idx.db.NewBatch()
+	oldLo, oldHi := idx.lo, idx.hi
+	lo, hi := number, number
+	switch {
+	case oldHi < oldLo:
+	case number+1 == oldLo:
+		if idx.horizon > 0 && oldHi-number+1 > idx.horizon {
+			return nil
+		}
+		hi = oldHi
+	case number < oldLo:
+		return fmt.Errorf("block %d is below the indexed blocks %d to %d", number, oldLo, oldHi)
+	case number <= oldHi+1:
+		lo = oldLo
+	}
+	if idx.horizon > 0 && hi-lo+1 > idx.horizon {
+		lo = hi - idx.horizon + 1
+	}
+	// drop the blocks that fell out of the range or are being replaced
+	for n := oldLo; oldHi >= oldLo && n <= oldHi; n++ {
+		if n < lo || n > hi || n == number {
+			if err := idx.deleteBlock(batch, n); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := json.Marshal(logs)
+	if err != nil {
+		return err
+	}
+	batch.Put(indexKey(logsPrefix, encodeNumber(number)), data)
+	batch.Put(indexKey(blockPrefix, hash.Bytes()), encodeNumber(number))
+	batch.Put(indexKey(hashPrefix, encodeNumber(number)), hash.Bytes())
+	for _, key := range logKeys(number, logs) {
+		batch.Put(key, nil)
+	}
+	batch.Put(indexRangeKey, append(encodeNumber(lo), encodeNumber(hi)...))
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	idx.lo, idx.hi = lo, hi
+	return nil
+}
+
+func logKeys(number uint64, logs []*ethtyp.Log) [][]byte {
+	suffix := encodeNumber(number)
+	var keys [][]byte
+	for _, log := range logs {
+		keys = append(keys, indexKey(addressPrefix, log.Address.Bytes(), suffix))
+		for i, topic := range log.Topics {
+			keys = append(keys, indexKey(topicPrefix, []byte{byte(i)}, topic.Bytes(), suffix))
+		}
+	}
+	return keys
+}
+
+func (idx *LogIndex) deleteBlock(batch ethdb.Batch, number uint64) error {
+	logs, err := idx.blockLogs(number)
+	if err != nil || logs == nil {
+		return err
+	}
+	for _, key := range logKeys(number, logs) {
+		batch.Delete(key)
+	}
+	if hash, err := idx.db.Get(indexKey(hashPrefix, encodeNumber(number))); err == nil {
+		batch.Delete(indexKey(blockPrefix, hash))
+	}
+	batch.Delete(indexKey(hashPrefix, encodeNumber(number)))
+	batch.Delete(indexKey(logsPrefix, encodeNumber(number)))
+	return nil
+}
+
+func (idx *LogIndex) blockLogs(number uint64) ([]*ethtyp.Log, error) {
+	key := indexKey(logsPrefix, encodeNumber(number))
+	if ok, err := idx.db.Has(key); !ok || err != nil {
+		return nil, err
+	}
+	data, err := idx.db.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	logs := []*ethtyp.Log{}
+	return logs, json.Unmarshal(data, &logs)
+}
+
+// BlockNumber returns the number of an indexed block.
+func (idx *LogIndex) BlockNumber(hash ethcmn.Hash) (uint64, bool) {
+	data, err := idx.db.Get(indexKey(blockPrefix, hash.Bytes()))
+	if err != nil || len(data) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(data), true
+}
+
+// blocksWith returns the blocks from..to that have a key under one of the
+// prefixes.
+func (idx *LogIndex) blocksWith(prefixes [][]byte, from, to uint64, blocks map[uint64]bool) {
+	for _, prefix := range prefixes {
+		it := idx.db.NewIterator(prefix, encodeNumber(from))
+		for it.Next() {
+			number := binary.BigEndian.Uint64(it.Key()[len(prefix):])
+			if number > to {
+				break
+			}
+			blocks[number] = true
+		}
+		it.Release()
+	}
+}
+
+// GetLogs answers a query over the indexed blocks from..to. The candidate
+// blocks come from the address index, or else from the index of the first
+// constrained topic position.
+func (idx *LogIndex) GetLogs(filter eth.FilterQuery, from, to uint64) ([]*ethtyp.Log, error) {
+	blocks := make(map[uint64]bool)
+	switch {
+	case len(filter.Addresses) > 0:
+		prefixes := make([][]byte, len(filter.Addresses))
+		for i, address := range filter.Addresses {
+			prefixes[i] = indexKey(addressPrefix, address.Bytes())
+		}
+		idx.blocksWith(prefixes, from, to, blocks)
+	default:
+		position := -1
+		for i, sub := range filter.Topics {
+			if len(sub) > 0 {
+				position = i
+				break
+			}
+		}
+		if position < 0 {
+			for n := from; n <= to; n++ {
+				blocks[n] = true
+			}
+			break
+		}
+		prefixes := make([][]byte, len(filter.Topics[position]))
+		for i, topic := range filter.Topics[position] {
+			prefixes[i] = indexKey(topicPrefix, []byte{byte(position)}, topic.Bytes())
+		}
+		idx.blocksWith(prefixes, from, to, blocks)
+	}
+
+	numbers := make([]uint64, 0, len(blocks))
+	for number := range blocks {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+	var logs []*ethtyp.Log
+	for _, number := range numbers {
+		blockLogs, err := idx.blockLogs(number)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, ethrpc.FilteLogs(blockLogs, filter)...)
+	}
+	return returnLogs(logs), nil
+}
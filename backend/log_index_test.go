@@ -0,0 +1,100 @@
+package backend
+
+import (
+	"testing"
+
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	"github.com/arcology-network/evm/ethdb/memorydb"
+)
+
+func TestLogIndex(t *testing.T) {
+	idx, err := NewLogIndex(memorydb.New(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := idx.Range(); ok {
+		t.Fatal("expected an empty index")
+	}
+
+	block := func(number uint64, salt byte) ([]*ethtyp.Log, ethcmn.Hash) {
+		logs := testLogs(4)
+		for _, log := range logs {
+			log.BlockNumber = number
+		}
+		return logs, ethcmn.BytesToHash([]byte{byte(number), salt})
+	}
+	for number := uint64(1); number <= 6; number++ {
+		logs, hash := block(number, 0)
+		if err := idx.Add(number, hash, logs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if lo, hi, _ := idx.Range(); lo != 3 || hi != 6 {
+		t.Fatalf("expected blocks 3 to 6 within the horizon, got %d to %d", lo, hi)
+	}
+	if idx.Covers(2, 6) || !idx.Covers(3, 6) {
+		t.Fatal("wrong coverage")
+	}
+	if _, ok := idx.BlockNumber(ethcmn.BytesToHash([]byte{2, 0})); ok {
+		t.Fatal("pruned block still indexed")
+	}
+
+	byAddress := eth.FilterQuery{Addresses: []ethcmn.Address{ethcmn.BytesToAddress([]byte{1})}}
+	if logs, err := idx.GetLogs(byAddress, 3, 6); err != nil || len(logs) != 4 {
+		t.Fatalf("expected a log per block by address, got %d: %v", len(logs), err)
+	}
+	byTopic := eth.FilterQuery{Topics: [][]ethcmn.Hash{{ethcmn.BytesToHash([]byte{2})}}}
+	if logs, err := idx.GetLogs(byTopic, 4, 5); err != nil || len(logs) != 2 {
+		t.Fatalf("expected a log per block by topic, got %d: %v", len(logs), err)
+	}
+
+	// a block at an indexed height replaces it and the blocks above it
+	logs, hash := block(5, 1)
+	if err := idx.Add(5, hash, logs[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if lo, hi, _ := idx.Range(); lo != 3 || hi != 5 {
+		t.Fatalf("expected blocks 3 to 5 after the reorg, got %d to %d", lo, hi)
+	}
+	if number, ok := idx.BlockNumber(hash); !ok || number != 5 {
+		t.Fatal("replacing block not indexed")
+	}
+	if _, ok := idx.BlockNumber(ethcmn.BytesToHash([]byte{5, 0})); ok {
+		t.Fatal("replaced block still indexed")
+	}
+	if logs, err := idx.GetLogs(eth.FilterQuery{}, 5, 5); err != nil || len(logs) != 1 {
+		t.Fatalf("expected the logs of the replacing block, got %d: %v", len(logs), err)
+	}
+
+	// a backfilled block below the indexed ones extends them while the
+	// horizon allows it, one further below is rejected
+	logs, hash = block(2, 0)
+	if err := idx.Add(2, hash, logs); err != nil {
+		t.Fatal(err)
+	}
+	if lo, hi, _ := idx.Range(); lo != 2 || hi != 5 {
+		t.Fatalf("expected blocks 2 to 5 after backfilling, got %d to %d", lo, hi)
+	}
+	logs, hash = block(1, 0)
+	if err := idx.Add(1, hash, logs); err != nil {
+		t.Fatal(err)
+	}
+	if lo, hi, _ := idx.Range(); lo != 2 || hi != 5 {
+		t.Fatalf("expected a full horizon to keep blocks 2 to 5, got %d to %d", lo, hi)
+	}
+
+	// a gap ahead restarts the index
+	logs, hash = block(8, 0)
+	if err := idx.Add(8, hash, logs); err != nil {
+		t.Fatal(err)
+	}
+	if lo, hi, _ := idx.Range(); lo != 8 || hi != 8 {
+		t.Fatalf("expected only block 8 after the gap, got %d to %d", lo, hi)
+	}
+	logs, hash = block(6, 0)
+	if err := idx.Add(6, hash, logs); err == nil {
+		t.Fatal("expected a block below a hole to be rejected")
+	}
+}
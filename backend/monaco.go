@@ -164,14 +164,16 @@ func (m *Monaco) GetTransactionByHash(hash ethcmn.Hash) (*ethrpc.RPCTransaction,
 	return response.Data.(*ethrpc.RPCTransaction), nil
 }
 
-func (m *Monaco) Call(msg eth.CallMsg) ([]byte, error) {
-	var response cmntyp.ExecutorResponses
+// callMessage converts a call into the executor's message, without a nonce
+// check. The executor's message has no access list, calls that need theirs
+// applied, as eth_createAccessList does, run on the Reexecutor instead.
+func callMessage(msg eth.CallMsg) thdtyp.Message {
 	var to *thdcmn.Address
 	if msg.To != nil {
 		addr := thdcmn.BytesToAddress(msg.To.Bytes())
 		to = &addr
 	}
-	message := thdtyp.NewMessage(
+	return thdtyp.NewMessage(
 		thdcmn.BytesToAddress(msg.From.Bytes()),
 		to,
 		1,
@@ -181,6 +183,11 @@ func (m *Monaco) Call(msg eth.CallMsg) ([]byte, error) {
 		msg.Data,
 		false,
 	)
+}
+
+func (m *Monaco) Call(msg eth.CallMsg) ([]byte, error) {
+	var response cmntyp.ExecutorResponses
+	message := callMessage(msg)
 	hash, _ := msgHash(&message)
 	err := m.executorClient.Call(context.Background(), "ExecTxs", &actor.Message{
 		Height: uint64(math.MaxUint64),
@@ -384,7 +391,7 @@ func (m *Monaco) NewPendingTransactionFilter() (ID, error) {
 	return m.filters.NewPendingTransactionFilter(), nil
 }
 func (m *Monaco) UninstallFilter(id ID) (bool, error) {
-	return m.filters.UninstallFilter(id), nil
+	return m.filters.UninstallFilter(id)
 }
 func (m *Monaco) GetFilterChanges(id ID) (interface{}, error) {
 	return m.filters.GetFilterChanges(id)
@@ -0,0 +1,14 @@
+package backend
+
+import (
+	"errors"
+
+	ethcmn "github.com/arcology-network/evm/common"
+)
+
+// GetProof fails: Monaco's storage answers none of the queries a proof needs,
+// the trie nodes of an account or of its storage. eth_getProof is not served
+// on Monaco.
+func (m *Monaco) GetProof(address ethcmn.Address, keys []string, number int64) (*AccountResult, error) {
+	return nil, errors.New("monaco storage serves no proofs")
+}
@@ -0,0 +1,56 @@
+package backend
+
+import (
+	"fmt"
+
+	"github.com/arcology-network/component-lib/ethrpc"
+	ethcmn "github.com/arcology-network/evm/common"
+)
+
+// GetBlockReceipts reads the receipts of a block a query per transaction,
+// storage having no query for the receipts of a whole block. Recent blocks
+// are served from the receipt cache before getting here.
+func (m *Monaco) GetBlockReceipts(number int64) ([]*BlockReceipt, error) {
+	block, err := m.GetBlockByNumber(number, true)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil || block.Header == nil {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+	return m.receiptsOf(block)
+}
+
+func (m *Monaco) GetBlockReceiptsByHash(hash ethcmn.Hash) ([]*BlockReceipt, error) {
+	block, err := m.GetBlockByHash(hash, true)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil || block.Header == nil {
+		return nil, fmt.Errorf("block %x not found", hash)
+	}
+	return m.receiptsOf(block)
+}
+
+// receiptsOf reads the receipts of the transactions of a full block one by
+// one.
+func (m *Monaco) receiptsOf(block *ethrpc.RPCBlock) ([]*BlockReceipt, error) {
+	receipts := make([]*BlockReceipt, len(block.Transactions))
+	for i, v := range block.Transactions {
+		tx, ok := v.(*ethrpc.RPCTransaction)
+		if !ok {
+			return nil, fmt.Errorf("block %d: unexpected transaction %T", block.Header.Number, v)
+		}
+		receipt, err := m.GetTransactionReceipt(tx.Hash)
+		if err != nil {
+			return nil, err
+		}
+		receipts[i] = &BlockReceipt{
+			Receipt:  receipt,
+			From:     tx.From,
+			To:       tx.To,
+			GasPrice: tx.GasPrice,
+		}
+	}
+	return receipts, nil
+}
@@ -0,0 +1,144 @@
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/common/hexutil"
+	"github.com/arcology-network/evm/core/state"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	ethcrp "github.com/arcology-network/evm/crypto"
+	"github.com/arcology-network/evm/ethdb/memorydb"
+	"github.com/arcology-network/evm/rlp"
+	"github.com/arcology-network/evm/trie"
+)
+
+// StorageResult is the proof of a storage slot.
+type StorageResult struct {
+	Key   string       `json:"key"`
+	Value *hexutil.Big `json:"value"`
+	Proof []string     `json:"proof"`
+}
+
+// AccountResult is the EIP-1186 proof of an account, as eth_getProof
+// returns it.
+type AccountResult struct {
+	Address      ethcmn.Address  `json:"address"`
+	AccountProof []string        `json:"accountProof"`
+	Balance      *hexutil.Big    `json:"balance"`
+	CodeHash     ethcmn.Hash     `json:"codeHash"`
+	Nonce        hexutil.Uint64  `json:"nonce"`
+	StorageHash  ethcmn.Hash     `json:"storageHash"`
+	StorageProof []StorageResult `json:"storageProof"`
+}
+
+// proofStrings renders trie nodes as hex strings.
+func proofStrings(nodes [][]byte) []string {
+	proof := make([]string, len(nodes))
+	for i, node := range nodes {
+		proof[i] = hexutil.Encode(node)
+	}
+	return proof
+}
+
+// accountProof builds the proof of an account and some of its slots from
+// statedb.
+func accountProof(statedb *state.StateDB, address ethcmn.Address, keys []string) (*AccountResult, error) {
+	nodes, err := statedb.GetProof(address)
+	if err != nil {
+		return nil, err
+	}
+	storageHash := ethtyp.EmptyRootHash
+	storage := statedb.StorageTrie(address)
+	if storage != nil {
+		storageHash = storage.Hash()
+	}
+	result := &AccountResult{
+		Address:      address,
+		AccountProof: proofStrings(nodes),
+		Balance:      (*hexutil.Big)(statedb.GetBalance(address)),
+		CodeHash:     statedb.GetCodeHash(address),
+		Nonce:        hexutil.Uint64(statedb.GetNonce(address)),
+		StorageHash:  storageHash,
+		StorageProof: make([]StorageResult, len(keys)),
+	}
+	for i, key := range keys {
+		slot := ethcmn.HexToHash(key)
+		var nodes [][]byte
+		if storage != nil {
+			if nodes, err = statedb.GetStorageProof(address, slot); err != nil {
+				return nil, err
+			}
+		}
+		result.StorageProof[i] = StorageResult{
+			Key:   key,
+			Value: (*hexutil.Big)(statedb.GetState(address, slot).Big()),
+			Proof: proofStrings(nodes),
+		}
+	}
+	return result, nil
+}
+
+// verifyNodes looks key up in the trie of root given by the proof nodes, a
+// nil value means the proof shows the key is absent.
+func verifyNodes(root ethcmn.Hash, key []byte, proof []string) ([]byte, error) {
+	if root == ethtyp.EmptyRootHash && len(proof) == 0 {
+		return nil, nil
+	}
+	db := memorydb.New()
+	for _, str := range proof {
+		node, err := hexutil.Decode(str)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.Put(ethcrp.Keccak256(node), node); err != nil {
+			return nil, err
+		}
+	}
+	return trie.VerifyProof(root, ethcrp.Keccak256(key), db)
+}
+
+// VerifyProof checks an eth_getProof result against the state root of the
+// block it was taken at.
+func VerifyProof(stateRoot ethcmn.Hash, result *AccountResult) error {
+	value, err := verifyNodes(stateRoot, result.Address.Bytes(), result.AccountProof)
+	if err != nil {
+		return fmt.Errorf("account proof: %v", err)
+	}
+	account := state.Account{Balance: new(big.Int), Root: ethtyp.EmptyRootHash, CodeHash: ethcrp.Keccak256(nil)}
+	if value != nil {
+		if err := rlp.DecodeBytes(value, &account); err != nil {
+			return fmt.Errorf("account proof: %v", err)
+		}
+	}
+	if account.Nonce != uint64(result.Nonce) || account.Balance.Cmp(result.Balance.ToInt()) != 0 {
+		return fmt.Errorf("account proof: nonce %d balance %v, proven nonce %d balance %v", result.Nonce, result.Balance, account.Nonce, account.Balance)
+	}
+	if account.Root != result.StorageHash {
+		return fmt.Errorf("account proof: storage hash %x, proven %x", result.StorageHash, account.Root)
+	}
+	if value != nil && !bytes.Equal(account.CodeHash, result.CodeHash.Bytes()) {
+		return fmt.Errorf("account proof: code hash %x, proven %x", result.CodeHash, account.CodeHash)
+	}
+
+	for _, slot := range result.StorageProof {
+		value, err := verifyNodes(result.StorageHash, ethcmn.HexToHash(slot.Key).Bytes(), slot.Proof)
+		if err != nil {
+			return fmt.Errorf("storage proof of %s: %v", slot.Key, err)
+		}
+		proven := new(big.Int)
+		if value != nil {
+			var content []byte
+			if err := rlp.DecodeBytes(value, &content); err != nil {
+				return fmt.Errorf("storage proof of %s: %v", slot.Key, err)
+			}
+			proven.SetBytes(content)
+		}
+		if proven.Cmp(slot.Value.ToInt()) != 0 {
+			return fmt.Errorf("storage proof of %s: value %v, proven %v", slot.Key, slot.Value, proven)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package backend
+
+import (
+	"math/big"
+	"sync"
+
+	ethcmn "github.com/arcology-network/evm/common"
+	ethtyp "github.com/arcology-network/evm/core/types"
+)
+
+// BlockReceipt is a receipt with the fields of its transaction that the
+// receipt json carries besides the receipt itself.
+type BlockReceipt struct {
+	Receipt  *ethtyp.Receipt
+	From     ethcmn.Address
+	To       *ethcmn.Address
+	GasPrice *big.Int
+}
+
+type cachedReceipts struct {
+	hash     ethcmn.Hash
+	receipts []*BlockReceipt
+}
+
+// ReceiptCache keeps the receipts of the most recent blocks, so that they
+// are served without a storage query.
+type ReceiptCache struct {
+	mu      sync.RWMutex
+	horizon uint64
+	blocks  map[uint64]*cachedReceipts
+	hashes  map[ethcmn.Hash]uint64
+	txs     map[ethcmn.Hash]uint64
+}
+
+func NewReceiptCache(horizon uint64) *ReceiptCache {
+	return &ReceiptCache{
+		horizon: horizon,
+		blocks:  make(map[uint64]*cachedReceipts),
+		hashes:  make(map[ethcmn.Hash]uint64),
+		txs:     make(map[ethcmn.Hash]uint64),
+	}
+}
+
+// Add keeps the receipts of a block and forgets the blocks past the horizon.
+// A block at a height already kept replaces it and drops the blocks above,
+// which belonged to the same abandoned branch.
+func (c *ReceiptCache) Add(number uint64, hash ethcmn.Hash, receipts []*BlockReceipt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for height, block := range c.blocks {
+		if height >= number || (number >= c.horizon && height <= number-c.horizon) {
+			delete(c.hashes, block.hash)
+			delete(c.blocks, height)
+			for _, receipt := range block.receipts {
+				if c.txs[receipt.Receipt.TxHash] == height {
+					delete(c.txs, receipt.Receipt.TxHash)
+				}
+			}
+		}
+	}
+	c.blocks[number] = &cachedReceipts{hash: hash, receipts: receipts}
+	c.hashes[hash] = number
+	for _, receipt := range receipts {
+		c.txs[receipt.Receipt.TxHash] = number
+	}
+}
+
+// ByNumber returns the receipts of a block, false if it is not kept.
+func (c *ReceiptCache) ByNumber(number uint64) ([]*BlockReceipt, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	block, ok := c.blocks[number]
+	if !ok {
+		return nil, false
+	}
+	return block.receipts, true
+}
+
+// ByHash returns the receipts of a block, false if it is not kept.
+func (c *ReceiptCache) ByHash(hash ethcmn.Hash) ([]*BlockReceipt, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	number, ok := c.hashes[hash]
+	if !ok {
+		return nil, false
+	}
+	return c.blocks[number].receipts, true
+}
+
+// ByTransaction returns the receipt of a transaction, false if its block is
+// not kept.
+func (c *ReceiptCache) ByTransaction(hash ethcmn.Hash) (*BlockReceipt, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	number, ok := c.txs[hash]
+	if !ok {
+		return nil, false
+	}
+	for _, receipt := range c.blocks[number].receipts {
+		if receipt.Receipt.TxHash == hash {
+			return receipt, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,45 @@
+package backend
+
+import (
+	"testing"
+
+	ethcmn "github.com/arcology-network/evm/common"
+	ethtyp "github.com/arcology-network/evm/core/types"
+)
+
+func TestReceiptCache(t *testing.T) {
+	cache := NewReceiptCache(3)
+	receipts := func(number uint64) []*BlockReceipt {
+		return []*BlockReceipt{{Receipt: &ethtyp.Receipt{TxHash: ethcmn.Hash{byte(number)}}}}
+	}
+	for number := uint64(1); number <= 4; number++ {
+		cache.Add(number, ethcmn.Hash{0xb, byte(number)}, receipts(number))
+	}
+	if _, ok := cache.ByNumber(1); ok {
+		t.Fatal("expected block 1 to be past the horizon")
+	}
+	if _, ok := cache.ByHash(ethcmn.Hash{0xb, 1}); ok {
+		t.Fatal("expected the hash of block 1 to be forgotten")
+	}
+	if got, ok := cache.ByHash(ethcmn.Hash{0xb, 3}); !ok || got[0].Receipt.TxHash != (ethcmn.Hash{3}) {
+		t.Fatalf("unexpected receipts of block 3 %v", got)
+	}
+
+	// a new block 3 abandons the old blocks 3 and 4
+	cache.Add(3, ethcmn.Hash{0xc, 3}, receipts(3))
+	if _, ok := cache.ByNumber(4); ok {
+		t.Fatal("expected block 4 to be dropped")
+	}
+	if _, ok := cache.ByHash(ethcmn.Hash{0xb, 3}); ok {
+		t.Fatal("expected the old block 3 to be dropped")
+	}
+	if _, ok := cache.ByNumber(2); !ok {
+		t.Fatal("expected block 2 to be kept")
+	}
+	if receipt, ok := cache.ByTransaction(ethcmn.Hash{3}); !ok || receipt.Receipt.TxHash != (ethcmn.Hash{3}) {
+		t.Fatalf("expected the receipt of the new block 3, got %v", receipt)
+	}
+	if _, ok := cache.ByTransaction(ethcmn.Hash{4}); ok {
+		t.Fatal("expected the transaction of block 4 to be forgotten")
+	}
+}
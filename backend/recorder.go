@@ -0,0 +1,302 @@
+package backend
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"sync"
+
+	"github.com/arcology-network/component-lib/ethrpc"
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+	ethtyp "github.com/arcology-network/evm/core/types"
+)
+
+// Record is one line of a recorded session, a single EthereumAPI call with
+// its arguments and outcome.
+type Record struct {
+	Method string            `json:"method"`
+	Args   []json.RawMessage `json:"args,omitempty"`
+	Result json.RawMessage   `json:"result,omitempty"`
+	Error  string            `json:"error,omitempty"`
+}
+
+// Recorder decorates an EthereumAPI and appends every call to a JSONL file.
+type Recorder struct {
+	api     EthereumAPI
+	file    *os.File
+	encoder *json.Encoder
+	fileMu  sync.Mutex
+}
+
+func NewRecorder(api EthereumAPI, path string) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{
+		api:     api,
+		file:    file,
+		encoder: json.NewEncoder(file),
+	}, nil
+}
+
+func (r *Recorder) Close() error {
+	r.fileMu.Lock()
+	defer r.fileMu.Unlock()
+	return r.file.Close()
+}
+
+func (r *Recorder) record(method string, result interface{}, err error, args ...interface{}) {
+	rec := Record{Method: method}
+	for _, arg := range args {
+		raw, _ := json.Marshal(arg)
+		rec.Args = append(rec.Args, raw)
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	} else {
+		rec.Result, _ = json.Marshal(result)
+	}
+
+	r.fileMu.Lock()
+	defer r.fileMu.Unlock()
+	r.encoder.Encode(&rec)
+}
+
+func (r *Recorder) BlockNumber() (uint64, error) {
+	number, err := r.api.BlockNumber()
+	r.record("BlockNumber", number, err)
+	return number, err
+}
+
+func (r *Recorder) GetBlockByNumber(number int64, fullTx bool) (*ethrpc.RPCBlock, error) {
+	block, err := r.api.GetBlockByNumber(number, fullTx)
+	r.record("GetBlockByNumber", block, err, number, fullTx)
+	return block, err
+}
+
+func (r *Recorder) GetBlockByHash(hash ethcmn.Hash, fullTx bool) (*ethrpc.RPCBlock, error) {
+	block, err := r.api.GetBlockByHash(hash, fullTx)
+	r.record("GetBlockByHash", block, err, hash, fullTx)
+	return block, err
+}
+
+func (r *Recorder) GetCode(address ethcmn.Address, number int64) ([]byte, error) {
+	code, err := r.api.GetCode(address, number)
+	r.record("GetCode", code, err, address, number)
+	return code, err
+}
+
+func (r *Recorder) GetBalance(address ethcmn.Address, number int64) (*big.Int, error) {
+	balance, err := r.api.GetBalance(address, number)
+	r.record("GetBalance", balance, err, address, number)
+	return balance, err
+}
+
+func (r *Recorder) GetTransactionCount(address ethcmn.Address, number int64) (uint64, error) {
+	nonce, err := r.api.GetTransactionCount(address, number)
+	r.record("GetTransactionCount", nonce, err, address, number)
+	return nonce, err
+}
+
+func (r *Recorder) GetStorageAt(address ethcmn.Address, key string, number int64) ([]byte, error) {
+	value, err := r.api.GetStorageAt(address, key, number)
+	r.record("GetStorageAt", value, err, address, key, number)
+	return value, err
+}
+
+func (r *Recorder) GetProof(address ethcmn.Address, keys []string, number int64) (*AccountResult, error) {
+	result, err := r.api.GetProof(address, keys, number)
+	r.record("GetProof", result, err, address, keys, number)
+	return result, err
+}
+
+func (r *Recorder) EstimateGas(msg eth.CallMsg) (uint64, error) {
+	gas, err := r.api.EstimateGas(msg)
+	r.record("EstimateGas", gas, err, msg)
+	return gas, err
+}
+
+func (r *Recorder) GasPrice() (*big.Int, error) {
+	price, err := r.api.GasPrice()
+	r.record("GasPrice", price, err)
+	return price, err
+}
+
+func (r *Recorder) GetTransactionByHash(hash ethcmn.Hash) (*ethrpc.RPCTransaction, error) {
+	tx, err := r.api.GetTransactionByHash(hash)
+	r.record("GetTransactionByHash", tx, err, hash)
+	return tx, err
+}
+
+func (r *Recorder) Call(msg eth.CallMsg) ([]byte, error) {
+	ret, err := r.api.Call(msg)
+	r.record("Call", ret, err, msg)
+	return ret, err
+}
+
+func (r *Recorder) SendRawTransaction(rawTx []byte) (ethcmn.Hash, error) {
+	hash, err := r.api.SendRawTransaction(rawTx)
+	r.record("SendRawTransaction", hash, err, rawTx)
+	return hash, err
+}
+
+func (r *Recorder) GetTransactionReceipt(hash ethcmn.Hash) (*ethtyp.Receipt, error) {
+	receipt, err := r.api.GetTransactionReceipt(hash)
+	r.record("GetTransactionReceipt", recordedReceipt(receipt), err, hash)
+	return receipt, err
+}
+
+func (r *Recorder) GetBlockReceipts(number int64) ([]*BlockReceipt, error) {
+	receipts, err := r.api.GetBlockReceipts(number)
+	r.record("GetBlockReceipts", recordedReceipts(receipts), err, number)
+	return receipts, err
+}
+
+func (r *Recorder) GetBlockReceiptsByHash(hash ethcmn.Hash) ([]*BlockReceipt, error) {
+	receipts, err := r.api.GetBlockReceiptsByHash(hash)
+	r.record("GetBlockReceiptsByHash", recordedReceipts(receipts), err, hash)
+	return receipts, err
+}
+
+// recordedReceipt gives the receipt json decoder the logs field it insists
+// on.
+func recordedReceipt(receipt *ethtyp.Receipt) *ethtyp.Receipt {
+	if receipt == nil || receipt.Logs != nil {
+		return receipt
+	}
+	copied := *receipt
+	copied.Logs = []*ethtyp.Log{}
+	return &copied
+}
+
+func recordedReceipts(receipts []*BlockReceipt) []*BlockReceipt {
+	recorded := make([]*BlockReceipt, len(receipts))
+	for i, receipt := range receipts {
+		copied := *receipt
+		copied.Receipt = recordedReceipt(receipt.Receipt)
+		recorded[i] = &copied
+	}
+	return recorded
+}
+
+func (r *Recorder) GetLogs(filter eth.FilterQuery) ([]*ethtyp.Log, error) {
+	logs, err := r.api.GetLogs(filter)
+	r.record("GetLogs", logs, err, filter)
+	return logs, err
+}
+
+func (r *Recorder) GetBlockTransactionCountByHash(hash ethcmn.Hash) (int, error) {
+	count, err := r.api.GetBlockTransactionCountByHash(hash)
+	r.record("GetBlockTransactionCountByHash", count, err, hash)
+	return count, err
+}
+
+func (r *Recorder) GetBlockTransactionCountByNumber(number int64) (int, error) {
+	count, err := r.api.GetBlockTransactionCountByNumber(number)
+	r.record("GetBlockTransactionCountByNumber", count, err, number)
+	return count, err
+}
+
+func (r *Recorder) GetTransactionByBlockHashAndIndex(hash ethcmn.Hash, index int) (*ethrpc.RPCTransaction, error) {
+	tx, err := r.api.GetTransactionByBlockHashAndIndex(hash, index)
+	r.record("GetTransactionByBlockHashAndIndex", tx, err, hash, index)
+	return tx, err
+}
+
+func (r *Recorder) GetTransactionByBlockNumberAndIndex(number int64, index int) (*ethrpc.RPCTransaction, error) {
+	tx, err := r.api.GetTransactionByBlockNumberAndIndex(number, index)
+	r.record("GetTransactionByBlockNumberAndIndex", tx, err, number, index)
+	return tx, err
+}
+
+func (r *Recorder) GetUncleCountByBlockHash(hash ethcmn.Hash) (int, error) {
+	count, err := r.api.GetUncleCountByBlockHash(hash)
+	r.record("GetUncleCountByBlockHash", count, err, hash)
+	return count, err
+}
+
+func (r *Recorder) GetUncleCountByBlockNumber(number int64) (int, error) {
+	count, err := r.api.GetUncleCountByBlockNumber(number)
+	r.record("GetUncleCountByBlockNumber", count, err, number)
+	return count, err
+}
+
+func (r *Recorder) SubmitWork() (bool, error) {
+	ok, err := r.api.SubmitWork()
+	r.record("SubmitWork", ok, err)
+	return ok, err
+}
+
+func (r *Recorder) SubmitHashrate() (bool, error) {
+	ok, err := r.api.SubmitHashrate()
+	r.record("SubmitHashrate", ok, err)
+	return ok, err
+}
+
+func (r *Recorder) Hashrate() (int, error) {
+	rate, err := r.api.Hashrate()
+	r.record("Hashrate", rate, err)
+	return rate, err
+}
+
+func (r *Recorder) GetWork() ([]string, error) {
+	work, err := r.api.GetWork()
+	r.record("GetWork", work, err)
+	return work, err
+}
+
+func (r *Recorder) ProtocolVersion() (int, error) {
+	version, err := r.api.ProtocolVersion()
+	r.record("ProtocolVersion", version, err)
+	return version, err
+}
+
+func (r *Recorder) Syncing() (bool, error) {
+	ok, err := r.api.Syncing()
+	r.record("Syncing", ok, err)
+	return ok, err
+}
+
+func (r *Recorder) Proposer() (bool, error) {
+	ok, err := r.api.Proposer()
+	r.record("Proposer", ok, err)
+	return ok, err
+}
+
+func (r *Recorder) NewFilter(filter eth.FilterQuery) (ID, error) {
+	id, err := r.api.NewFilter(filter)
+	r.record("NewFilter", id, err, filter)
+	return id, err
+}
+
+func (r *Recorder) NewBlockFilter() (ID, error) {
+	id, err := r.api.NewBlockFilter()
+	r.record("NewBlockFilter", id, err)
+	return id, err
+}
+
+func (r *Recorder) NewPendingTransactionFilter() (ID, error) {
+	id, err := r.api.NewPendingTransactionFilter()
+	r.record("NewPendingTransactionFilter", id, err)
+	return id, err
+}
+
+func (r *Recorder) UninstallFilter(id ID) (bool, error) {
+	ok, err := r.api.UninstallFilter(id)
+	r.record("UninstallFilter", ok, err, id)
+	return ok, err
+}
+
+func (r *Recorder) GetFilterChanges(id ID) (interface{}, error) {
+	changes, err := r.api.GetFilterChanges(id)
+	r.record("GetFilterChanges", changes, err, id)
+	return changes, err
+}
+
+func (r *Recorder) GetFilterLogs(id ID) ([]*ethtyp.Log, error) {
+	logs, err := r.api.GetFilterLogs(id)
+	r.record("GetFilterLogs", logs, err, id)
+	return logs, err
+}
@@ -0,0 +1,85 @@
+package backend
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/arcology-network/component-lib/ethrpc"
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	recorder, err := NewRecorder(NewEthereumAPIMock(big.NewInt(1)), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := ethcmn.HexToAddress("0x57de3b28c55095e5ca67a8e20fa9d7d5d9aef891")
+	balance, _ := recorder.GetBalance(address, 10)
+	receipt, _ := recorder.GetTransactionReceipt(ethcmn.HexToHash("0x01"))
+	recorder.Close()
+
+	replayer, err := NewReplayer(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayedBalance, err := replayer.GetBalance(address, 10)
+	if err != nil || replayedBalance.Cmp(balance) != 0 {
+		t.Fatalf("balance mismatch: %v %v", replayedBalance, err)
+	}
+	replayedReceipt, err := replayer.GetTransactionReceipt(ethcmn.HexToHash("0x01"))
+	if err != nil || replayedReceipt.TxHash != receipt.TxHash || replayedReceipt.GasUsed != receipt.GasUsed {
+		t.Fatalf("receipt mismatch: %v %v", replayedReceipt, err)
+	}
+	if _, err := replayer.GetBalance(address, 11); err == nil {
+		t.Fatal("expected strict replay to fail on an unrecorded call")
+	}
+}
+
+func TestReplayBlocks(t *testing.T) {
+	chain := NewDevChain(big.NewInt(1), nil, 0, nil)
+	sender, to := ethcmn.Address{1}, ethcmn.Address{2}
+	chain.SetBalance(sender, big.NewInt(1e18))
+	chain.ImpersonateAccount(sender)
+	hash, err := chain.SendImpersonatedTransaction(eth.CallMsg{From: sender, To: &to})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	recorder, err := NewRecorder(chain, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.GetBlockByNumber(1, true)
+	recorder.GetBlockByNumber(1, false)
+	recorder.Close()
+
+	replayer, err := NewReplayer(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, err := replayer.GetBlockByNumber(1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx, ok := full.Transactions[0].(*ethrpc.RPCTransaction); !ok || tx.Hash != hash || tx.From != sender || *tx.To != to {
+		t.Fatalf("expected the transaction back, got %#v", full.Transactions[0])
+	}
+	hashes, err := replayer.GetBlockByNumber(1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := hashes.Transactions[0].(ethcmn.Hash); !ok || got != hash {
+		t.Fatalf("expected the transaction hash back, got %#v", hashes.Transactions[0])
+	}
+
+	if block, err := replayer.GetBlockByNumber(2, false); err == nil || block != nil {
+		t.Fatal("expected an unrecorded block not to be found")
+	}
+	if balance, err := replayer.GetBalance(sender, 1); err != nil || balance.Sign() != 0 {
+		t.Fatalf("expected a zero balance for an unrecorded call, got %v %v", balance, err)
+	}
+}
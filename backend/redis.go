@@ -0,0 +1,66 @@
+package backend
+
+import (
+	"time"
+
+	redis "gopkg.in/redis.v5"
+)
+
+// RedisFilterState shares the filter state between replicated instances
+// through a Redis server. Records expire ttl after they were last written,
+// so the filters of an instance that died are dropped eventually; a live
+// instance writes a record again whenever the filter is polled past a new
+// block.
+type RedisFilterState struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisFilterState connects to the server at addr, a zero ttl keeps the
+// records until they are deleted.
+func NewRedisFilterState(addr string, ttl time.Duration) (*RedisFilterState, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:         addr,
+		DialTimeout:  5 * time.Second,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+	})
+	if err := client.Ping().Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &RedisFilterState{client: client, ttl: ttl}, nil
+}
+
+func (s *RedisFilterState) Put(key, value []byte) error {
+	return s.client.Set(string(key), value, s.ttl).Err()
+}
+
+func (s *RedisFilterState) Get(key []byte) ([]byte, error) {
+	value, err := s.client.Get(string(key)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return value, err
+}
+
+func (s *RedisFilterState) Delete(key []byte) error {
+	return s.client.Del(string(key)).Err()
+}
+
+func (s *RedisFilterState) Keys(prefix []byte) ([][]byte, error) {
+	var keys [][]byte
+	var cursor uint64
+	for {
+		batch, next, err := s.client.Scan(cursor, string(prefix)+"*", 1000).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range batch {
+			keys = append(keys, []byte(key))
+		}
+		if cursor = next; cursor == 0 {
+			return keys, nil
+		}
+	}
+}
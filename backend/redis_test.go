@@ -0,0 +1,205 @@
+package backend
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+)
+
+// readRedisCommand reads one command in the Redis protocol, an array of bulk
+// strings.
+func readRedisCommand(r *bufio.Reader) ([]string, error) {
+	var n int
+	if _, err := fmt.Fscanf(r, "*%d\r\n", &n); err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		var size int
+		if _, err := fmt.Fscanf(r, "$%d\r\n", &size); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+// startRedisStandIn serves the commands used by RedisFilterState from a map,
+// recording the expiry each key was set with.
+func startRedisStandIn(t *testing.T) (string, func(key string) string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var (
+		mu      sync.Mutex
+		data    = make(map[string]string)
+		expires = make(map[string]string)
+	)
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			args, err := readRedisCommand(r)
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(args[0])
+
+			mu.Lock()
+			switch cmd {
+			case "PING":
+				fmt.Fprint(conn, "+PONG\r\n")
+			case "SET":
+				data[args[1]] = args[2]
+				expires[args[1]] = strings.Join(args[3:], " ")
+				fmt.Fprint(conn, "+OK\r\n")
+			case "GET":
+				if value, ok := data[args[1]]; ok {
+					fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(value), value)
+				} else {
+					fmt.Fprint(conn, "$-1\r\n")
+				}
+			case "DEL":
+				delete(data, args[1])
+				fmt.Fprint(conn, ":1\r\n")
+			case "SCAN":
+				prefix := strings.TrimSuffix(args[3], "*")
+				var keys []string
+				for key := range data {
+					if strings.HasPrefix(key, prefix) {
+						keys = append(keys, key)
+					}
+				}
+				fmt.Fprintf(conn, "*2\r\n$1\r\n0\r\n*%d\r\n", len(keys))
+				for _, key := range keys {
+					fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(key), key)
+				}
+			default:
+				fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", cmd)
+			}
+			mu.Unlock()
+		}
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	expiry := func(key string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		return expires[key]
+	}
+	return listener.Addr().String(), expiry
+}
+
+// serveFilters answers the forwarded filter calls like the rpc server does,
+// from the local filters only.
+func serveFilters(fs *Filters) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(FilterHopHeader) == "" {
+			http.Error(w, "not forwarded by a peer", http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			Method string `json:"method"`
+			Params []ID   `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		var result interface{}
+		switch req.Method {
+		case "eth_getFilterChanges":
+			result, _ = fs.GetFilterChanges(req.Params[0])
+		case "eth_uninstallFilter":
+			result, _ = fs.UninstallFilter(req.Params[0])
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": result})
+	}))
+}
+
+func TestSharedFilters(t *testing.T) {
+	defer SetInstanceHint(0)
+	addr, expiry := startRedisStandIn(t)
+	state, err := NewRedisFilterState(addr, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := NewEthereumAPIMock(big.NewInt(1))
+
+	SetInstanceHint(1)
+	owner := NewFilters(time.Minute, FilterLimits{})
+	if err := owner.Restore(state, api, 100); err != nil {
+		t.Fatal(err)
+	}
+	blocks := owner.NewBlockFilter()
+	address := ethcmn.HexToAddress("0x1234")
+	logs := owner.NewFilter(eth.FilterQuery{Addresses: []ethcmn.Address{address}})
+	owner.OnLogsArrived(1, nil, ethcmn.Hash{1}, ethcmn.Hash{})
+	server := serveFilters(owner)
+	defer server.Close()
+
+	SetInstanceHint(2)
+	other := NewFilters(time.Minute, FilterLimits{})
+	if err := other.Restore(state, api, 100); err != nil {
+		t.Fatal(err)
+	}
+	other.SetPeers([]string{"", server.URL})
+	if ex := expiry(string(filterKey(blocks))); ex != "ex 3600" {
+		t.Fatalf("expected the record to expire in an hour, got %q", ex)
+	}
+
+	if _, err := other.GetFilterChanges(blocks); err == nil {
+		t.Fatal("expected a filter of the owner not to be polled here")
+	}
+	var changes json.RawMessage
+	if forwarded, err := other.Forward(blocks, "eth_getFilterChanges", "", &changes); !forwarded || err != nil {
+		t.Fatalf("expected the call to be forwarded to the owner: %v", err)
+	}
+	var hashes []ethcmn.Hash
+	if err := json.Unmarshal(changes, &hashes); err != nil {
+		t.Fatal(err)
+	}
+	if len(hashes) != 1 || hashes[0] != (ethcmn.Hash{1}) {
+		t.Fatalf("expected the owner's block hash, got %v", hashes)
+	}
+
+	crit, err := other.GetFilterLogsCrit(logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(crit.Addresses) != 1 || crit.Addresses[0] != address {
+		t.Fatalf("expected the shared criteria, got %v", crit.Addresses)
+	}
+
+	var found bool
+	if _, err := other.Forward(blocks, "eth_uninstallFilter", "", &found); err != nil || !found {
+		t.Fatalf("expected the owner to uninstall the filter: %v", err)
+	}
+	if _, err := owner.GetFilterChanges(blocks); err == nil {
+		t.Fatal("expected the filter to be gone")
+	}
+}
@@ -0,0 +1,158 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/arcology-network/component-lib/ethrpc"
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/core"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	"github.com/arcology-network/evm/core/vm"
+	"github.com/arcology-network/evm/params"
+)
+
+// Reexecutor traces transactions by running them again with the evm in this
+// process, on state read through an EthereumAPI as of the block they ran in,
+// and simulates calls the same way. It serves backends whose executor can
+// neither attach a tracer nor keep state across calls, such as Monaco.
+//
+// A block is replayed one transaction after the other in block order. Monaco
+// executes them in parallel and reverts the ones that conflict, so a replay
+// only matches what happened for blocks without conflicts, the traces of the
+// others may show effects the chain discarded. The state comes from the
+// backend by block number, see remoteDatabase, and is not proven.
+type Reexecutor struct {
+	api    EthereumAPI
+	config *params.ChainConfig
+}
+
+func NewReexecutor(api EthereumAPI, chainID *big.Int) *Reexecutor {
+	return &Reexecutor{
+		api:    api,
+		config: chainConfig(chainID),
+	}
+}
+
+// header resolves a block number or one of the ethrpc block tags.
+func (r *Reexecutor) header(number int64) (*ethtyp.Header, error) {
+	block, err := r.api.GetBlockByNumber(number, false)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil || block.Header == nil {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+	return block.Header, nil
+}
+
+func (r *Reexecutor) blockContext(header *ethtyp.Header) vm.BlockContext {
+	return headerContext(header, func(number uint64) ethcmn.Hash {
+		block, err := r.api.GetBlockByNumber(int64(number), false)
+		if err != nil || block == nil || block.Header == nil {
+			return ethcmn.Hash{}
+		}
+		return block.Header.Hash()
+	})
+}
+
+// TraceTransaction replays the block of a transaction up to it on the state
+// of the previous block and traces the transaction itself.
+func (r *Reexecutor) TraceTransaction(hash ethcmn.Hash, config *TraceConfig) (json.RawMessage, error) {
+	tx, err := r.api.GetTransactionByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil || tx.BlockNumber == nil || tx.TransactionIndex == nil {
+		return nil, fmt.Errorf("transaction %x not found", hash)
+	}
+	index := int(*tx.TransactionIndex)
+	traces, err := r.replayBlock(tx.BlockNumber.Int64(), index, config)
+	if err != nil {
+		return nil, err
+	}
+	return traces[index].Result, nil
+}
+
+// TraceBlock replays a block on the state of the previous block and traces
+// each of its transactions.
+func (r *Reexecutor) TraceBlock(number int64, config *TraceConfig) ([]*TxTrace, error) {
+	return r.replayBlock(number, -1, config)
+}
+
+// replayBlock applies the transactions of a block up to the one at index
+// last, or all of them if last is negative, and traces them.
+func (r *Reexecutor) replayBlock(number int64, last int, config *TraceConfig) ([]*TxTrace, error) {
+	block, err := r.api.GetBlockByNumber(number, true)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil || block.Header == nil {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+	if block.Header.Number.Sign() == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	if last >= len(block.Transactions) {
+		return nil, fmt.Errorf("block %d has no transaction %d", block.Header.Number, last)
+	}
+
+	msgs, hashes := make([]ethtyp.Message, len(block.Transactions)), make([]ethcmn.Hash, len(block.Transactions))
+	for i, v := range block.Transactions {
+		tx, ok := v.(*ethrpc.RPCTransaction)
+		if !ok {
+			return nil, fmt.Errorf("block %d: unexpected transaction %T", block.Header.Number, v)
+		}
+		msgs[i] = ethtyp.NewMessage(tx.From, tx.To, tx.Nonce, tx.Value, tx.Gas, tx.GasPrice, tx.Input, nil, true)
+		hashes[i] = tx.Hash
+	}
+
+	parent, err := r.header(block.Header.Number.Int64() - 1)
+	if err != nil {
+		return nil, err
+	}
+	statedb, err := remoteState(r.api, parent)
+	if err != nil {
+		return nil, err
+	}
+	return replayMessages(r.config, r.blockContext(block.Header), statedb, msgs, hashes, last, config)
+}
+
+// TraceCall traces a call on the state as of the given block, gas defaulting
+// to the block gas limit.
+func (r *Reexecutor) TraceCall(msg eth.CallMsg, number int64, config *TraceConfig) (json.RawMessage, error) {
+	header, err := r.header(number)
+	if err != nil {
+		return nil, err
+	}
+	statedb, err := remoteState(r.api, header)
+	if err != nil {
+		return nil, err
+	}
+	message := callAsMessage(msg, statedb.GetNonce(msg.From), header.GasLimit)
+	trace, err := traceMessage(r.config, r.blockContext(header), statedb, message, new(core.GasPool).AddGas(message.Gas()), config)
+	if err != nil {
+		return nil, err
+	}
+	return trace, statedb.Error()
+}
+
+// Simulate runs simulated blocks on the state as of the given block. It does
+// not go through Monaco's executor like Call: ExecTxs runs each request on
+// the latest state and answers with return data only, with no logs, gas used
+// or status per call, no block or state overrides and no state carried from
+// one request to the next.
+func (r *Reexecutor) Simulate(number int64, blocks []*SimBlock, validation bool) ([]*SimBlockResult, error) {
+	header, err := r.header(number)
+	if err != nil {
+		return nil, err
+	}
+	statedb, err := remoteState(r.api, header)
+	if err != nil {
+		return nil, err
+	}
+	return simulateBlocks(r.config, r.blockContext, statedb, header, blocks, validation)
+}
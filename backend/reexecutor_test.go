@@ -0,0 +1,98 @@
+package backend
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+)
+
+// TestReexecutor traces the transactions of a dev chain again on the state it
+// serves, the traces must match those of the chain itself.
+func TestReexecutor(t *testing.T) {
+	chain := NewDevChain(big.NewInt(1), nil, 0, nil)
+	sender, contract := ethcmn.Address{1}, ethcmn.Address{2}
+	chain.SetBalance(sender, big.NewInt(1e18))
+	// SSTORE(0, SLOAD(0) + 1) STOP
+	chain.SetCode(contract, []byte{0x60, 0, 0x54, 0x60, 1, 0x01, 0x60, 0, 0x55, 0x00})
+	chain.SetStorageAt(contract, ethcmn.Hash{}, ethcmn.BigToHash(big.NewInt(41)))
+	chain.ImpersonateAccount(sender)
+	var hashes []ethcmn.Hash
+	for i := 0; i < 2; i++ {
+		hash, err := chain.SendImpersonatedTransaction(eth.CallMsg{From: sender, To: &contract, Gas: 100000, GasPrice: big.NewInt(devGasPrice)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	reexecutor := NewReexecutor(chain, big.NewInt(1))
+	for _, config := range []*TraceConfig{nil, {Tracer: "callTracer"}, {Tracer: "prestateTracer"}} {
+		for _, hash := range hashes {
+			want, err := chain.TraceTransaction(hash, config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := reexecutor.TraceTransaction(hash, config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Fatalf("trace of %x differs:\n%s\n%s", hash, got, want)
+			}
+		}
+	}
+
+	want, err := chain.TraceCall(eth.CallMsg{From: sender, To: &contract}, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := reexecutor.TraceCall(eth.CallMsg{From: sender, To: &contract, Gas: devGasLimit}, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Fatalf("call trace differs:\n%s\n%s", got, want)
+	}
+
+	traces, err := reexecutor.TraceBlock(2, &TraceConfig{Tracer: "callTracer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(traces) != 1 || traces[0].TxHash != hashes[1] {
+		t.Fatalf("unexpected block traces %+v", traces)
+	}
+	if _, err := reexecutor.TraceBlock(0, nil); err == nil {
+		t.Fatal("expected genesis not to be traceable")
+	}
+
+	blocks := func() []*SimBlock {
+		return []*SimBlock{{Calls: []eth.CallMsg{{From: sender, To: &contract}, {From: sender, To: &contract}}}}
+	}
+	for _, validation := range []bool{false, true} {
+		want, err := chain.Simulate(-1, blocks(), validation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := reexecutor.Simulate(-1, blocks(), validation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the simulated roots, and so the hashes, come from different tries
+		for _, results := range [][]*SimBlockResult{want, got} {
+			results[0].Hash = ethcmn.Hash{}
+			for _, call := range results[0].Calls {
+				for _, log := range call.Logs {
+					log.BlockHash = ethcmn.Hash{}
+				}
+			}
+		}
+		wantJSON, _ := json.Marshal(want)
+		gotJSON, _ := json.Marshal(got)
+		if string(gotJSON) != string(wantJSON) {
+			t.Fatalf("simulation differs:\n%s\n%s", gotJSON, wantJSON)
+		}
+	}
+}
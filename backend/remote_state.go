@@ -0,0 +1,237 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/core/state"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	ethcrp "github.com/arcology-network/evm/crypto"
+	"github.com/arcology-network/evm/ethdb"
+	ethrlp "github.com/arcology-network/evm/rlp"
+	"github.com/arcology-network/evm/trie"
+)
+
+// remoteDatabase is a state.Database reading accounts and storage through an
+// EthereumAPI as of one block, so that a state.StateDB can re-execute
+// transactions of a backend keeping its state elsewhere. Writes stay in the
+// tries, nothing is ever committed.
+//
+// The backend is trusted to serve the state as of number, nothing checks it
+// against the state root of the block. Each account is read once, its
+// balance, nonce and code concurrently, and so is each storage slot.
+type remoteDatabase struct {
+	api       EthereumAPI
+	number    int64
+	addresses map[ethcmn.Hash]ethcmn.Address // by address hash, for the storage tries
+	codes     map[ethcmn.Hash][]byte
+	accounts  map[ethcmn.Address][]byte
+	slots     map[ethcmn.Address]map[ethcmn.Hash][]byte
+}
+
+// remoteState opens the state as of the block of header.
+func remoteState(api EthereumAPI, header *ethtyp.Header) (*state.StateDB, error) {
+	return state.New(header.Root, &remoteDatabase{
+		api:       api,
+		number:    header.Number.Int64(),
+		addresses: make(map[ethcmn.Hash]ethcmn.Address),
+		codes:     make(map[ethcmn.Hash][]byte),
+		accounts:  make(map[ethcmn.Address][]byte),
+		slots:     make(map[ethcmn.Address]map[ethcmn.Hash][]byte),
+	}, nil)
+}
+
+// account reads an account in its trie encoding, nil when it is empty since
+// the evm treats empty and missing accounts alike.
+func (db *remoteDatabase) account(address ethcmn.Address) ([]byte, error) {
+	if enc, ok := db.accounts[address]; ok {
+		return enc, nil
+	}
+
+	var (
+		wg                            sync.WaitGroup
+		balance                       *big.Int
+		nonce                         uint64
+		code                          []byte
+		balanceErr, nonceErr, codeErr error
+	)
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		balance, balanceErr = db.api.GetBalance(address, db.number)
+	}()
+	go func() {
+		defer wg.Done()
+		nonce, nonceErr = db.api.GetTransactionCount(address, db.number)
+	}()
+	go func() {
+		defer wg.Done()
+		code, codeErr = db.api.GetCode(address, db.number)
+	}()
+	wg.Wait()
+	for _, err := range []error{balanceErr, nonceErr, codeErr} {
+		if err != nil {
+			return nil, err
+		}
+	}
+	if balance == nil {
+		balance = new(big.Int)
+	}
+	if balance.Sign() == 0 && nonce == 0 && len(code) == 0 {
+		db.accounts[address] = nil
+		return nil, nil
+	}
+
+	codeHash := ethcrp.Keccak256Hash(code)
+	db.codes[codeHash] = code
+	db.addresses[ethcrp.Keccak256Hash(address.Bytes())] = address
+	enc, err := ethrlp.EncodeToBytes(&state.Account{
+		Nonce:    nonce,
+		Balance:  balance,
+		Root:     ethtyp.EmptyRootHash,
+		CodeHash: codeHash.Bytes(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	db.accounts[address] = enc
+	return enc, nil
+}
+
+// storage reads a storage slot in its trie encoding, nil when it is zero.
+func (db *remoteDatabase) storage(address ethcmn.Address, key ethcmn.Hash) ([]byte, error) {
+	slots, ok := db.slots[address]
+	if !ok {
+		slots = make(map[ethcmn.Hash][]byte)
+		db.slots[address] = slots
+	}
+	if enc, ok := slots[key]; ok {
+		return enc, nil
+	}
+
+	value, err := db.api.GetStorageAt(address, key.Hex(), db.number)
+	if err != nil {
+		return nil, err
+	}
+	var enc []byte
+	if value = ethcmn.TrimLeftZeroes(value); len(value) > 0 {
+		if enc, err = ethrlp.EncodeToBytes(value); err != nil {
+			return nil, err
+		}
+	}
+	slots[key] = enc
+	return enc, nil
+}
+
+func (db *remoteDatabase) OpenTrie(root ethcmn.Hash) (state.Trie, error) {
+	return &remoteTrie{db: db, root: root, accounts: true, dirty: make(map[string][]byte)}, nil
+}
+
+func (db *remoteDatabase) OpenStorageTrie(addrHash, root ethcmn.Hash) (state.Trie, error) {
+	t := &remoteTrie{db: db, root: root, dirty: make(map[string][]byte)}
+	// accounts created during the re-execution have no remote storage
+	if address, ok := db.addresses[addrHash]; ok {
+		t.address = &address
+	}
+	return t, nil
+}
+
+func (db *remoteDatabase) CopyTrie(t state.Trie) state.Trie {
+	switch t := t.(type) {
+	case *remoteTrie:
+		cpy := *t
+		cpy.dirty = make(map[string][]byte, len(t.dirty))
+		for key, value := range t.dirty {
+			cpy.dirty[key] = value
+		}
+		return &cpy
+	default:
+		panic(fmt.Errorf("unknown trie type %T", t))
+	}
+}
+
+func (db *remoteDatabase) ContractCode(addrHash, codeHash ethcmn.Hash) ([]byte, error) {
+	if code, ok := db.codes[codeHash]; ok {
+		return code, nil
+	}
+	return nil, fmt.Errorf("code %x not found", codeHash)
+}
+
+func (db *remoteDatabase) ContractCodeSize(addrHash, codeHash ethcmn.Hash) (int, error) {
+	code, err := db.ContractCode(addrHash, codeHash)
+	return len(code), err
+}
+
+func (db *remoteDatabase) TrieDB() *trie.Database {
+	return nil
+}
+
+// remoteTrie is the account trie, or the storage trie of address, of a
+// remoteDatabase. Updated keys shadow the remote ones, a nil value marking a
+// deleted key.
+type remoteTrie struct {
+	db       *remoteDatabase
+	root     ethcmn.Hash
+	accounts bool
+	address  *ethcmn.Address // nil for the storage of a new account
+	dirty    map[string][]byte
+}
+
+func (t *remoteTrie) GetKey([]byte) []byte {
+	return nil
+}
+
+func (t *remoteTrie) TryGet(key []byte) ([]byte, error) {
+	if value, ok := t.dirty[string(key)]; ok {
+		return value, nil
+	}
+	if t.accounts {
+		return t.db.account(ethcmn.BytesToAddress(key))
+	}
+	if t.address == nil {
+		return nil, nil
+	}
+	return t.db.storage(*t.address, ethcmn.BytesToHash(key))
+}
+
+func (t *remoteTrie) TryUpdate(key, value []byte) error {
+	t.dirty[string(key)] = ethcmn.CopyBytes(value)
+	return nil
+}
+
+func (t *remoteTrie) TryDelete(key []byte) error {
+	t.dirty[string(key)] = nil
+	return nil
+}
+
+// Hash stands in for the root, it changes with every update but it is not
+// the root of any real trie.
+func (t *remoteTrie) Hash() ethcmn.Hash {
+	keys := make([]string, 0, len(t.dirty))
+	for key := range t.dirty {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	data := [][]byte{t.root.Bytes()}
+	for _, key := range keys {
+		data = append(data, []byte(key), t.dirty[key])
+	}
+	return ethcrp.Keccak256Hash(data...)
+}
+
+func (t *remoteTrie) Commit(onleaf trie.LeafCallback) (ethcmn.Hash, error) {
+	return ethcmn.Hash{}, errors.New("remote state is read only")
+}
+
+func (t *remoteTrie) NodeIterator(startKey []byte) trie.NodeIterator {
+	return nil
+}
+
+func (t *remoteTrie) Prove(key []byte, fromLevel uint, proofDb ethdb.KeyValueWriter) error {
+	return errors.New("remote state has no proofs")
+}
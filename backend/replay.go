@@ -0,0 +1,99 @@
+package backend
+
+import (
+	"math/big"
+
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/core"
+	"github.com/arcology-network/evm/core/state"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	"github.com/arcology-network/evm/core/vm"
+	"github.com/arcology-network/evm/params"
+)
+
+// chainConfig enables every fork the evm knows from genesis on.
+func chainConfig(chainID *big.Int) *params.ChainConfig {
+	return &params.ChainConfig{
+		ChainID:             chainID,
+		HomesteadBlock:      big.NewInt(0),
+		EIP150Block:         big.NewInt(0),
+		EIP155Block:         big.NewInt(0),
+		EIP158Block:         big.NewInt(0),
+		ByzantiumBlock:      big.NewInt(0),
+		ConstantinopleBlock: big.NewInt(0),
+		PetersburgBlock:     big.NewInt(0),
+		IstanbulBlock:       big.NewInt(0),
+		BerlinBlock:         big.NewInt(0),
+	}
+}
+
+// headerContext is the evm context of the block of header, getHash resolving
+// the hashes of earlier blocks.
+func headerContext(header *ethtyp.Header, getHash vm.GetHashFunc) vm.BlockContext {
+	difficulty := new(big.Int)
+	if header.Difficulty != nil {
+		difficulty.Set(header.Difficulty)
+	}
+	return vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		GetHash:     getHash,
+		Coinbase:    header.Coinbase,
+		BlockNumber: new(big.Int).Set(header.Number),
+		Time:        new(big.Int).SetUint64(header.Time),
+		Difficulty:  difficulty,
+		GasLimit:    header.GasLimit,
+	}
+}
+
+// callAsMessage is the message of a call from an account at the given
+// nonce, gas defaulting to gasCap and the gas price and value to zero.
+func callAsMessage(msg eth.CallMsg, nonce, gasCap uint64) ethtyp.Message {
+	gas := msg.Gas
+	if gas == 0 {
+		gas = gasCap
+	}
+	gasPrice := msg.GasPrice
+	if gasPrice == nil {
+		gasPrice = new(big.Int)
+	}
+	value := msg.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	return ethtyp.NewMessage(msg.From, msg.To, nonce, value, gas, gasPrice, msg.Data, msg.AccessList, false)
+}
+
+// replayMessages applies the messages of a block in order on statedb, the
+// state of its parent, and traces them. When last is not negative only the
+// messages up to it are applied and only it is traced.
+func replayMessages(chainConfig *params.ChainConfig, blockCtx vm.BlockContext, statedb *state.StateDB, msgs []ethtyp.Message, hashes []ethcmn.Hash, last int, config *TraceConfig) ([]*TxTrace, error) {
+	gp := new(core.GasPool).AddGas(blockCtx.GasLimit)
+	if last >= 0 {
+		msgs = msgs[:last+1]
+	}
+	traces := make([]*TxTrace, len(msgs))
+	for i, msg := range msgs {
+		statedb.Prepare(hashes[i], ethcmn.Hash{}, i)
+		traces[i] = &TxTrace{TxHash: hashes[i]}
+		if last < 0 || i == last {
+			result, err := traceMessage(chainConfig, blockCtx, statedb, msg, gp, config)
+			if err != nil {
+				return nil, err
+			}
+			traces[i].Result = result
+		} else {
+			evm := vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), statedb, chainConfig, vm.Config{})
+			if _, err := core.ApplyMessage(evm, msg, gp); err != nil {
+				return nil, err
+			}
+		}
+		// a remote state read that failed leaves zero values behind
+		if err := statedb.Error(); err != nil {
+			return nil, err
+		}
+		statedb.Finalise(true)
+	}
+	return traces, nil
+}
@@ -0,0 +1,343 @@
+package backend
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/arcology-network/component-lib/ethrpc"
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+	ethtyp "github.com/arcology-network/evm/core/types"
+)
+
+// Replayer serves the answers of a session captured by Recorder. Calls are
+// matched by method and arguments; repeated calls are answered in recorded
+// order and the last answer is kept for any further repeats. In strict mode
+// a call that was never recorded fails, otherwise it returns a zero value,
+// or a not found error when it looks up an object such as a block.
+type Replayer struct {
+	strict    bool
+	records   map[string][]*Record
+	recordsMu sync.Mutex
+}
+
+func NewReplayer(path string, strict bool) (*Replayer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := &Replayer{
+		strict:  strict,
+		records: make(map[string][]*Record),
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return nil, err
+		}
+		key := recordKey(rec.Method, rec.Args)
+		r.records[key] = append(r.records[key], &rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func recordKey(method string, args []json.RawMessage) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = string(arg)
+	}
+	return method + "(" + strings.Join(parts, ",") + ")"
+}
+
+func (r *Replayer) replay(method string, result interface{}, args ...interface{}) error {
+	raws := make([]json.RawMessage, len(args))
+	for i, arg := range args {
+		raws[i], _ = json.Marshal(arg)
+	}
+	key := recordKey(method, raws)
+
+	r.recordsMu.Lock()
+	queue := r.records[key]
+	if len(queue) == 0 {
+		r.recordsMu.Unlock()
+		if r.strict {
+			return fmt.Errorf("replay: unrecorded call %s", key)
+		}
+		if reflect.TypeOf(result).Elem().Kind() == reflect.Ptr {
+			return fmt.Errorf("replay: %s not found", key)
+		}
+		return nil
+	}
+	rec := queue[0]
+	if len(queue) > 1 {
+		r.records[key] = queue[1:]
+	}
+	r.recordsMu.Unlock()
+
+	if rec.Error != "" {
+		return errors.New(rec.Error)
+	}
+	if len(rec.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(rec.Result, result)
+}
+
+func (r *Replayer) BlockNumber() (uint64, error) {
+	var number uint64
+	err := r.replay("BlockNumber", &number)
+	return number, err
+}
+
+func (r *Replayer) GetBlockByNumber(number int64, fullTx bool) (*ethrpc.RPCBlock, error) {
+	return r.replayBlock("GetBlockByNumber", fullTx, number, fullTx)
+}
+
+func (r *Replayer) GetBlockByHash(hash ethcmn.Hash, fullTx bool) (*ethrpc.RPCBlock, error) {
+	return r.replayBlock("GetBlockByHash", fullTx, hash, fullTx)
+}
+
+// replayBlock replays a block and types its transactions back, which JSON
+// leaves as maps or strings.
+func (r *Replayer) replayBlock(method string, fullTx bool, args ...interface{}) (*ethrpc.RPCBlock, error) {
+	var block *ethrpc.RPCBlock
+	if err := r.replay(method, &block, args...); err != nil || block == nil {
+		return block, err
+	}
+	for i, v := range block.Transactions {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		if fullTx {
+			tx := new(ethrpc.RPCTransaction)
+			if err := json.Unmarshal(data, tx); err != nil {
+				return nil, err
+			}
+			block.Transactions[i] = tx
+		} else {
+			var hash ethcmn.Hash
+			if err := json.Unmarshal(data, &hash); err != nil {
+				return nil, err
+			}
+			block.Transactions[i] = hash
+		}
+	}
+	return block, nil
+}
+
+func (r *Replayer) GetCode(address ethcmn.Address, number int64) ([]byte, error) {
+	var code []byte
+	err := r.replay("GetCode", &code, address, number)
+	return code, err
+}
+
+func (r *Replayer) GetBalance(address ethcmn.Address, number int64) (*big.Int, error) {
+	balance := new(big.Int)
+	err := r.replay("GetBalance", balance, address, number)
+	return balance, err
+}
+
+func (r *Replayer) GetTransactionCount(address ethcmn.Address, number int64) (uint64, error) {
+	var nonce uint64
+	err := r.replay("GetTransactionCount", &nonce, address, number)
+	return nonce, err
+}
+
+func (r *Replayer) GetStorageAt(address ethcmn.Address, key string, number int64) ([]byte, error) {
+	var value []byte
+	err := r.replay("GetStorageAt", &value, address, key, number)
+	return value, err
+}
+
+func (r *Replayer) GetProof(address ethcmn.Address, keys []string, number int64) (*AccountResult, error) {
+	var result *AccountResult
+	err := r.replay("GetProof", &result, address, keys, number)
+	return result, err
+}
+
+func (r *Replayer) EstimateGas(msg eth.CallMsg) (uint64, error) {
+	var gas uint64
+	err := r.replay("EstimateGas", &gas, msg)
+	return gas, err
+}
+
+func (r *Replayer) GasPrice() (*big.Int, error) {
+	var price *big.Int
+	err := r.replay("GasPrice", &price)
+	return price, err
+}
+
+func (r *Replayer) GetTransactionByHash(hash ethcmn.Hash) (*ethrpc.RPCTransaction, error) {
+	var tx *ethrpc.RPCTransaction
+	err := r.replay("GetTransactionByHash", &tx, hash)
+	return tx, err
+}
+
+func (r *Replayer) Call(msg eth.CallMsg) ([]byte, error) {
+	var ret []byte
+	err := r.replay("Call", &ret, msg)
+	return ret, err
+}
+
+func (r *Replayer) SendRawTransaction(rawTx []byte) (ethcmn.Hash, error) {
+	var hash ethcmn.Hash
+	err := r.replay("SendRawTransaction", &hash, rawTx)
+	return hash, err
+}
+
+func (r *Replayer) GetTransactionReceipt(hash ethcmn.Hash) (*ethtyp.Receipt, error) {
+	var receipt *ethtyp.Receipt
+	err := r.replay("GetTransactionReceipt", &receipt, hash)
+	return receipt, err
+}
+
+func (r *Replayer) GetBlockReceipts(number int64) ([]*BlockReceipt, error) {
+	var receipts []*BlockReceipt
+	err := r.replay("GetBlockReceipts", &receipts, number)
+	return receipts, err
+}
+
+func (r *Replayer) GetBlockReceiptsByHash(hash ethcmn.Hash) ([]*BlockReceipt, error) {
+	var receipts []*BlockReceipt
+	err := r.replay("GetBlockReceiptsByHash", &receipts, hash)
+	return receipts, err
+}
+
+func (r *Replayer) GetLogs(filter eth.FilterQuery) ([]*ethtyp.Log, error) {
+	var logs []*ethtyp.Log
+	err := r.replay("GetLogs", &logs, filter)
+	return logs, err
+}
+
+func (r *Replayer) GetBlockTransactionCountByHash(hash ethcmn.Hash) (int, error) {
+	var count int
+	err := r.replay("GetBlockTransactionCountByHash", &count, hash)
+	return count, err
+}
+
+func (r *Replayer) GetBlockTransactionCountByNumber(number int64) (int, error) {
+	var count int
+	err := r.replay("GetBlockTransactionCountByNumber", &count, number)
+	return count, err
+}
+
+func (r *Replayer) GetTransactionByBlockHashAndIndex(hash ethcmn.Hash, index int) (*ethrpc.RPCTransaction, error) {
+	var tx *ethrpc.RPCTransaction
+	err := r.replay("GetTransactionByBlockHashAndIndex", &tx, hash, index)
+	return tx, err
+}
+
+func (r *Replayer) GetTransactionByBlockNumberAndIndex(number int64, index int) (*ethrpc.RPCTransaction, error) {
+	var tx *ethrpc.RPCTransaction
+	err := r.replay("GetTransactionByBlockNumberAndIndex", &tx, number, index)
+	return tx, err
+}
+
+func (r *Replayer) GetUncleCountByBlockHash(hash ethcmn.Hash) (int, error) {
+	var count int
+	err := r.replay("GetUncleCountByBlockHash", &count, hash)
+	return count, err
+}
+
+func (r *Replayer) GetUncleCountByBlockNumber(number int64) (int, error) {
+	var count int
+	err := r.replay("GetUncleCountByBlockNumber", &count, number)
+	return count, err
+}
+
+func (r *Replayer) SubmitWork() (bool, error) {
+	var ok bool
+	err := r.replay("SubmitWork", &ok)
+	return ok, err
+}
+
+func (r *Replayer) SubmitHashrate() (bool, error) {
+	var ok bool
+	err := r.replay("SubmitHashrate", &ok)
+	return ok, err
+}
+
+func (r *Replayer) Hashrate() (int, error) {
+	var rate int
+	err := r.replay("Hashrate", &rate)
+	return rate, err
+}
+
+func (r *Replayer) GetWork() ([]string, error) {
+	var work []string
+	err := r.replay("GetWork", &work)
+	return work, err
+}
+
+func (r *Replayer) ProtocolVersion() (int, error) {
+	var version int
+	err := r.replay("ProtocolVersion", &version)
+	return version, err
+}
+
+func (r *Replayer) Syncing() (bool, error) {
+	var ok bool
+	err := r.replay("Syncing", &ok)
+	return ok, err
+}
+
+func (r *Replayer) Proposer() (bool, error) {
+	var ok bool
+	err := r.replay("Proposer", &ok)
+	return ok, err
+}
+
+func (r *Replayer) NewFilter(filter eth.FilterQuery) (ID, error) {
+	var id ID
+	err := r.replay("NewFilter", &id, filter)
+	return id, err
+}
+
+func (r *Replayer) NewBlockFilter() (ID, error) {
+	var id ID
+	err := r.replay("NewBlockFilter", &id)
+	return id, err
+}
+
+func (r *Replayer) NewPendingTransactionFilter() (ID, error) {
+	var id ID
+	err := r.replay("NewPendingTransactionFilter", &id)
+	return id, err
+}
+
+func (r *Replayer) UninstallFilter(id ID) (bool, error) {
+	var ok bool
+	err := r.replay("UninstallFilter", &ok, id)
+	return ok, err
+}
+
+func (r *Replayer) GetFilterChanges(id ID) (interface{}, error) {
+	var changes interface{}
+	err := r.replay("GetFilterChanges", &changes, id)
+	return changes, err
+}
+
+func (r *Replayer) GetFilterLogs(id ID) ([]*ethtyp.Log, error) {
+	var logs []*ethtyp.Log
+	err := r.replay("GetFilterLogs", &logs, id)
+	return logs, err
+}
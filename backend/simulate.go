@@ -0,0 +1,217 @@
+package backend
+
+import (
+	"fmt"
+	"math/big"
+
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/core"
+	"github.com/arcology-network/evm/core/state"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	"github.com/arcology-network/evm/core/vm"
+	"github.com/arcology-network/evm/params"
+)
+
+// StateOverride replaces parts of an account before a simulated block. State
+// replaces the whole storage, StateDiff only the given slots.
+type StateOverride struct {
+	Balance   *big.Int
+	Nonce     *uint64
+	Code      []byte
+	State     map[ethcmn.Hash]ethcmn.Hash
+	StateDiff map[ethcmn.Hash]ethcmn.Hash
+}
+
+// BlockOverrides replaces the header fields of a simulated block, the ones
+// left nil follow from the previous block.
+type BlockOverrides struct {
+	Number       *uint64
+	Time         *uint64
+	GasLimit     *uint64
+	FeeRecipient *ethcmn.Address
+}
+
+// SimBlock is a simulated block, its calls run in order on the state left by
+// the previous ones.
+type SimBlock struct {
+	BlockOverrides *BlockOverrides
+	StateOverrides map[ethcmn.Address]*StateOverride
+	Calls          []eth.CallMsg
+}
+
+// SimCallResult is the outcome of a simulated call. Revert holds the revert
+// data of a reverted call, Error the reason of a failed one.
+type SimCallResult struct {
+	ReturnData []byte
+	Logs       []*ethtyp.Log
+	GasUsed    uint64
+	Status     uint64
+	Error      string
+	Revert     []byte
+}
+
+// SimBlockResult is a simulated block with the outcome of its calls.
+type SimBlockResult struct {
+	Number       uint64
+	Hash         ethcmn.Hash
+	ParentHash   ethcmn.Hash
+	Time         uint64
+	GasLimit     uint64
+	GasUsed      uint64
+	FeeRecipient ethcmn.Address
+	TxHashes     []ethcmn.Hash
+	Calls        []*SimCallResult
+}
+
+// apply writes the override to statedb.
+func (o *StateOverride) apply(statedb *state.StateDB, address ethcmn.Address) {
+	if o.Balance != nil {
+		statedb.SetBalance(address, o.Balance)
+	}
+	if o.Nonce != nil {
+		statedb.SetNonce(address, *o.Nonce)
+	}
+	if o.Code != nil {
+		statedb.SetCode(address, o.Code)
+	}
+	if o.State != nil {
+		statedb.SetStorage(address, o.State)
+	}
+	for key, value := range o.StateDiff {
+		statedb.SetState(address, key, value)
+	}
+}
+
+// simulateBlocks runs the calls of each simulated block on statedb, the state
+// of parent, every block on the state the previous one left. Without
+// validation nonces are not checked, calls are free unless they name a gas
+// price and a call the evm refuses to run fails on its own. With validation
+// such a call fails the whole simulation.
+func simulateBlocks(chainConfig *params.ChainConfig, blockContext func(*ethtyp.Header) vm.BlockContext, statedb *state.StateDB, parent *ethtyp.Header, blocks []*SimBlock, validation bool) ([]*SimBlockResult, error) {
+	results := make([]*SimBlockResult, len(blocks))
+	for i, sim := range blocks {
+		header, err := simHeader(parent, sim.BlockOverrides)
+		if err != nil {
+			return nil, err
+		}
+		for address, override := range sim.StateOverrides {
+			override.apply(statedb, address)
+		}
+
+		gp := new(core.GasPool).AddGas(header.GasLimit)
+		result := &SimBlockResult{
+			ParentHash:   parent.Hash(),
+			Time:         header.Time,
+			GasLimit:     header.GasLimit,
+			FeeRecipient: header.Coinbase,
+			TxHashes:     make([]ethcmn.Hash, len(sim.Calls)),
+			Calls:        make([]*SimCallResult, len(sim.Calls)),
+		}
+		var logs []*ethtyp.Log
+		for j, call := range sim.Calls {
+			if call.Gas == 0 {
+				call.Gas = gp.Gas()
+			}
+			if call.GasPrice == nil {
+				call.GasPrice = new(big.Int)
+			}
+			if call.Value == nil {
+				call.Value = new(big.Int)
+			}
+			nonce := statedb.GetNonce(call.From)
+			msg := ethtyp.NewMessage(call.From, call.To, nonce, call.Value, call.Gas, call.GasPrice, call.Data, call.AccessList, validation)
+			hash := simTxHash(call, nonce)
+
+			statedb.Prepare(hash, ethcmn.Hash{}, j)
+			snapshot, gas := statedb.Snapshot(), gp.Gas()
+			evm := vm.NewEVM(blockContext(header), core.NewEVMTxContext(msg), statedb, chainConfig, vm.Config{})
+			res, err := core.ApplyMessage(evm, msg, gp)
+			if dbErr := statedb.Error(); dbErr != nil {
+				return nil, dbErr
+			}
+			if err != nil {
+				if validation {
+					return nil, fmt.Errorf("block %d call %d: %v", header.Number, j, err)
+				}
+				// the gas bought before the evm gave up is handed back
+				statedb.RevertToSnapshot(snapshot)
+				gp.AddGas(gas - gp.Gas())
+				result.TxHashes[j] = hash
+				result.Calls[j] = &SimCallResult{ReturnData: []byte{}, Status: ethtyp.ReceiptStatusFailed, Error: err.Error()}
+				continue
+			}
+			statedb.Finalise(true)
+			header.GasUsed += res.UsedGas
+
+			out := &SimCallResult{ReturnData: res.Return(), GasUsed: res.UsedGas, Status: ethtyp.ReceiptStatusSuccessful}
+			if res.Failed() {
+				out.Status, out.Error, out.Revert = ethtyp.ReceiptStatusFailed, res.Err.Error(), res.Revert()
+				out.ReturnData = res.Revert()
+			}
+			out.Logs = statedb.GetLogs(hash)
+			logs = append(logs, out.Logs...)
+			result.TxHashes[j], result.Calls[j] = hash, out
+		}
+		header.Root = statedb.IntermediateRoot(true)
+
+		result.Number, result.Hash, result.GasUsed = header.Number.Uint64(), header.Hash(), header.GasUsed
+		for index, log := range logs {
+			log.BlockNumber, log.BlockHash, log.Index = result.Number, result.Hash, uint(index)
+		}
+		results[i] = result
+		parent = header
+	}
+	return results, nil
+}
+
+// simHeader derives the header of a simulated block from its parent and the
+// overrides. Numbers and timestamps must increase.
+func simHeader(parent *ethtyp.Header, overrides *BlockOverrides) (*ethtyp.Header, error) {
+	header := &ethtyp.Header{
+		ParentHash: parent.Hash(),
+		Coinbase:   parent.Coinbase,
+		Difficulty: new(big.Int),
+		Number:     new(big.Int).Add(parent.Number, big.NewInt(1)),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + 1,
+	}
+	if parent.Difficulty != nil {
+		header.Difficulty.Set(parent.Difficulty)
+	}
+	if overrides == nil {
+		return header, nil
+	}
+	if overrides.Number != nil {
+		if *overrides.Number <= parent.Number.Uint64() {
+			return nil, fmt.Errorf("block number %d not above %d", *overrides.Number, parent.Number)
+		}
+		header.Number = new(big.Int).SetUint64(*overrides.Number)
+	}
+	if overrides.Time != nil {
+		if *overrides.Time <= parent.Time {
+			return nil, fmt.Errorf("block timestamp %d not above %d", *overrides.Time, parent.Time)
+		}
+		header.Time = *overrides.Time
+	}
+	if overrides.GasLimit != nil {
+		header.GasLimit = *overrides.GasLimit
+	}
+	if overrides.FeeRecipient != nil {
+		header.Coinbase = *overrides.FeeRecipient
+	}
+	return header, nil
+}
+
+// simTxHash is the hash of the unsigned transaction a simulated call stands
+// for.
+func simTxHash(msg eth.CallMsg, nonce uint64) ethcmn.Hash {
+	value := msg.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	if msg.To == nil {
+		return ethtyp.NewContractCreation(nonce, value, msg.Gas, msg.GasPrice, msg.Data).Hash()
+	}
+	return ethtyp.NewTransaction(nonce, *msg.To, value, msg.Gas, msg.GasPrice, msg.Data).Hash()
+}
@@ -0,0 +1,69 @@
+package backend
+
+import (
+	"math/big"
+	"testing"
+
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+)
+
+func TestSimulate(t *testing.T) {
+	chain := NewDevChain(big.NewInt(1), nil, 0, nil)
+	sender, contract := ethcmn.Address{1}, ethcmn.Address{2}
+	// with calldata SSTORE(0, CALLDATALOAD(0)), then return SLOAD(0)
+	code := []byte{
+		0x36, 0x15, 0x60, 0x0b, 0x57,
+		0x60, 0, 0x35, 0x60, 0, 0x55,
+		0x5b, 0x60, 0, 0x54, 0x60, 0, 0x52, 0x60, 0x20, 0x60, 0, 0xf3,
+	}
+	write := ethcmn.BigToHash(big.NewInt(42)).Bytes()
+	later := uint64(1 << 40)
+	blocks := []*SimBlock{
+		{
+			StateOverrides: map[ethcmn.Address]*StateOverride{contract: {Code: code}},
+			Calls: []eth.CallMsg{
+				{From: sender, To: &contract, Data: write},
+				{From: sender, To: &contract},
+			},
+		},
+		{
+			BlockOverrides: &BlockOverrides{Time: &later},
+			Calls:          []eth.CallMsg{{From: sender, To: &contract}},
+		},
+	}
+
+	results, err := chain.Simulate(-1, blocks, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[1].Number != results[0].Number+1 || results[1].Time != later || results[1].ParentHash != results[0].Hash {
+		t.Fatalf("unexpected simulated blocks %+v", results)
+	}
+	for _, read := range []*SimCallResult{results[0].Calls[1], results[1].Calls[0]} {
+		if read.Status != 1 || ethcmn.BytesToHash(read.ReturnData) != ethcmn.BytesToHash(write) {
+			t.Fatalf("expected the written value, got %+v", read)
+		}
+	}
+	if results[0].GasUsed != results[0].Calls[0].GasUsed+results[0].Calls[1].GasUsed {
+		t.Fatalf("block gas %d does not add up", results[0].GasUsed)
+	}
+	if code, _ := chain.GetCode(contract, -1); len(code) != 0 {
+		t.Fatal("expected the simulation to leave the chain untouched")
+	}
+
+	blocks[0].Calls[0].GasPrice = big.NewInt(1)
+	if _, err := chain.Simulate(-1, blocks, true); err == nil {
+		t.Fatal("expected validation to reject a sender without funds")
+	}
+	results, err = chain.Simulate(-1, blocks, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if call := results[0].Calls[0]; call.Status != 0 || call.Error == "" || call.GasUsed != 0 {
+		t.Fatalf("expected the unfunded call to fail on its own, got %+v", call)
+	}
+	if read := results[0].Calls[1]; read.Status != 1 || ethcmn.BytesToHash(read.ReturnData) != (ethcmn.Hash{}) {
+		t.Fatalf("expected the next call to run on untouched storage, got %+v", read)
+	}
+}
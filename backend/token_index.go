@@ -0,0 +1,373 @@
+package backend
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	ethcmn "github.com/arcology-network/evm/common"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	"github.com/arcology-network/evm/ethdb"
+)
+
+// Token standards of a transfer.
+const (
+	TokenERC20   = "erc20"
+	TokenERC721  = "erc721"
+	TokenERC1155 = "erc1155"
+)
+
+var (
+	transferTopic       = ethcmn.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	transferSingleTopic = ethcmn.HexToHash("0xc3d58168c5ae7397731d063d5bbf3d657854427343f4c083240f7aacaa2d0f62")
+	transferBatchTopic  = ethcmn.HexToHash("0x4a39dc06d4c0dbc64b70af90fd698a233a518aa5d07e595d983b8c0526c8f7fb")
+)
+
+var (
+	movementPrefix   = []byte("m") // holder + ^block number + ^sequence -> transfer
+	holdingPrefix    = []byte("h") // holder + token + token id -> holding
+	snapshotPrefix   = []byte("s") // holder + token + token id + ^block number -> holding
+	tokenBlockPrefix = []byte("k") // block number -> transfers of the block
+)
+
+// TokenTransfer is a decoded Transfer, TransferSingle or TransferBatch event.
+// TokenID is nil for fungible tokens.
+type TokenTransfer struct {
+	Standard string         `json:"standard"`
+	Token    ethcmn.Address `json:"token"`
+	From     ethcmn.Address `json:"from"`
+	To       ethcmn.Address `json:"to"`
+	TokenID  *big.Int       `json:"tokenId,omitempty"`
+	Value    *big.Int       `json:"value"`
+	Block    uint64         `json:"blockNumber"`
+	TxHash   ethcmn.Hash    `json:"transactionHash"`
+	LogIndex uint           `json:"logIndex"`
+}
+
+// TokenHolding is the balance of a holder in a token as of a block. Balances
+// count the transfers seen since the index started, so they are only exact
+// for tokens created after that.
+type TokenHolding struct {
+	Standard string         `json:"standard"`
+	Token    ethcmn.Address `json:"token"`
+	TokenID  *big.Int       `json:"tokenId,omitempty"`
+	Balance  *big.Int       `json:"balance"`
+	Block    uint64         `json:"blockNumber"`
+}
+
+func word(data []byte, i int) *big.Int {
+	if len(data) < (i+1)*32 {
+		return nil
+	}
+	return new(big.Int).SetBytes(data[i*32 : (i+1)*32])
+}
+
+func topicAddress(topic ethcmn.Hash) ethcmn.Address {
+	return ethcmn.BytesToAddress(topic.Bytes())
+}
+
+// wordArray decodes an ABI encoded uint256[] at the offset in word i.
+func wordArray(data []byte, i int) []*big.Int {
+	offset := word(data, i)
+	if offset == nil || !offset.IsUint64() || offset.Uint64()%32 != 0 {
+		return nil
+	}
+	start := int(offset.Uint64() / 32)
+	length := word(data, start)
+	if length == nil || !length.IsUint64() || length.Uint64() > uint64(len(data)/32) {
+		return nil
+	}
+	values := make([]*big.Int, length.Uint64())
+	for j := range values {
+		if values[j] = word(data, start+1+j); values[j] == nil {
+			return nil
+		}
+	}
+	return values
+}
+
+// DecodeTokenTransfers picks the standard token transfer events out of the
+// logs of a block. ERC-20 and ERC-721 share the Transfer event and differ in
+// whether the amount is indexed.
+func DecodeTokenTransfers(number uint64, logs []*ethtyp.Log) []*TokenTransfer {
+	var transfers []*TokenTransfer
+	for _, log := range logs {
+		if len(log.Topics) == 0 {
+			continue
+		}
+		base := TokenTransfer{Token: log.Address, Block: number, TxHash: log.TxHash, LogIndex: log.Index}
+		switch {
+		case log.Topics[0] == transferTopic && len(log.Topics) == 3 && len(log.Data) == 32:
+			transfer := base
+			transfer.Standard = TokenERC20
+			transfer.From, transfer.To = topicAddress(log.Topics[1]), topicAddress(log.Topics[2])
+			transfer.Value = word(log.Data, 0)
+			transfers = append(transfers, &transfer)
+		case log.Topics[0] == transferTopic && len(log.Topics) == 4:
+			transfer := base
+			transfer.Standard = TokenERC721
+			transfer.From, transfer.To = topicAddress(log.Topics[1]), topicAddress(log.Topics[2])
+			transfer.TokenID = log.Topics[3].Big()
+			transfer.Value = big.NewInt(1)
+			transfers = append(transfers, &transfer)
+		case log.Topics[0] == transferSingleTopic && len(log.Topics) == 4 && len(log.Data) == 64:
+			transfer := base
+			transfer.Standard = TokenERC1155
+			transfer.From, transfer.To = topicAddress(log.Topics[2]), topicAddress(log.Topics[3])
+			transfer.TokenID, transfer.Value = word(log.Data, 0), word(log.Data, 1)
+			transfers = append(transfers, &transfer)
+		case log.Topics[0] == transferBatchTopic && len(log.Topics) == 4:
+			ids, values := wordArray(log.Data, 0), wordArray(log.Data, 1)
+			if ids == nil || len(ids) != len(values) {
+				continue
+			}
+			for i := range ids {
+				transfer := base
+				transfer.Standard = TokenERC1155
+				transfer.From, transfer.To = topicAddress(log.Topics[2]), topicAddress(log.Topics[3])
+				transfer.TokenID, transfer.Value = ids[i], values[i]
+				transfers = append(transfers, &transfer)
+			}
+		}
+	}
+	return transfers
+}
+
+// TokenIndex keeps the token transfers of each holder and their balance
+// after every block that changed it. Snapshots of a balance older than
+// horizon blocks are pruned once a newer one is beyond the horizon too, so
+// balances as of older blocks, and rewinds deeper than that, are not
+// answered.
+type TokenIndex struct {
+	*heightIndex
+	horizon uint64 // 0 keeps every snapshot
+}
+
+func NewTokenIndex(db ethdb.KeyValueStore, horizon uint64) (*TokenIndex, error) {
+	h, err := newHeightIndex(db, tokenBlockPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenIndex{heightIndex: h, horizon: horizon}, nil
+}
+
+func holdingKey(holder, token ethcmn.Address, id *big.Int) []byte {
+	var tokenID ethcmn.Hash
+	if id != nil {
+		tokenID = ethcmn.BigToHash(id)
+	}
+	return indexKey(holder.Bytes(), token.Bytes(), tokenID.Bytes())
+}
+
+func movementKey(holder ethcmn.Address, number uint64, seq int) []byte {
+	s := make([]byte, 4)
+	binary.BigEndian.PutUint32(s, ^uint32(seq))
+	return indexKey(movementPrefix, holder.Bytes(), encodeNumber(^number), s)
+}
+
+// tokenMovement is the side of a transfer one holder sees.
+type tokenMovement struct {
+	holder ethcmn.Address
+	sign   int
+}
+
+// movements lists the holders a transfer moves tokens for, with the sign of
+// the change. The zero address stands for mints and burns and holds nothing.
+func movements(transfer *TokenTransfer) []tokenMovement {
+	var moved []tokenMovement
+	if transfer.From != (ethcmn.Address{}) {
+		moved = append(moved, tokenMovement{transfer.From, -1})
+	}
+	if transfer.To != (ethcmn.Address{}) && transfer.To != transfer.From {
+		moved = append(moved, tokenMovement{transfer.To, 1})
+	}
+	return moved
+}
+
+// Add indexes the token transfers of a block.
+func (idx *TokenIndex) Add(number uint64, transfers []*TokenTransfer) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if number <= idx.head {
+		if err := idx.rewindHoldings(number); err != nil {
+			return err
+		}
+	}
+
+	batch := idx.db.NewBatch()
+	holdings := make(map[string]*TokenHolding)
+	for seq, transfer := range transfers {
+		encoded, err := json.Marshal(transfer)
+		if err != nil {
+			return err
+		}
+		for _, moved := range movements(transfer) {
+			batch.Put(movementKey(moved.holder, number, seq), encoded)
+
+			key := string(holdingKey(moved.holder, transfer.Token, transfer.TokenID))
+			holding, ok := holdings[key]
+			if !ok {
+				if holding, err = idx.holding([]byte(key)); err != nil {
+					return err
+				}
+				if holding == nil {
+					holding = &TokenHolding{Standard: transfer.Standard, Token: transfer.Token, TokenID: transfer.TokenID, Balance: new(big.Int)}
+				}
+				holdings[key] = holding
+			}
+			if moved.sign < 0 {
+				holding.Balance.Sub(holding.Balance, transfer.Value)
+			} else {
+				holding.Balance.Add(holding.Balance, transfer.Value)
+			}
+			holding.Block = number
+		}
+	}
+	for key, holding := range holdings {
+		encoded, err := json.Marshal(holding)
+		if err != nil {
+			return err
+		}
+		batch.Put(indexKey(holdingPrefix, []byte(key)), encoded)
+		batch.Put(indexKey(snapshotPrefix, []byte(key), encodeNumber(^number)), encoded)
+	}
+	if idx.horizon > 0 && number > idx.horizon {
+		if err := idx.prune(batch, number-idx.horizon); err != nil {
+			return err
+		}
+	}
+	return idx.commit(batch, number, transfers)
+}
+
+// prune deletes the snapshots older than those taken at block number, which
+// stand in for them from then on.
+func (idx *TokenIndex) prune(batch ethdb.Batch, number uint64) error {
+	var transfers []*TokenTransfer
+	if ok, err := idx.record(number, &transfers); !ok || err != nil {
+		return err
+	}
+	for _, transfer := range transfers {
+		for _, moved := range movements(transfer) {
+			prefix := indexKey(snapshotPrefix, holdingKey(moved.holder, transfer.Token, transfer.TokenID))
+			it := idx.db.NewIterator(prefix, encodeNumber(^(number - 1)))
+			for it.Next() {
+				batch.Delete(append([]byte{}, it.Key()...))
+			}
+			it.Release()
+		}
+	}
+	return nil
+}
+
+func (idx *TokenIndex) holding(key []byte) (*TokenHolding, error) {
+	full := indexKey(holdingPrefix, key)
+	if ok, err := idx.db.Has(full); !ok || err != nil {
+		return nil, err
+	}
+	data, err := idx.db.Get(full)
+	if err != nil {
+		return nil, err
+	}
+	holding := new(TokenHolding)
+	return holding, json.Unmarshal(data, holding)
+}
+
+// rewindHoldings removes the blocks from number on, the holdings they
+// changed go back to their latest remaining snapshot. The caller must hold
+// mu.
+func (idx *TokenIndex) rewindHoldings(number uint64) error {
+	touched := make(map[string]bool)
+	err := idx.rewind(number, func(batch ethdb.Batch, n uint64) error {
+		var transfers []*TokenTransfer
+		if ok, err := idx.record(n, &transfers); !ok || err != nil {
+			return err
+		}
+		for seq, transfer := range transfers {
+			for _, moved := range movements(transfer) {
+				batch.Delete(movementKey(moved.holder, n, seq))
+				holding := holdingKey(moved.holder, transfer.Token, transfer.TokenID)
+				batch.Delete(indexKey(snapshotPrefix, holding, encodeNumber(^n)))
+				touched[string(holding)] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	batch := idx.db.NewBatch()
+	for key := range touched {
+		it := idx.db.NewIterator(indexKey(snapshotPrefix, []byte(key)), nil)
+		if it.Next() {
+			batch.Put(indexKey(holdingPrefix, []byte(key)), append([]byte{}, it.Value()...))
+		} else {
+			batch.Delete(indexKey(holdingPrefix, []byte(key)))
+		}
+		it.Release()
+	}
+	return batch.Write()
+}
+
+// Transfers returns the transfers of a holder, newest first, optionally only
+// those of one token. next is the cursor of the following page, nil on the
+// last page.
+func (idx *TokenIndex) Transfers(holder ethcmn.Address, token *ethcmn.Address, cursor []byte, limit int) ([]*TokenTransfer, []byte, error) {
+	transfers := []*TokenTransfer{}
+	next, err := readPage(idx.db, indexKey(movementPrefix, holder.Bytes()), cursor, limit, func(value []byte) (bool, error) {
+		transfer := new(TokenTransfer)
+		if err := json.Unmarshal(value, transfer); err != nil {
+			return false, err
+		}
+		if token != nil && transfer.Token != *token {
+			return false, nil
+		}
+		transfers = append(transfers, transfer)
+		return true, nil
+	})
+	return transfers, next, err
+}
+
+// snapshot returns a holding as of a block, nil if it had not changed yet.
+func (idx *TokenIndex) snapshot(key []byte, number uint64) (*TokenHolding, error) {
+	it := idx.db.NewIterator(indexKey(snapshotPrefix, key), encodeNumber(^number))
+	defer it.Release()
+
+	if !it.Next() {
+		return nil, nil
+	}
+	holding := new(TokenHolding)
+	return holding, json.Unmarshal(it.Value(), holding)
+}
+
+// Holdings returns the tokens a holder has a non-zero balance of as of a
+// block within the horizon, or the latest block if number is 0.
+func (idx *TokenIndex) Holdings(holder ethcmn.Address, number uint64, cursor []byte, limit int) ([]*TokenHolding, []byte, error) {
+	if head := idx.Head(); number > 0 && idx.horizon > 0 && head > idx.horizon && number < head-idx.horizon {
+		return nil, nil, fmt.Errorf("block %d is beyond the %d blocks of balance history kept", number, idx.horizon)
+	}
+	prefix := indexKey(holdingPrefix, holder.Bytes())
+	holdings := []*TokenHolding{}
+	next, err := readPage(idx.db, prefix, cursor, limit, func(value []byte) (bool, error) {
+		holding := new(TokenHolding)
+		if err := json.Unmarshal(value, holding); err != nil {
+			return false, err
+		}
+		if number > 0 && holding.Block > number {
+			key := holdingKey(holder, holding.Token, holding.TokenID)
+			past, err := idx.snapshot(key, number)
+			if err != nil || past == nil {
+				return false, err
+			}
+			holding = past
+		}
+		if holding.Balance.Sign() == 0 {
+			return false, nil
+		}
+		holdings = append(holdings, holding)
+		return true, nil
+	})
+	return holdings, next, err
+}
@@ -0,0 +1,142 @@
+package backend
+
+import (
+	"math/big"
+	"testing"
+
+	ethcmn "github.com/arcology-network/evm/common"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	"github.com/arcology-network/evm/ethdb/memorydb"
+)
+
+func addressTopic(address ethcmn.Address) ethcmn.Hash {
+	return ethcmn.BytesToHash(address.Bytes())
+}
+
+func erc20Log(token, from, to ethcmn.Address, value int64) *ethtyp.Log {
+	return &ethtyp.Log{
+		Address: token,
+		Topics:  []ethcmn.Hash{transferTopic, addressTopic(from), addressTopic(to)},
+		Data:    ethcmn.BigToHash(big.NewInt(value)).Bytes(),
+	}
+}
+
+func TestDecodeTokenTransfers(t *testing.T) {
+	token, alice, bob := ethcmn.Address{0xa0}, ethcmn.Address{1}, ethcmn.Address{2}
+	var batch []byte
+	for _, w := range []int64{64, 160, 2, 7, 8, 2, 1, 3} { // ids [7, 8], values [1, 3]
+		batch = append(batch, ethcmn.BigToHash(big.NewInt(w)).Bytes()...)
+	}
+	logs := []*ethtyp.Log{
+		erc20Log(token, alice, bob, 5),
+		{Address: token, Topics: []ethcmn.Hash{transferTopic, addressTopic(alice), addressTopic(bob), ethcmn.BigToHash(big.NewInt(42))}},
+		{Address: token, Topics: []ethcmn.Hash{transferBatchTopic, addressTopic(alice), addressTopic(alice), addressTopic(bob)}, Data: batch},
+	}
+	transfers := DecodeTokenTransfers(1, logs)
+	if len(transfers) != 4 {
+		t.Fatalf("expected 4 transfers, got %d", len(transfers))
+	}
+	if transfers[0].Standard != TokenERC20 || transfers[0].Value.Int64() != 5 {
+		t.Fatalf("wrong erc20 transfer %+v", transfers[0])
+	}
+	if transfers[1].Standard != TokenERC721 || transfers[1].TokenID.Int64() != 42 {
+		t.Fatalf("wrong erc721 transfer %+v", transfers[1])
+	}
+	if transfers[3].Standard != TokenERC1155 || transfers[3].TokenID.Int64() != 8 || transfers[3].Value.Int64() != 3 {
+		t.Fatalf("wrong erc1155 transfer %+v", transfers[3])
+	}
+}
+
+func TestTokenIndex(t *testing.T) {
+	idx, err := NewTokenIndex(memorydb.New(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, alice, bob := ethcmn.Address{0xa0}, ethcmn.Address{1}, ethcmn.Address{2}
+	blocks := [][]*ethtyp.Log{
+		{erc20Log(token, ethcmn.Address{}, alice, 100)},
+		{erc20Log(token, alice, bob, 30), erc20Log(token, alice, bob, 20)},
+		{erc20Log(token, bob, alice, 50)},
+	}
+	for i, logs := range blocks {
+		number := uint64(i + 1)
+		if err := idx.Add(number, DecodeTokenTransfers(number, logs)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	balance := func(holder ethcmn.Address, number uint64) int64 {
+		holdings, _, err := idx.Holdings(holder, number, nil, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(holdings) == 0 {
+			return 0
+		}
+		return holdings[0].Balance.Int64()
+	}
+	if balance(alice, 0) != 100 || balance(bob, 0) != 0 || balance(alice, 2) != 50 || balance(bob, 2) != 50 {
+		t.Fatal("wrong balances")
+	}
+
+	var seen []*TokenTransfer
+	var cursor []byte
+	for {
+		page, next, err := idx.Transfers(alice, nil, cursor, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen = append(seen, page...)
+		if next == nil {
+			break
+		}
+		cursor = next
+	}
+	if len(seen) != 4 || seen[0].Block != 3 || seen[3].Block != 1 {
+		t.Fatalf("expected alice's 4 transfers newest first, got %d", len(seen))
+	}
+
+	// replacing block 2 rolls the balances back to block 1
+	if err := idx.Add(2, nil); err != nil {
+		t.Fatal(err)
+	}
+	if balance(alice, 0) != 100 || balance(bob, 0) != 0 {
+		t.Fatal("balances not rolled back")
+	}
+	if transfers, _, _ := idx.Transfers(bob, nil, nil, 10); len(transfers) != 0 {
+		t.Fatalf("expected bob's transfers gone, got %d", len(transfers))
+	}
+}
+
+func TestTokenSnapshotHorizon(t *testing.T) {
+	db := memorydb.New()
+	idx, err := NewTokenIndex(db, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, alice := ethcmn.Address{0xa0}, ethcmn.Address{1}
+	for number := uint64(1); number <= 5; number++ {
+		logs := []*ethtyp.Log{erc20Log(token, ethcmn.Address{}, alice, 10)}
+		if err := idx.Add(number, DecodeTokenTransfers(number, logs)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// block 3 stands in for the pruned blocks 1 and 2
+	it := db.NewIterator(indexKey(snapshotPrefix, holdingKey(alice, token, nil)), nil)
+	snapshots := 0
+	for it.Next() {
+		snapshots++
+	}
+	it.Release()
+	if snapshots != 3 {
+		t.Fatalf("expected the snapshots of blocks 3 to 5, got %d", snapshots)
+	}
+	holdings, _, err := idx.Holdings(alice, 3, nil, 10)
+	if err != nil || len(holdings) != 1 || holdings[0].Balance.Int64() != 30 {
+		t.Fatalf("expected the balance as of block 3, got %v %v", holdings, err)
+	}
+	if _, _, err := idx.Holdings(alice, 2, nil, 10); err == nil {
+		t.Fatal("expected a block beyond the horizon to be rejected")
+	}
+}
@@ -0,0 +1,80 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/arcology-network/evm/core"
+	"github.com/arcology-network/evm/core/state"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	"github.com/arcology-network/evm/core/vm"
+	"github.com/arcology-network/evm/params"
+)
+
+// resultTracer is an evm tracer that renders its trace once the message has
+// been applied.
+type resultTracer interface {
+	vm.Tracer
+	result(gas uint64, res *core.ExecutionResult) (interface{}, error)
+}
+
+// newTracer builds the tracer named in config for a message about to run on
+// statedb.
+func newTracer(config *TraceConfig, statedb *state.StateDB) (resultTracer, error) {
+	if config == nil {
+		config = &TraceConfig{}
+	}
+	switch config.Tracer {
+	case "":
+		return newStructLogger(config), nil
+	case "callTracer":
+		var options struct {
+			OnlyTopCall bool `json:"onlyTopCall"`
+		}
+		if len(config.TracerConfig) > 0 {
+			if err := json.Unmarshal(config.TracerConfig, &options); err != nil {
+				return nil, fmt.Errorf("invalid callTracer config: %v", err)
+			}
+		}
+		tracer := newCallTracer()
+		tracer.onlyTopCall = options.OnlyTopCall
+		return tracer, nil
+	case "prestateTracer":
+		var options struct {
+			DiffMode bool `json:"diffMode"`
+		}
+		if len(config.TracerConfig) > 0 {
+			if err := json.Unmarshal(config.TracerConfig, &options); err != nil {
+				return nil, fmt.Errorf("invalid prestateTracer config: %v", err)
+			}
+		}
+		if options.DiffMode {
+			return nil, errors.New("prestateTracer diffMode not supported")
+		}
+		return newPrestateTracer(statedb.Copy()), nil
+	case "accessListTracer":
+		return newAccessListTracer(), nil
+	default:
+		return nil, fmt.Errorf("tracer %q not supported", config.Tracer)
+	}
+}
+
+// traceMessage applies a message on statedb with the configured tracer
+// attached and returns the trace.
+func traceMessage(chainConfig *params.ChainConfig, blockCtx vm.BlockContext, statedb *state.StateDB, msg ethtyp.Message, gp *core.GasPool, config *TraceConfig) (json.RawMessage, error) {
+	tracer, err := newTracer(config, statedb)
+	if err != nil {
+		return nil, err
+	}
+	evm := vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), statedb, chainConfig, vm.Config{Debug: true, Tracer: tracer})
+	res, err := core.ApplyMessage(evm, msg, gp)
+	if err != nil {
+		return nil, err
+	}
+	trace, err := tracer.result(msg.Gas(), res)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(trace)
+}
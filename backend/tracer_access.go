@@ -0,0 +1,149 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sort"
+	"time"
+
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/common/hexutil"
+	"github.com/arcology-network/evm/core"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	"github.com/arcology-network/evm/core/vm"
+)
+
+// maxAccessListRounds bounds how often a call is re-run to let its access
+// list settle.
+const maxAccessListRounds = 10
+
+var errAccessListUnstable = errors.New("access list did not settle")
+
+// AccessListResult is the output of the access list tracer and the reply of
+// eth_createAccessList.
+type AccessListResult struct {
+	AccessList ethtyp.AccessList `json:"accessList"`
+	GasUsed    hexutil.Uint64    `json:"gasUsed"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// accessListTracer collects the addresses and storage slots a message
+// touches. Like geth's access list tracer it leaves out the sender, the
+// recipient and the precompiles unless their storage is touched.
+type accessListTracer struct {
+	excluded map[ethcmn.Address]bool
+	list     map[ethcmn.Address]map[ethcmn.Hash]bool
+}
+
+func newAccessListTracer() *accessListTracer {
+	return &accessListTracer{
+		excluded: make(map[ethcmn.Address]bool),
+		list:     make(map[ethcmn.Address]map[ethcmn.Hash]bool),
+	}
+}
+
+func (t *accessListTracer) addAddress(address ethcmn.Address) {
+	if t.excluded[address] {
+		return
+	}
+	if _, ok := t.list[address]; !ok {
+		t.list[address] = make(map[ethcmn.Hash]bool)
+	}
+}
+
+// addSlot records a storage slot, the slots of excluded addresses included.
+func (t *accessListTracer) addSlot(address ethcmn.Address, slot ethcmn.Hash) {
+	if _, ok := t.list[address]; !ok {
+		t.list[address] = make(map[ethcmn.Hash]bool)
+	}
+	t.list[address][slot] = true
+}
+
+func (t *accessListTracer) CaptureStart(env *vm.EVM, from ethcmn.Address, to ethcmn.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.excluded[from], t.excluded[to] = true, true
+	for _, address := range vm.ActivePrecompiles(env.ChainConfig().Rules(env.Context.BlockNumber)) {
+		t.excluded[address] = true
+	}
+}
+
+func (t *accessListTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	stack := scope.Stack
+	size := len(stack.Data())
+	switch {
+	case (op == vm.SLOAD || op == vm.SSTORE) && size >= 1:
+		t.addSlot(scope.Contract.Address(), ethcmn.Hash(stack.Back(0).Bytes32()))
+	case (op == vm.EXTCODECOPY || op == vm.EXTCODEHASH || op == vm.EXTCODESIZE || op == vm.BALANCE || op == vm.SELFDESTRUCT) && size >= 1:
+		t.addAddress(ethcmn.Address(stack.Back(0).Bytes20()))
+	case (op == vm.DELEGATECALL || op == vm.CALL || op == vm.STATICCALL || op == vm.CALLCODE) && size >= 5:
+		t.addAddress(ethcmn.Address(stack.Back(1).Bytes20()))
+	}
+}
+
+func (t *accessListTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+func (t *accessListTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) {}
+
+func (t *accessListTracer) result(gas uint64, res *core.ExecutionResult) (interface{}, error) {
+	list := make(ethtyp.AccessList, 0, len(t.list))
+	for address, slots := range t.list {
+		tuple := ethtyp.AccessTuple{Address: address, StorageKeys: make([]ethcmn.Hash, 0, len(slots))}
+		for slot := range slots {
+			tuple.StorageKeys = append(tuple.StorageKeys, slot)
+		}
+		sort.Slice(tuple.StorageKeys, func(i, j int) bool {
+			return bytes.Compare(tuple.StorageKeys[i].Bytes(), tuple.StorageKeys[j].Bytes()) < 0
+		})
+		list = append(list, tuple)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return bytes.Compare(list[i].Address.Bytes(), list[j].Address.Bytes()) < 0
+	})
+	result := &AccessListResult{AccessList: list, GasUsed: hexutil.Uint64(res.UsedGas)}
+	if res.Err != nil {
+		result.Error = res.Err.Error()
+	}
+	return result, nil
+}
+
+// CreateAccessList re-runs a call with the access list of the previous run
+// until the list no longer changes, then returns the list with the gas the
+// call uses with it.
+func CreateAccessList(tracer Tracer, msg eth.CallMsg, number int64) (*AccessListResult, error) {
+	config := &TraceConfig{Tracer: "accessListTracer"}
+	for round := 0; round < maxAccessListRounds; round++ {
+		data, err := tracer.TraceCall(msg, number, config)
+		if err != nil {
+			return nil, err
+		}
+		var result AccessListResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, err
+		}
+		if sameAccessList(result.AccessList, msg.AccessList) {
+			return &result, nil
+		}
+		msg.AccessList = result.AccessList
+	}
+	return nil, errAccessListUnstable
+}
+
+func sameAccessList(a, b ethtyp.AccessList) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Address != b[i].Address || len(a[i].StorageKeys) != len(b[i].StorageKeys) {
+			return false
+		}
+		for j := range a[i].StorageKeys {
+			if a[i].StorageKeys[j] != b[i].StorageKeys[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
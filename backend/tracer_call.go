@@ -0,0 +1,253 @@
+package backend
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/common/hexutil"
+	"github.com/arcology-network/evm/core"
+	"github.com/arcology-network/evm/core/vm"
+)
+
+// CallFrame is a call in the output of geth's callTracer.
+type CallFrame struct {
+	Type    string         `json:"type"`
+	From    ethcmn.Address `json:"from"`
+	To      ethcmn.Address `json:"to"`
+	Value   *hexutil.Big   `json:"value,omitempty"`
+	Gas     hexutil.Uint64 `json:"gas"`
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Input   hexutil.Bytes  `json:"input"`
+	Output  hexutil.Bytes  `json:"output,omitempty"`
+	Error   string         `json:"error,omitempty"`
+	Calls   []*CallFrame   `json:"calls,omitempty"`
+}
+
+// openCall is a call the tracer has entered but not yet returned from.
+type openCall struct {
+	frame          *CallFrame
+	gasIn, gasCost uint64
+	outOff, outLen uint64
+	gasKnown       bool
+}
+
+// callTracer rebuilds the call tree from the interpreter steps, the way
+// geth's callTracer does: a call is opened at its CALL or CREATE opcode and
+// closed at the first step back at the caller's depth.
+type callTracer struct {
+	env         *vm.EVM
+	stack       []*openCall
+	descended   bool
+	precompiles map[ethcmn.Address]bool
+	onlyTopCall bool
+}
+
+func newCallTracer() *callTracer {
+	return &callTracer{precompiles: make(map[ethcmn.Address]bool)}
+}
+
+func (t *callTracer) CaptureStart(env *vm.EVM, from ethcmn.Address, to ethcmn.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+	for _, address := range vm.ActivePrecompiles(env.ChainConfig().Rules(env.Context.BlockNumber)) {
+		t.precompiles[address] = true
+	}
+	typ := "CALL"
+	if create {
+		typ = "CREATE"
+	}
+	root := &CallFrame{
+		Type:  typ,
+		From:  from,
+		To:    to,
+		Value: (*hexutil.Big)(new(big.Int).Set(value)),
+		Gas:   hexutil.Uint64(gas),
+		Input: append([]byte{}, input...),
+	}
+	t.stack = []*openCall{{frame: root, gasKnown: true}}
+}
+
+func (t *callTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if t.descended {
+		if depth == len(t.stack) {
+			top := t.stack[len(t.stack)-1]
+			top.frame.Gas, top.gasKnown = hexutil.Uint64(gas), true
+		}
+		t.descended = false
+	}
+	for len(t.stack) > depth && len(t.stack) > 1 {
+		t.close(gas, scope)
+	}
+	if err != nil {
+		t.fault(gas, err)
+		return
+	}
+
+	stack := scope.Stack
+	switch op {
+	case vm.CREATE, vm.CREATE2:
+		if len(stack.Data()) < 3 {
+			return
+		}
+		offset, size := stack.Back(1).Uint64(), stack.Back(2).Uint64()
+		t.open(&openCall{
+			frame: &CallFrame{
+				Type:  op.String(),
+				From:  scope.Contract.Address(),
+				Input: memorySlice(scope.Memory, offset, size),
+				Value: (*hexutil.Big)(stack.Back(0).ToBig()),
+			},
+			gasIn:   gas,
+			gasCost: cost,
+		})
+	case vm.SELFDESTRUCT:
+		if len(stack.Data()) < 1 {
+			return
+		}
+		from := scope.Contract.Address()
+		parent := t.stack[len(t.stack)-1].frame
+		parent.Calls = append(parent.Calls, &CallFrame{
+			Type:  op.String(),
+			From:  from,
+			To:    ethcmn.Address(stack.Back(0).Bytes20()),
+			Value: (*hexutil.Big)(env.StateDB.GetBalance(from)),
+			Input: []byte{},
+		})
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		off := 1
+		if op == vm.DELEGATECALL || op == vm.STATICCALL {
+			off = 0
+		}
+		if len(stack.Data()) < 6+off {
+			return
+		}
+		to := ethcmn.Address(stack.Back(1).Bytes20())
+		if t.precompiles[to] {
+			return
+		}
+		call := &openCall{
+			frame: &CallFrame{
+				Type:  op.String(),
+				From:  scope.Contract.Address(),
+				To:    to,
+				Input: memorySlice(scope.Memory, stack.Back(2+off).Uint64(), stack.Back(3+off).Uint64()),
+			},
+			gasIn:   gas,
+			gasCost: cost,
+			outOff:  stack.Back(4 + off).Uint64(),
+			outLen:  stack.Back(5 + off).Uint64(),
+		}
+		switch op {
+		case vm.CALL, vm.CALLCODE:
+			call.frame.Value = (*hexutil.Big)(stack.Back(2).ToBig())
+		case vm.DELEGATECALL:
+			call.frame.Value = t.stack[len(t.stack)-1].frame.Value
+		}
+		t.open(call)
+	case vm.REVERT:
+		t.stack[len(t.stack)-1].frame.Error = "execution reverted"
+	}
+}
+
+func (t *callTracer) open(call *openCall) {
+	t.stack = append(t.stack, call)
+	t.descended = true
+}
+
+// close ends the innermost call, its result is on top of the caller's stack.
+func (t *callTracer) close(gas uint64, scope *vm.ScopeContext) {
+	call := t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+
+	success := len(scope.Stack.Data()) > 0 && !scope.Stack.Back(0).IsZero()
+	frame := call.frame
+	switch frame.Type {
+	case "CREATE", "CREATE2":
+		frame.GasUsed = hexutil.Uint64(subGas(call.gasIn, call.gasCost+gas))
+		if success {
+			frame.To = ethcmn.Address(scope.Stack.Back(0).Bytes20())
+			frame.Output = t.env.StateDB.GetCode(frame.To)
+		}
+	default:
+		if call.gasKnown {
+			frame.GasUsed = hexutil.Uint64(subGas(call.gasIn+uint64(frame.Gas), call.gasCost+gas))
+		}
+		if success {
+			frame.Output = memorySlice(scope.Memory, call.outOff, call.outLen)
+		}
+	}
+	if !success && frame.Error == "" {
+		frame.Error = "internal failure"
+	}
+	parent := t.stack[len(t.stack)-1].frame
+	parent.Calls = append(parent.Calls, frame)
+}
+
+// fault ends the innermost call with an error.
+func (t *callTracer) fault(gas uint64, err error) {
+	top := t.stack[len(t.stack)-1]
+	if top.frame.Error != "" {
+		return
+	}
+	top.frame.Error = err.Error()
+	if len(t.stack) == 1 {
+		return
+	}
+	t.stack = t.stack[:len(t.stack)-1]
+	top.frame.GasUsed = top.frame.Gas
+	parent := t.stack[len(t.stack)-1].frame
+	parent.Calls = append(parent.Calls, top.frame)
+}
+
+func (t *callTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	t.fault(gas, err)
+}
+
+func (t *callTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) {
+	if len(t.stack) == 0 {
+		return
+	}
+	root := t.stack[0].frame
+	root.Output = append([]byte{}, output...)
+	if err != nil {
+		root.Error = err.Error()
+		if errors.Is(err, vm.ErrExecutionReverted) {
+			root.Error = "execution reverted"
+		}
+	}
+}
+
+// result returns the call tree, the root accounts for the whole message
+// including its intrinsic gas.
+func (t *callTracer) result(gas uint64, res *core.ExecutionResult) (interface{}, error) {
+	if len(t.stack) == 0 {
+		return nil, errors.New("nothing traced")
+	}
+	root := t.stack[0].frame
+	root.Gas, root.GasUsed = hexutil.Uint64(gas), hexutil.Uint64(res.UsedGas)
+	if res.Err != nil && root.Error == "" {
+		root.Error = res.Err.Error()
+	}
+	if res.Failed() {
+		root.Output = res.Revert()
+	}
+	if t.onlyTopCall {
+		root.Calls = nil
+	}
+	return root, nil
+}
+
+func memorySlice(memory *vm.Memory, offset, size uint64) []byte {
+	if size == 0 || offset+size > uint64(memory.Len()) {
+		return []byte{}
+	}
+	return memory.GetCopy(int64(offset), int64(size))
+}
+
+func subGas(a, b uint64) uint64 {
+	if a < b {
+		return 0
+	}
+	return a - b
+}
@@ -0,0 +1,91 @@
+package backend
+
+import (
+	"math/big"
+	"time"
+
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/common/hexutil"
+	"github.com/arcology-network/evm/core"
+	"github.com/arcology-network/evm/core/state"
+	"github.com/arcology-network/evm/core/vm"
+	ethcrp "github.com/arcology-network/evm/crypto"
+)
+
+// prestateAccount is an account in the output of geth's prestateTracer.
+type prestateAccount struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Nonce   uint64                      `json:"nonce,omitempty"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[ethcmn.Hash]ethcmn.Hash `json:"storage,omitempty"`
+}
+
+// prestateTracer collects the accounts and storage slots a message touches as
+// they were before it ran, read from a copy of the state taken beforehand.
+type prestateTracer struct {
+	pre      *state.StateDB
+	accounts map[ethcmn.Address]*prestateAccount
+}
+
+func newPrestateTracer(pre *state.StateDB) *prestateTracer {
+	return &prestateTracer{pre: pre, accounts: make(map[ethcmn.Address]*prestateAccount)}
+}
+
+func (t *prestateTracer) lookupAccount(address ethcmn.Address) {
+	if _, ok := t.accounts[address]; ok {
+		return
+	}
+	t.accounts[address] = &prestateAccount{
+		Balance: (*hexutil.Big)(t.pre.GetBalance(address)),
+		Nonce:   t.pre.GetNonce(address),
+		Code:    t.pre.GetCode(address),
+		Storage: make(map[ethcmn.Hash]ethcmn.Hash),
+	}
+}
+
+func (t *prestateTracer) lookupStorage(address ethcmn.Address, key ethcmn.Hash) {
+	t.lookupAccount(address)
+	account := t.accounts[address]
+	if _, ok := account.Storage[key]; !ok {
+		account.Storage[key] = t.pre.GetState(address, key)
+	}
+}
+
+func (t *prestateTracer) CaptureStart(env *vm.EVM, from ethcmn.Address, to ethcmn.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.lookupAccount(from)
+	t.lookupAccount(to)
+	t.lookupAccount(env.Context.Coinbase)
+}
+
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if err != nil {
+		return
+	}
+	stack := scope.Stack
+	size := len(stack.Data())
+	caller := scope.Contract.Address()
+	switch {
+	case (op == vm.SLOAD || op == vm.SSTORE) && size >= 1:
+		t.lookupStorage(caller, ethcmn.Hash(stack.Back(0).Bytes32()))
+	case (op == vm.EXTCODECOPY || op == vm.EXTCODEHASH || op == vm.EXTCODESIZE || op == vm.BALANCE || op == vm.SELFDESTRUCT) && size >= 1:
+		t.lookupAccount(ethcmn.Address(stack.Back(0).Bytes20()))
+	case (op == vm.DELEGATECALL || op == vm.CALL || op == vm.STATICCALL || op == vm.CALLCODE) && size >= 2:
+		t.lookupAccount(ethcmn.Address(stack.Back(1).Bytes20()))
+	case op == vm.CREATE:
+		t.lookupAccount(ethcrp.CreateAddress(caller, env.StateDB.GetNonce(caller)))
+	case op == vm.CREATE2 && size >= 4:
+		offset, length := stack.Back(1).Uint64(), stack.Back(2).Uint64()
+		salt := stack.Back(3).Bytes32()
+		codeHash := ethcrp.Keccak256(memorySlice(scope.Memory, offset, length))
+		t.lookupAccount(ethcrp.CreateAddress2(caller, salt, codeHash))
+	}
+}
+
+func (t *prestateTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) {}
+
+func (t *prestateTracer) result(gas uint64, res *core.ExecutionResult) (interface{}, error) {
+	return t.accounts, nil
+}
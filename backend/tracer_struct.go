@@ -0,0 +1,125 @@
+package backend
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/core"
+	"github.com/arcology-network/evm/core/vm"
+)
+
+// structLog is a step in the output of geth's struct logger.
+type structLog struct {
+	Pc         uint64             `json:"pc"`
+	Op         string             `json:"op"`
+	Gas        uint64             `json:"gas"`
+	GasCost    uint64             `json:"gasCost"`
+	Depth      int                `json:"depth"`
+	Error      string             `json:"error,omitempty"`
+	Stack      *[]string          `json:"stack,omitempty"`
+	Memory     *[]string          `json:"memory,omitempty"`
+	Storage    *map[string]string `json:"storage,omitempty"`
+	ReturnData string             `json:"returnData,omitempty"`
+}
+
+type structLogResult struct {
+	Gas         uint64       `json:"gas"`
+	Failed      bool         `json:"failed"`
+	ReturnValue string       `json:"returnValue"`
+	StructLogs  []*structLog `json:"structLogs"`
+}
+
+// structLogger records every interpreter step the way geth's default tracer
+// does. Storage is only shown at SLOAD and SSTORE, as the slots of the
+// current contract seen so far.
+type structLogger struct {
+	config  TraceConfig
+	storage map[ethcmn.Address]map[ethcmn.Hash]ethcmn.Hash
+	logs    []*structLog
+}
+
+func newStructLogger(config *TraceConfig) *structLogger {
+	l := &structLogger{storage: make(map[ethcmn.Address]map[ethcmn.Hash]ethcmn.Hash)}
+	if config != nil {
+		l.config = *config
+	}
+	return l
+}
+
+func (l *structLogger) CaptureStart(env *vm.EVM, from ethcmn.Address, to ethcmn.Address, create bool, input []byte, gas uint64, value *big.Int) {
+}
+
+func (l *structLogger) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if l.config.Limit != 0 && len(l.logs) >= l.config.Limit {
+		return
+	}
+	log := &structLog{Pc: pc, Op: op.String(), Gas: gas, GasCost: cost, Depth: depth}
+	if err != nil {
+		log.Error = err.Error()
+	}
+	if l.config.EnableMemory {
+		data := scope.Memory.Data()
+		memory := make([]string, 0, (len(data)+31)/32)
+		for i := 0; i+32 <= len(data); i += 32 {
+			memory = append(memory, fmt.Sprintf("%x", data[i:i+32]))
+		}
+		log.Memory = &memory
+	}
+	if !l.config.DisableStack {
+		data := scope.Stack.Data()
+		stack := make([]string, len(data))
+		for i := range data {
+			stack[i] = data[i].Hex()
+		}
+		log.Stack = &stack
+	}
+	if !l.config.DisableStorage && (op == vm.SLOAD || op == vm.SSTORE) {
+		address := scope.Contract.Address()
+		slots := l.storage[address]
+		if slots == nil {
+			slots = make(map[ethcmn.Hash]ethcmn.Hash)
+			l.storage[address] = slots
+		}
+		stack := scope.Stack
+		if op == vm.SLOAD && len(stack.Data()) >= 1 {
+			key := ethcmn.Hash(stack.Back(0).Bytes32())
+			slots[key] = env.StateDB.GetState(address, key)
+		}
+		if op == vm.SSTORE && len(stack.Data()) >= 2 {
+			slots[ethcmn.Hash(stack.Back(0).Bytes32())] = ethcmn.Hash(stack.Back(1).Bytes32())
+		}
+		storage := make(map[string]string, len(slots))
+		for key, value := range slots {
+			storage[fmt.Sprintf("%x", key)] = fmt.Sprintf("%x", value)
+		}
+		log.Storage = &storage
+	}
+	if l.config.EnableReturnData && len(rData) > 0 {
+		log.ReturnData = fmt.Sprintf("%x", rData)
+	}
+	l.logs = append(l.logs, log)
+}
+
+func (l *structLogger) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+func (l *structLogger) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) {}
+
+func (l *structLogger) result(gas uint64, res *core.ExecutionResult) (interface{}, error) {
+	returnValue := fmt.Sprintf("%x", res.Return())
+	if revert := res.Revert(); len(revert) > 0 {
+		returnValue = fmt.Sprintf("%x", revert)
+	}
+	logs := l.logs
+	if logs == nil {
+		logs = []*structLog{}
+	}
+	return &structLogResult{
+		Gas:         res.UsedGas,
+		Failed:      res.Failed(),
+		ReturnValue: returnValue,
+		StructLogs:  logs,
+	}, nil
+}
@@ -0,0 +1,127 @@
+package backend
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+)
+
+func TestCallTracer(t *testing.T) {
+	chain := NewDevChain(big.NewInt(1), nil, 0, nil)
+	sender, caller, callee := ethcmn.Address{1}, ethcmn.Address{2}, ethcmn.HexToAddress("0xbeef")
+	chain.SetBalance(sender, big.NewInt(1e18))
+	// CALL(gas, 0xbeef, 0, 0, 0, 0, 0) STOP
+	chain.SetCode(caller, []byte{0x60, 0, 0x60, 0, 0x60, 0, 0x60, 0, 0x60, 0, 0x61, 0xbe, 0xef, 0x5a, 0xf1, 0x00})
+	chain.SetCode(callee, []byte{0x00})
+	chain.ImpersonateAccount(sender)
+	hash, err := chain.SendImpersonatedTransaction(eth.CallMsg{From: sender, To: &caller, Gas: 100000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := chain.TraceTransaction(hash, &TraceConfig{Tracer: "callTracer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var root CallFrame
+	if err := json.Unmarshal(data, &root); err != nil {
+		t.Fatal(err)
+	}
+	if root.Type != "CALL" || root.From != sender || root.To != caller || root.Error != "" {
+		t.Fatalf("wrong root call %+v", root)
+	}
+	if len(root.Calls) != 1 {
+		t.Fatalf("expected one inner call, got %d", len(root.Calls))
+	}
+	if call := root.Calls[0]; call.Type != "CALL" || call.From != caller || call.To != callee || call.Gas == 0 || call.Error != "" {
+		t.Fatalf("wrong inner call %+v", call)
+	}
+	if _, err := chain.TraceTransaction(hash, &TraceConfig{Tracer: "unknownTracer"}); err == nil {
+		t.Fatal("expected an unknown tracer to fail")
+	}
+}
+
+func TestStructAndPrestateTracers(t *testing.T) {
+	chain := NewDevChain(big.NewInt(1), nil, 0, nil)
+	sender, contract := ethcmn.Address{1}, ethcmn.Address{2}
+	chain.SetBalance(sender, big.NewInt(1e18))
+	// SSTORE(0, SLOAD(0) + 1) STOP
+	chain.SetCode(contract, []byte{0x60, 0, 0x54, 0x60, 1, 0x01, 0x60, 0, 0x55, 0x00})
+	chain.SetStorageAt(contract, ethcmn.Hash{}, ethcmn.BigToHash(big.NewInt(41)))
+	chain.ImpersonateAccount(sender)
+	hash, err := chain.SendImpersonatedTransaction(eth.CallMsg{From: sender, To: &contract, Gas: 100000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := chain.TraceTransaction(hash, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logs structLogResult
+	if err := json.Unmarshal(data, &logs); err != nil {
+		t.Fatal(err)
+	}
+	if logs.Failed || len(logs.StructLogs) != 7 || logs.StructLogs[1].Op != "SLOAD" {
+		t.Fatalf("unexpected struct logs %+v", logs)
+	}
+	if sstore := logs.StructLogs[5]; sstore.Op != "SSTORE" || sstore.Storage == nil || (*sstore.Storage)[ethcmn.Hash{}.Hex()[2:]] != ethcmn.BigToHash(big.NewInt(42)).Hex()[2:] {
+		t.Fatalf("expected the stored slot at SSTORE, got %+v", sstore)
+	}
+
+	data, err = chain.TraceTransaction(hash, &TraceConfig{Tracer: "prestateTracer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pre map[ethcmn.Address]*prestateAccount
+	if err := json.Unmarshal(data, &pre); err != nil {
+		t.Fatal(err)
+	}
+	if account := pre[contract]; account == nil || account.Storage[ethcmn.Hash{}] != ethcmn.BigToHash(big.NewInt(41)) {
+		t.Fatalf("expected the slot before the transaction, got %+v", account)
+	}
+	if account := pre[sender]; account == nil || account.Balance.ToInt().Cmp(big.NewInt(1e18)) != 0 {
+		t.Fatalf("expected the sender before paying for gas, got %+v", account)
+	}
+
+	traces, err := chain.TraceBlock(-1, &TraceConfig{Tracer: "callTracer"})
+	if err != nil || len(traces) != 1 || traces[0].TxHash != hash {
+		t.Fatalf("expected the trace of the mined transaction, got %v err %v", traces, err)
+	}
+	if _, err := chain.TraceCall(eth.CallMsg{From: sender, To: &contract}, -1, &TraceConfig{Tracer: "callTracer", TracerConfig: json.RawMessage(`{"onlyTopCall":true}`)}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateAccessList(t *testing.T) {
+	chain := NewDevChain(big.NewInt(1), nil, 0, nil)
+	sender, contract, other := ethcmn.Address{1}, ethcmn.Address{2}, ethcmn.HexToAddress("0xbeef")
+	// SLOAD(1) BALANCE(0xbeef) STOP
+	chain.SetCode(contract, []byte{0x60, 1, 0x54, 0x61, 0xbe, 0xef, 0x31, 0x00})
+
+	result, err := CreateAccessList(chain, eth.CallMsg{From: sender, To: &contract, Gas: 100000}, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != "" || len(result.AccessList) != 2 {
+		t.Fatalf("unexpected access list %+v", result)
+	}
+	if tuple := result.AccessList[1]; tuple.Address != contract || len(tuple.StorageKeys) != 1 || tuple.StorageKeys[0] != ethcmn.BigToHash(big.NewInt(1)) {
+		t.Fatalf("expected the slot of the contract, got %+v", tuple)
+	}
+	if tuple := result.AccessList[0]; tuple.Address != other || len(tuple.StorageKeys) != 0 {
+		t.Fatalf("expected the balance lookup, got %+v", tuple)
+	}
+
+	// re-executed elsewhere the list must be applied the same, gas included
+	remote, err := CreateAccessList(NewReexecutor(chain, big.NewInt(1)), eth.CallMsg{From: sender, To: &contract, Gas: 100000}, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remote.GasUsed != result.GasUsed || !sameAccessList(remote.AccessList, result.AccessList) {
+		t.Fatalf("expected %+v, got %+v", result, remote)
+	}
+}
@@ -12,6 +12,7 @@ import (
 func main() {
 
 	st := service.StartCmd
+	st.AddCommand(service.BackfillLogsCmd)
 
 	cmd := tmCli.PrepareMainCmd(st, "BC", os.ExpandEnv("$HOME/monacos/ethapi"))
 	if err := cmd.Execute(); err != nil {
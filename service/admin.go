@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+
+	jsonrpc "github.com/deliveroo/jsonrpc-go"
+	"github.com/spf13/viper"
+)
+
+// adminMethods are registered when an admin key is configured, each call has
+// to carry that key.
+func adminMethods() jsonrpc.Methods {
+	return jsonrpc.Methods{
+		"admin_filters": adminFilters,
+	}
+}
+
+func adminAuthorized(ctx context.Context) error {
+	key, adminKey := apiKey(ctx), viper.GetString("admin-key")
+	if adminKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(adminKey)) != 1 {
+		return jsonrpc.Unauthorized("admin key required")
+	}
+	return nil
+}
+
+func adminFilters(ctx context.Context) (interface{}, error) {
+	if err := adminAuthorized(ctx); err != nil {
+		return nil, err
+	}
+	return apiFilters.List(), nil
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/big"
@@ -29,11 +30,13 @@ type Options struct {
 	ChainID         uint64 `short:"c" long:"chainid" description:"Network chain ID"`
 	Port            uint64 `short:"p" long:"port" description:"Service port" default:"7545"`
 	Zookeeper       string `short:"z" long:"zookeeper" description:"Zookeeper service address" default:"127.0.0.1:2181"`
-	Debug           bool   `short:"d" long:"debug" description:"Enable debug mode"`
+	Debug           bool   `short:"d" long:"debug" description:"Enable debug mode, with the evm_/hardhat_/anvil_ test methods"`
 	Waits           int    `short:"w" long:"waits" description:"wait seconds when query receipt" default:"60"`
 	Coinbase        string `short:"cb" long:"coinbase" description:"coinbase address of node`
 	ProtocolVersion int    `short:"pv" long:"protocolVersion" description:"Protocol Version`
 	Hashrate        int    `short:"hr" long:hashrate" description:"hash rate`
+	Dev             bool   `long:"dev" description:"Run an in-memory development chain instead of connecting to the cluster"`
+	DevBlockTime    int    `long:"devblocktime" description:"Dev chain block interval in seconds, 0 mines a block per transaction"`
 }
 
 var options Options
@@ -68,6 +71,9 @@ func getBlockByNumber(ctx context.Context, params []interface{}) (interface{}, e
 	if err != nil {
 		return nil, jsonrpc.InternalError(err)
 	}
+	if block == nil {
+		return nil, nil
+	}
 	// for i := range block.Transactions {
 	// 	fmt.Printf("====hash:%x\n", block.Transactions[i])
 	// }
@@ -84,6 +90,9 @@ func getBlockByHash(ctx context.Context, params []interface{}) (interface{}, err
 	if err != nil {
 		return nil, jsonrpc.InternalError(err)
 	}
+	if block == nil {
+		return nil, nil
+	}
 	return parseBlock(block), nil
 }
 
@@ -192,6 +201,37 @@ func getStorageAt(ctx context.Context, params []interface{}) (interface{}, error
 	return "0x" + hex.EncodeToString(value), nil
 }
 
+func getProof(ctx context.Context, params []interface{}) (interface{}, error) {
+	address, err := ToAddress(params[0])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid address given %v", params[0])
+	}
+
+	list, ok := params[1].([]interface{})
+	if !ok {
+		return nil, jsonrpc.InvalidParams("invalid storage keys given %v", params[1])
+	}
+	keys := make([]string, len(list))
+	for i, v := range list {
+		key, ok := v.(string)
+		if !ok {
+			return nil, jsonrpc.InvalidParams("invalid storage key given %v", v)
+		}
+		keys[i] = key
+	}
+
+	number, err := ToBlockNumber(params[2])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid block number given %v", params[2])
+	}
+
+	result, err := backend.GetProof(address, keys, number)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	return result, nil
+}
+
 func accounts(ctx context.Context) (interface{}, error) {
 	return wallet.Accounts(), nil
 }
@@ -209,6 +249,34 @@ func estimateGas(ctx context.Context, params []interface{}) (interface{}, error)
 	return NumberToHex(gas), nil
 }
 
+func createAccessList(ctx context.Context, params []interface{}) (interface{}, error) {
+	msg, err := ToCallMsg(params[0], true)
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid call msg given %v", params[0])
+	}
+	if msg.Gas == 0 {
+		msg.Gas = math.MaxUint32
+	}
+	if msg.GasPrice == nil {
+		msg.GasPrice = big.NewInt(0xff)
+	}
+	number := int64(ethrpc.BlockNumberLatest)
+	if len(params) > 1 && params[1] != nil {
+		if number, err = ToBlockNumber(params[1]); err != nil {
+			return nil, jsonrpc.InvalidParams("invalid block number given %v", params[1])
+		}
+	}
+	if txTracer == nil {
+		return nil, jsonrpc.InternalError(errNoTracer)
+	}
+
+	result, err := internal.CreateAccessList(txTracer, msg, number)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	return result, nil
+}
+
 func gasPrice(ctx context.Context) (interface{}, error) {
 	gp, err := backend.GasPrice()
 	if err != nil {
@@ -218,6 +286,10 @@ func gasPrice(ctx context.Context) (interface{}, error) {
 }
 
 func sendTransaction(ctx context.Context, params []interface{}) (interface{}, error) {
+	if from, ok := impersonatedSender(params[0]); ok {
+		return sendImpersonatedTransaction(from, params[0])
+	}
+
 	tx, err := ToSendTxArgs(params[0])
 	if err != nil {
 		return nil, jsonrpc.InvalidParams("invalid transaction given %v", params[0])
@@ -240,6 +312,11 @@ func getTransactionReceipt(ctx context.Context, params []interface{}) (interface
 	if err != nil {
 		return nil, jsonrpc.InvalidParams("invalid hash given %v", params[0])
 	}
+	if receiptCache != nil {
+		if receipt, ok := receiptCache.ByTransaction(hash); ok {
+			return formatReceipt(receipt), nil
+		}
+	}
 	var receipt *ethtyp.Receipt
 
 	queryCounter := options.Waits
@@ -254,7 +331,15 @@ func getTransactionReceipt(ctx context.Context, params []interface{}) (interface
 			break
 		}
 	}
-	return receipt, nil
+	if receipt == nil {
+		return nil, nil
+	}
+
+	r, err := txReceipt(receipt, nil)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	return formatReceipt(r), nil
 }
 
 func getTransactionByHash(ctx context.Context, params []interface{}) (interface{}, error) {
@@ -301,17 +386,21 @@ func call(ctx context.Context, params []interface{}) (interface{}, error) {
 	return "0x" + hex.EncodeToString(ret), nil
 }
 
+// getLogs serves eth_getLogs. Besides the filter it takes an optional,
+// non-standard second parameter {"limit": n, "cursor": "0x..."}: with it the
+// reply is a page {"logs": [...], "cursor": "0x..."} of at most limit logs,
+// and the cursor, null on the last page, is passed back to get the next one.
+// Without it a query over the range or result limits fails with a narrower
+// range to try.
 func getLogs(ctx context.Context, params []interface{}) (interface{}, error) {
 	filter, err := ToFilter(params[0])
 	if err != nil {
-		return nil, jsonrpc.InvalidParams("invalid filter given %v", params[0])
+		return nil, jsonrpc.InvalidParams("invalid filter given %v: %v", params[0], err)
 	}
-
-	logs, err := backend.GetLogs(filter)
-	if err != nil {
-		return nil, jsonrpc.InternalError(err)
+	if len(params) > 1 && params[1] != nil {
+		return pagedGetLogs(filter, params[1])
 	}
-	return logs, nil
+	return boundedGetLogs(filter)
 }
 
 func getBlockTransactionCountByHash(ctx context.Context, params []interface{}) (interface{}, error) {
@@ -490,28 +579,46 @@ func mining(ctx context.Context, params []interface{}) (interface{}, error) {
 func newFilter(ctx context.Context, params []interface{}) (interface{}, error) {
 	filter, err := ToFilter(params[0])
 	if err != nil {
-		return nil, jsonrpc.InvalidParams("invalid filter given %v", params[0])
+		return nil, jsonrpc.InvalidParams("invalid filter given %v: %v", params[0], err)
 	}
 
+	owner := clientID(ctx)
+	if err := apiFilters.Admit(owner, &filter); err != nil {
+		return nil, quotaError(err)
+	}
 	id, err := backend.NewFilter(filter)
 	if err != nil {
+		apiFilters.Release(owner)
 		return nil, jsonrpc.InternalError(err)
 	}
+	apiFilters.SetOwner(id, owner)
 	return id, nil
 }
 
 func newBlockFilter(ctx context.Context, params []interface{}) (interface{}, error) {
+	owner := clientID(ctx)
+	if err := apiFilters.Admit(owner, nil); err != nil {
+		return nil, quotaError(err)
+	}
 	id, err := backend.NewBlockFilter()
 	if err != nil {
+		apiFilters.Release(owner)
 		return nil, jsonrpc.InternalError(err)
 	}
+	apiFilters.SetOwner(id, owner)
 	return id, nil
 }
 func newPendingTransactionFilter(ctx context.Context, params []interface{}) (interface{}, error) {
+	owner := clientID(ctx)
+	if err := apiFilters.Admit(owner, nil); err != nil {
+		return nil, quotaError(err)
+	}
 	id, err := backend.NewPendingTransactionFilter()
 	if err != nil {
+		apiFilters.Release(owner)
 		return nil, jsonrpc.InternalError(err)
 	}
+	apiFilters.SetOwner(id, owner)
 	return id, nil
 }
 func uninstallFilter(ctx context.Context, params []interface{}) (interface{}, error) {
@@ -519,6 +626,17 @@ func uninstallFilter(ctx context.Context, params []interface{}) (interface{}, er
 	if err != nil {
 		return nil, jsonrpc.InvalidParams("invalid filter given %v", params[0])
 	}
+	if forwardedByPeer(ctx) {
+		ok, _ := apiFilters.UninstallFilter(id)
+		return ok, nil
+	}
+	var found bool
+	if forwarded, err := apiFilters.Forward(id, "eth_uninstallFilter", clientID(ctx), &found); forwarded {
+		if err != nil {
+			return nil, jsonrpc.InternalError(err)
+		}
+		return found, nil
+	}
 	ok, err := backend.UninstallFilter(id)
 	if err != nil {
 		return nil, jsonrpc.InternalError(err)
@@ -531,6 +649,20 @@ func getFilterChanges(ctx context.Context, params []interface{}) (interface{}, e
 	if err != nil {
 		return nil, jsonrpc.InvalidParams("invalid filter given %v", params[0])
 	}
+	if forwardedByPeer(ctx) {
+		results, err := apiFilters.GetFilterChanges(id)
+		if err != nil {
+			return nil, jsonrpc.InternalError(err)
+		}
+		return results, nil
+	}
+	var changes json.RawMessage
+	if forwarded, err := apiFilters.Forward(id, "eth_getFilterChanges", clientID(ctx), &changes); forwarded {
+		if err != nil {
+			return nil, jsonrpc.InternalError(err)
+		}
+		return changes, nil
+	}
 	results, err := backend.GetFilterChanges(id)
 	if err != nil {
 		return nil, jsonrpc.InternalError(err)
@@ -542,6 +674,15 @@ func getFilterLogs(ctx context.Context, params []interface{}) (interface{}, erro
 	if err != nil {
 		return nil, jsonrpc.InvalidParams("invalid filter given %v", params[0])
 	}
+	if crit, err := apiFilters.GetFilterLogsCrit(id); err == nil {
+		head, err := backend.BlockNumber()
+		if err != nil {
+			return nil, jsonrpc.InternalError(err)
+		}
+		if err := apiFilters.CheckLogRange(crit, head); err != nil {
+			return nil, quotaError(err)
+		}
+	}
 
 	logs, err := backend.GetFilterLogs(id)
 	if err != nil {
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	internal "github.com/arcology-network/eth-api-svc/backend"
+)
+
+func TestReplayedSession(t *testing.T) {
+	replayer, err := internal.NewReplayer("testdata/session.jsonl", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend = replayer
+	ctx := context.Background()
+	address := "0x57de3b28c55095e5ca67a8e20fa9d7d5d9aef891"
+
+	if number, err := blockNumber(ctx); err != nil || number != "0x10" {
+		t.Fatalf("eth_blockNumber: %v %v", number, err)
+	}
+	if balance, err := getBalance(ctx, []interface{}{address, "0x10"}); err != nil || balance != "0x3e8" {
+		t.Fatalf("eth_getBalance: %v %v", balance, err)
+	}
+	if _, err := getTransactionCount(ctx, []interface{}{address, "0x10"}); err == nil {
+		t.Fatal("eth_getTransactionCount: expected the recorded error")
+	}
+	if _, err := getCode(ctx, []interface{}{address, "0x10"}); err == nil {
+		t.Fatal("eth_getCode: expected strict replay to reject an unrecorded call")
+	}
+}
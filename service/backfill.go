@@ -0,0 +1,150 @@
+package service
+
+import (
+	"fmt"
+	"math/big"
+
+	mainCfg "github.com/arcology-network/component-lib/config"
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	eth "github.com/arcology-network/evm"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// BackfillLogsCmd fills the log index of a stopped service: leveldb lets a
+// single process open the index, so it cannot run next to the service.
+var BackfillLogsCmd = &cobra.Command{
+	Use:   "backfill-logs",
+	Short: "Fill the local log index with the logs of past blocks",
+	Long: `Fill the local log index with the logs of past blocks.
+
+The index is a leveldb database, which only one process can open at a time:
+stop the service using it before backfilling, and start it again afterwards.`,
+	RunE: backfillLogsCmd,
+}
+
+func init() {
+	flags := BackfillLogsCmd.Flags()
+
+	flags.String("ethapicfg", "./eth-api.tom", "eth config file path")
+	flags.String("monacocfg", "./monaco.toml", "main config file path")
+	flags.String("log-index", "", "leveldb directory of the local log index")
+	flags.Uint64("log-index-horizon", 100000, "max blocks kept in the local log index, 0 for unlimited")
+	flags.Uint64("from", 0, "first block to index, 0 for the start of the horizon")
+	flags.Uint64("to", 0, "last block to index, 0 for the latest block")
+	flags.Uint64("batch", 100, "blocks fetched per storage query")
+}
+
+func backfillLogsCmd(cmd *cobra.Command, args []string) error {
+	path := viper.GetString("log-index")
+	if path == "" {
+		return fmt.Errorf("no log index given")
+	}
+	horizon := viper.GetUint64("log-index-horizon")
+	index, err := openLogIndex(path, horizon)
+	if err != nil {
+		return err
+	}
+	logger, err := zap.NewProduction()
+	if err != nil {
+		return err
+	}
+	defer logger.Sync()
+
+	LoadCfg(viper.GetString("ethapicfg"), &options)
+	mainCfg.InitCfg(viper.GetString("monacocfg"))
+	monaco := internal.NewMonaco(options.Zookeeper, nil)
+
+	to := viper.GetUint64("to")
+	if to == 0 {
+		if to, err = monaco.BlockNumber(); err != nil {
+			return err
+		}
+	}
+	from := viper.GetUint64("from")
+	if from == 0 {
+		from = 1
+		if horizon > 0 && to >= horizon {
+			from = to - horizon + 1
+		}
+	}
+	batch := viper.GetUint64("batch")
+	if batch == 0 {
+		batch = 1
+	}
+
+	// the index only grows by whole blocks at either end: blocks below the
+	// indexed ones are added downwards, the ones above upwards
+	lo, hi, ok := index.Range()
+	if !ok {
+		lo, hi = to+1, to
+	}
+	if from < lo {
+		top := lo - 1
+		if to < top {
+			top = to
+		}
+		for end := top; end >= from; end -= batch {
+			start := from
+			if end-from >= batch {
+				start = end - batch + 1
+			}
+			if err := backfillLogs(monaco, index, start, end, true); err != nil {
+				return err
+			}
+			logger.Info("indexed blocks", zap.Uint64("from", start), zap.Uint64("to", end))
+			if start == from {
+				break
+			}
+		}
+	}
+	if hi < from {
+		hi = from - 1
+	}
+	for start := hi + 1; start <= to; start += batch {
+		end := start + batch - 1
+		if end > to {
+			end = to
+		}
+		if err := backfillLogs(monaco, index, start, end, false); err != nil {
+			return err
+		}
+		logger.Info("indexed blocks", zap.Uint64("from", start), zap.Uint64("to", end))
+	}
+	return nil
+}
+
+// backfillLogs indexes the blocks start..end, from the last one down if
+// downwards is set.
+func backfillLogs(monaco *internal.Monaco, index *internal.LogIndex, start, end uint64, downwards bool) error {
+	logs, err := monaco.GetLogs(eth.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(start),
+		ToBlock:   new(big.Int).SetUint64(end),
+	})
+	if err != nil {
+		return err
+	}
+	blockLogs := make(map[uint64][]*ethtyp.Log)
+	for _, log := range logs {
+		blockLogs[log.BlockNumber] = append(blockLogs[log.BlockNumber], log)
+	}
+	for i := uint64(0); i <= end-start; i++ {
+		number := start + i
+		if downwards {
+			number = end - i
+		}
+		block, err := monaco.GetBlockByNumber(int64(number), false)
+		if err != nil {
+			return err
+		}
+		if block == nil || block.Header == nil {
+			return fmt.Errorf("block %d not found", number)
+		}
+		if err := index.Add(number, block.Header.Hash(), blockLogs[number]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	//"github.com/sirupsen/logrus"
 
 	internal "github.com/arcology-network/eth-api-svc/backend"
+	ethcmn "github.com/arcology-network/evm/common"
 	"github.com/spf13/viper"
 )
 
@@ -17,14 +18,30 @@ type Config struct {
 	concurrency int
 	groupid     string
 	filters     *internal.Filters
+	logIndex    *internal.LogIndex
+	accounts    *internal.AccountIndex
+	tokens      *internal.TokenIndex
+	contracts   *internal.ContractIndex
+	insights    *internal.ExecutionInsights
+	receipts    *internal.ReceiptCache
+	chainID     uint64
+	code        func(ethcmn.Address, int64) ([]byte, error)
 }
 
 //return a Subscriber struct
-func NewConfig(filters *internal.Filters) *Config {
+func NewConfig(filters *internal.Filters, logIndex *internal.LogIndex, accounts *internal.AccountIndex, tokens *internal.TokenIndex, contracts *internal.ContractIndex, insights *internal.ExecutionInsights, receipts *internal.ReceiptCache) *Config {
 	return &Config{
 		concurrency: viper.GetInt("concurrency"),
 		groupid:     "ethapi",
 		filters:     filters,
+		logIndex:    logIndex,
+		accounts:    accounts,
+		tokens:      tokens,
+		contracts:   contracts,
+		insights:    insights,
+		receipts:    receipts,
+		chainID:     options.ChainID,
+		code:        backend.GetCode,
 	}
 }
 
@@ -47,6 +64,7 @@ func (cfg *Config) Start() {
 		actor.MsgInclusive,
 		actor.MsgPendingBlock,
 		actor.MsgBlockCompleted,
+		actor.MsgTxLocals,
 	}
 
 	receiveTopics := []string{
@@ -54,6 +72,7 @@ func (cfg *Config) Start() {
 		viper.GetString("receipts"),
 		viper.GetString("inclusive-txs"),
 		viper.GetString("local-block"),
+		viper.GetString("local-txs"),
 	}
 
 	//01 apc module kafkaDownloader
@@ -62,7 +81,7 @@ func (cfg *Config) Start() {
 		broker,
 		[]string{actor.MsgStartSub},
 		receiveMseeages,
-		[]int{1, 1, 1, 1},
+		[]int{1, 1, 1, 1, 1},
 		kafka.NewKafkaDownloader(cfg.concurrency, cfg.groupid, receiveTopics, receiveMseeages, viper.GetString("mqaddr")),
 	)
 	apcKafkaDownloader.Connect(streamer.NewDisjunctions(apcKafkaDownloader, 10))
@@ -97,10 +116,91 @@ func (cfg *Config) Start() {
 		},
 		[]string{},
 		[]int{},
-		workers.NewFilterManager(cfg.concurrency, cfg.groupid, cfg.filters),
+		workers.NewFilterManager(cfg.concurrency, cfg.groupid, cfg.filters, cfg.logIndex, cfg.receipts, cfg.chainID),
 	)
 	filterManager.Connect(streamer.NewConjunctions(filterManager))
 
+	//10 pendingTxs
+	pendingTxs := actor.NewActor(
+		"pendingTxs",
+		broker,
+		[]string{
+			actor.MsgTxLocals,
+		},
+		[]string{},
+		[]int{},
+		workers.NewPendingTxs(cfg.concurrency, cfg.groupid, cfg.filters),
+	)
+	pendingTxs.Connect(streamer.NewDisjunctions(pendingTxs, 10))
+
+	//11 accountIndexer
+	if cfg.accounts != nil {
+		accountIndexer := actor.NewActor(
+			"accountIndexer",
+			broker,
+			[]string{
+				actor.MsgSelectedReceipts,
+				actor.MsgBlockCompleted,
+				actor.MsgPendingBlock,
+			},
+			[]string{},
+			[]int{},
+			workers.NewAccountIndexer(cfg.concurrency, cfg.groupid, cfg.accounts, cfg.chainID),
+		)
+		accountIndexer.Connect(streamer.NewConjunctions(accountIndexer))
+	}
+
+	//12 tokenIndexer
+	if cfg.tokens != nil {
+		tokenIndexer := actor.NewActor(
+			"tokenIndexer",
+			broker,
+			[]string{
+				actor.MsgSelectedReceipts,
+				actor.MsgBlockCompleted,
+				actor.MsgPendingBlock,
+			},
+			[]string{},
+			[]int{},
+			workers.NewTokenIndexer(cfg.concurrency, cfg.groupid, cfg.tokens),
+		)
+		tokenIndexer.Connect(streamer.NewConjunctions(tokenIndexer))
+	}
+
+	//13 contractIndexer
+	if cfg.contracts != nil {
+		contractIndexer := actor.NewActor(
+			"contractIndexer",
+			broker,
+			[]string{
+				actor.MsgSelectedReceipts,
+				actor.MsgBlockCompleted,
+				actor.MsgPendingBlock,
+			},
+			[]string{},
+			[]int{},
+			workers.NewContractIndexer(cfg.concurrency, cfg.groupid, cfg.contracts, cfg.chainID, cfg.code),
+		)
+		contractIndexer.Connect(streamer.NewConjunctions(contractIndexer))
+	}
+
+	//14 executionRecorder
+	if cfg.insights != nil {
+		executionRecorder := actor.NewActor(
+			"executionRecorder",
+			broker,
+			[]string{
+				actor.MsgReceipts,
+				actor.MsgInclusive,
+				actor.MsgBlockCompleted,
+			},
+			[]string{},
+			[]int{},
+			workers.NewExecutionRecorder(cfg.concurrency, cfg.groupid, cfg.insights),
+		)
+		executionRecorder.Connect(streamer.NewDisjunctions(executionRecorder, 1))
+	}
+
 	//starter
 	selfStarter := streamer.NewDefaultProducer("selfStarter", []string{actor.MsgStarting}, []int{1})
 	broker.RegisterProducer(selfStarter)
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/common/hexutil"
+	jsonrpc "github.com/deliveroo/jsonrpc-go"
+)
+
+var (
+	contractIndex *internal.ContractIndex
+
+	errNoContractIndex = errors.New("contract index not configured")
+)
+
+// contractMethods serve the contract deployment index.
+func contractMethods() jsonrpc.Methods {
+	return jsonrpc.Methods{
+		"eth_getContractCreation":         ethGetContractCreation,
+		"arcology_getContractDeployments": arcologyGetContractDeployments,
+	}
+}
+
+type contractCreationResponse struct {
+	ContractAddress ethcmn.Address `json:"contractAddress"`
+	Creator         ethcmn.Address `json:"creator"`
+	TransactionHash ethcmn.Hash    `json:"transactionHash"`
+	BlockNumber     hexutil.Uint64 `json:"blockNumber"`
+	CodeHash        *ethcmn.Hash   `json:"codeHash"`
+}
+
+// contractCreation renders a deployment, a contract deployed without code
+// has a null code hash.
+func contractCreation(creation *internal.ContractCreation) *contractCreationResponse {
+	response := &contractCreationResponse{
+		ContractAddress: creation.Address,
+		Creator:         creation.Creator,
+		TransactionHash: creation.TxHash,
+		BlockNumber:     hexutil.Uint64(creation.Block),
+	}
+	if creation.CodeHash != (ethcmn.Hash{}) {
+		response.CodeHash = &creation.CodeHash
+	}
+	return response
+}
+
+func ethGetContractCreation(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, jsonrpc.InvalidParams("address expected")
+	}
+	address, err := ToAddress(params[0])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid address given %v", params[0])
+	}
+	if contractIndex == nil {
+		return nil, jsonrpc.InternalError(errNoContractIndex)
+	}
+	creation, err := contractIndex.Creation(address)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	if creation == nil {
+		return nil, nil
+	}
+	return contractCreation(creation), nil
+}
+
+// arcologyGetContractDeployments lists the contracts deployed in a block
+// range, oldest first.
+func arcologyGetContractDeployments(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 2 {
+		return nil, jsonrpc.InvalidParams("block range expected")
+	}
+	var bounds [2]uint64
+	for i := range bounds {
+		number, err := ToBlockNumber(params[i])
+		if err != nil {
+			return nil, jsonrpc.InvalidParams("invalid block number given %v", params[i])
+		}
+		if number < 0 {
+			latest, err := backend.BlockNumber()
+			if err != nil {
+				return nil, jsonrpc.InternalError(err)
+			}
+			number = int64(latest)
+		}
+		bounds[i] = uint64(number)
+	}
+	if bounds[0] > bounds[1] {
+		return nil, jsonrpc.InvalidParams("invalid block range given %v..%v", params[0], params[1])
+	}
+	opts := map[string]interface{}{}
+	if len(params) > 2 && params[2] != nil {
+		if opts, _ = params[2].(map[string]interface{}); opts == nil {
+			return nil, jsonrpc.InvalidParams("invalid page options given %v", params[2])
+		}
+	}
+	cursor, limit, err := toPage(opts)
+	if err != nil {
+		return nil, err
+	}
+	if contractIndex == nil {
+		return nil, jsonrpc.InternalError(errNoContractIndex)
+	}
+
+	creations, next, err := contractIndex.Deployments(bounds[0], bounds[1], cursor, limit)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	items := make([]*contractCreationResponse, len(creations))
+	for i, creation := range creations {
+		items[i] = contractCreation(creation)
+	}
+	return &itemsPage{Items: items, Cursor: nextCursor(next)}, nil
+}
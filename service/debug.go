@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"math/big"
+
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	jsonrpc "github.com/deliveroo/jsonrpc-go"
+)
+
+// debugMethods serve geth's debug_trace* methods. Traces come from txTracer,
+// re-executing on the dev chain or in this process on the state the backend
+// serves.
+func debugMethods() jsonrpc.Methods {
+	return jsonrpc.Methods{
+		"debug_traceTransaction":   debugTraceTransaction,
+		"debug_traceCall":          debugTraceCall,
+		"debug_traceBlockByNumber": debugTraceBlockByNumber,
+		"debug_traceBlockByHash":   debugTraceBlockByHash,
+	}
+}
+
+// toTraceConfig parses the optional trace options at params[i].
+func toTraceConfig(params []interface{}, i int) (*internal.TraceConfig, error) {
+	config := &internal.TraceConfig{}
+	if len(params) <= i || params[i] == nil {
+		return config, nil
+	}
+	if _, ok := params[i].(map[string]interface{}); !ok {
+		return nil, jsonrpc.InvalidParams("invalid trace config given %v", params[i])
+	}
+	data, err := json.Marshal(params[i])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid trace config given %v", params[i])
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, jsonrpc.InvalidParams("invalid trace config given %v: %v", params[i], err)
+	}
+	return config, nil
+}
+
+// toTraceBlock resolves a block number, tag or hash to a block number.
+func toTraceBlock(v interface{}) (int64, error) {
+	if str, ok := v.(string); ok && len(str) == 66 {
+		hash, err := ToHash(str)
+		if err != nil {
+			return 0, jsonrpc.InvalidParams("invalid block hash given %v", v)
+		}
+		block, err := backend.GetBlockByHash(hash, false)
+		if err != nil {
+			return 0, jsonrpc.InternalError(err)
+		}
+		return block.Header.Number.Int64(), nil
+	}
+	number, err := ToBlockNumber(v)
+	if err != nil {
+		return 0, jsonrpc.InvalidParams("invalid block number given %v", v)
+	}
+	return number, nil
+}
+
+func debugTraceTransaction(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, jsonrpc.InvalidParams("transaction hash expected")
+	}
+	hash, err := ToHash(params[0])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid hash given %v", params[0])
+	}
+	config, err := toTraceConfig(params, 1)
+	if err != nil {
+		return nil, err
+	}
+	if txTracer == nil {
+		return nil, jsonrpc.InternalError(errNoTracer)
+	}
+	trace, err := txTracer.TraceTransaction(hash, config)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	return trace, nil
+}
+
+func debugTraceCall(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, jsonrpc.InvalidParams("call msg expected")
+	}
+	msg, err := ToCallArgs(params[0])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid call msg given %v", params[0])
+	}
+	if msg.Gas == 0 {
+		msg.Gas = math.MaxUint32
+	}
+	if msg.GasPrice == nil {
+		msg.GasPrice = big.NewInt(0xff)
+	}
+	number := int64(-1)
+	if len(params) > 1 && params[1] != nil {
+		if number, err = toTraceBlock(params[1]); err != nil {
+			return nil, err
+		}
+	}
+	config, err := toTraceConfig(params, 2)
+	if err != nil {
+		return nil, err
+	}
+	if txTracer == nil {
+		return nil, jsonrpc.InternalError(errNoTracer)
+	}
+	trace, err := txTracer.TraceCall(msg, number, config)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	return trace, nil
+}
+
+func debugTraceBlockByNumber(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, jsonrpc.InvalidParams("block number expected")
+	}
+	number, err := ToBlockNumber(params[0])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid block number given %v", params[0])
+	}
+	return traceBlock(number, params)
+}
+
+func debugTraceBlockByHash(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, jsonrpc.InvalidParams("block hash expected")
+	}
+	hash, err := ToHash(params[0])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid hash given %v", params[0])
+	}
+	block, err := backend.GetBlockByHash(hash, false)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	return traceBlock(block.Header.Number.Int64(), params)
+}
+
+func traceBlock(number int64, params []interface{}) (interface{}, error) {
+	config, err := toTraceConfig(params, 1)
+	if err != nil {
+		return nil, err
+	}
+	if txTracer == nil {
+		return nil, jsonrpc.InternalError(errNoTracer)
+	}
+	traces, err := txTracer.TraceBlock(number, config)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	return traces, nil
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"math/big"
+
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	ethcmn "github.com/arcology-network/evm/common"
+	jsonrpc "github.com/deliveroo/jsonrpc-go"
+)
+
+// devControl is set in debug mode, when the backend keeps state the test
+// control methods can act on and they are registered.
+var devControl internal.DevControl
+
+func devMethods() jsonrpc.Methods {
+	methods := jsonrpc.Methods{
+		"evm_mine":                  evmMine,
+		"evm_snapshot":              evmSnapshot,
+		"evm_revert":                evmRevert,
+		"evm_increaseTime":          evmIncreaseTime,
+		"evm_setNextBlockTimestamp": evmSetNextBlockTimestamp,
+	}
+	for _, prefix := range []string{"hardhat_", "anvil_"} {
+		methods[prefix+"setBalance"] = devSetBalance
+		methods[prefix+"setCode"] = devSetCode
+		methods[prefix+"setStorageAt"] = devSetStorageAt
+		methods[prefix+"setNonce"] = devSetNonce
+		methods[prefix+"impersonateAccount"] = devImpersonateAccount
+		methods[prefix+"stopImpersonatingAccount"] = devStopImpersonatingAccount
+	}
+	return methods
+}
+
+func evmMine(ctx context.Context, params []interface{}) (interface{}, error) {
+	var timestamp uint64
+	if len(params) > 0 && params[0] != nil {
+		var err error
+		if timestamp, err = ToUint64(params[0]); err != nil {
+			return nil, jsonrpc.InvalidParams("invalid timestamp given %v", params[0])
+		}
+	}
+
+	if _, err := devControl.Mine(timestamp); err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	return "0x0", nil
+}
+
+func evmSnapshot(ctx context.Context) (interface{}, error) {
+	id, err := devControl.Snapshot()
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	return id, nil
+}
+
+func evmRevert(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, jsonrpc.InvalidParams("snapshot id expected")
+	}
+	id, ok := params[0].(string)
+	if !ok {
+		return nil, jsonrpc.InvalidParams("invalid snapshot id given %v", params[0])
+	}
+
+	reverted, err := devControl.Revert(id)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	return reverted, nil
+}
+
+func evmIncreaseTime(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, jsonrpc.InvalidParams("seconds expected")
+	}
+	seconds, err := ToUint64(params[0])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid seconds given %v", params[0])
+	}
+
+	offset, err := devControl.IncreaseTime(seconds)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	return offset, nil
+}
+
+func evmSetNextBlockTimestamp(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, jsonrpc.InvalidParams("timestamp expected")
+	}
+	timestamp, err := ToUint64(params[0])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid timestamp given %v", params[0])
+	}
+
+	if err := devControl.SetNextBlockTimestamp(timestamp); err != nil {
+		return nil, jsonrpc.InvalidParams(err.Error())
+	}
+	return true, nil
+}
+
+func devSetBalance(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 2 {
+		return nil, jsonrpc.InvalidParams("address and balance expected")
+	}
+	address, err := ToAddress(params[0])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid address given %v", params[0])
+	}
+	balance, err := ToBigInt(params[1])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid balance given %v", params[1])
+	}
+
+	if err := devControl.SetBalance(address, balance); err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	return true, nil
+}
+
+func devSetCode(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 2 {
+		return nil, jsonrpc.InvalidParams("address and code expected")
+	}
+	address, err := ToAddress(params[0])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid address given %v", params[0])
+	}
+	code, err := ToBytes(params[1])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid code given %v", params[1])
+	}
+
+	if err := devControl.SetCode(address, code); err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	return true, nil
+}
+
+func devSetStorageAt(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 3 {
+		return nil, jsonrpc.InvalidParams("address, key and value expected")
+	}
+	address, err := ToAddress(params[0])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid address given %v", params[0])
+	}
+	key, err := ToHash(params[1])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid key given %v", params[1])
+	}
+	value, err := ToHash(params[2])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid value given %v", params[2])
+	}
+
+	if err := devControl.SetStorageAt(address, key, value); err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	return true, nil
+}
+
+func devSetNonce(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 2 {
+		return nil, jsonrpc.InvalidParams("address and nonce expected")
+	}
+	address, err := ToAddress(params[0])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid address given %v", params[0])
+	}
+	nonce, err := ToUint64(params[1])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid nonce given %v", params[1])
+	}
+
+	if err := devControl.SetNonce(address, nonce); err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	return true, nil
+}
+
+func devImpersonateAccount(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, jsonrpc.InvalidParams("address expected")
+	}
+	address, err := ToAddress(params[0])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid address given %v", params[0])
+	}
+
+	if err := devControl.ImpersonateAccount(address); err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	return true, nil
+}
+
+func devStopImpersonatingAccount(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, jsonrpc.InvalidParams("address expected")
+	}
+	address, err := ToAddress(params[0])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid address given %v", params[0])
+	}
+
+	if err := devControl.StopImpersonatingAccount(address); err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	return true, nil
+}
+
+// impersonatedSender reports the sender of an eth_sendTransaction request if
+// it is an impersonated dev account.
+func impersonatedSender(v interface{}) (ethcmn.Address, bool) {
+	if devControl == nil {
+		return ethcmn.Address{}, false
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return ethcmn.Address{}, false
+	}
+	from, ok := m["from"].(string)
+	if !ok {
+		return ethcmn.Address{}, false
+	}
+	address := ethcmn.HexToAddress(from)
+	return address, devControl.IsImpersonated(address)
+}
+
+func sendImpersonatedTransaction(from ethcmn.Address, v interface{}) (interface{}, error) {
+	msg, err := ToCallMsg(v, false)
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid transaction given %v", v)
+	}
+	msg.From = from
+	if msg.Value == nil {
+		msg.Value = new(big.Int)
+	}
+
+	hash, err := devControl.SendImpersonatedTransaction(msg)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	return hash.Hex(), nil
+}
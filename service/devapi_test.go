@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	ethcmn "github.com/arcology-network/evm/common"
+)
+
+func TestDevMethods(t *testing.T) {
+	chain := internal.NewDevChain(big.NewInt(1), nil, 0, nil)
+	oldBackend, oldControl := backend, devControl
+	t.Cleanup(func() { backend, devControl = oldBackend, oldControl })
+	backend, devControl = chain, chain
+	ctx := context.Background()
+	account := ethcmn.Address{1}
+
+	if _, err := devSetBalance(ctx, []interface{}{account.Hex(), float64(-1)}); err == nil {
+		t.Fatal("expected a negative balance to be rejected")
+	}
+	if _, err := devSetBalance(ctx, []interface{}{account.Hex()}); err == nil {
+		t.Fatal("expected a missing balance to be rejected")
+	}
+	if _, err := devSetBalance(ctx, []interface{}{account.Hex(), "0x100"}); err != nil {
+		t.Fatal(err)
+	}
+	if balance, _ := chain.GetBalance(account, -1); balance.Int64() != 0x100 {
+		t.Fatalf("expected the balance set, got %v", balance)
+	}
+
+	id, err := evmSnapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := evmMine(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if head, _ := chain.BlockNumber(); head != 1 {
+		t.Fatalf("expected a mined block, head is %d", head)
+	}
+	if _, err := evmRevert(ctx, nil); err == nil {
+		t.Fatal("expected a missing snapshot id to be rejected")
+	}
+	reverted, err := evmRevert(ctx, []interface{}{id})
+	if err != nil || reverted != true {
+		t.Fatalf("expected the snapshot to be reverted, %v", err)
+	}
+	if head, _ := chain.BlockNumber(); head != 0 {
+		t.Fatalf("expected the head back at 0, got %d", head)
+	}
+
+	if _, err := devImpersonateAccount(ctx, []interface{}{account.Hex()}); err != nil {
+		t.Fatal(err)
+	}
+	if from, ok := impersonatedSender(map[string]interface{}{"from": account.Hex()}); !ok || from != account {
+		t.Fatal("expected the impersonated sender to be recognised")
+	}
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	eth "github.com/arcology-network/evm"
+	"github.com/arcology-network/evm/common/hexutil"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	jsonrpc "github.com/deliveroo/jsonrpc-go"
+	"github.com/spf13/viper"
+)
+
+// logsLimits bounds what a single eth_getLogs call may scan and return. Zero
+// values mean unlimited.
+type logsLimits struct {
+	maxRange   uint64
+	maxResults int
+	maxBytes   int
+}
+
+func getLogsLimits() logsLimits {
+	return logsLimits{
+		maxRange:   viper.GetUint64("getlogs-max-range"),
+		maxResults: viper.GetInt("getlogs-max-results"),
+		maxBytes:   viper.GetInt("getlogs-max-bytes"),
+	}
+}
+
+// logsChunk is how many blocks are queried at a time, a query stops after
+// the chunk in which its logs outgrow the limits instead of fetching the
+// whole range first.
+var logsChunk uint64 = 1000
+
+// logsPage is the reply of eth_getLogs with the cursor extension, Cursor is
+// nil once the whole range has been returned.
+type logsPage struct {
+	Logs   []*ethtyp.Log `json:"logs"`
+	Cursor *string       `json:"cursor"`
+}
+
+// logJSONSize estimates the encoded size of a log.
+func logJSONSize(log *ethtyp.Log) int {
+	return 400 + len(log.Topics)*70 + len(log.Data)*2
+}
+
+// overLimit returns the index of the first log that does not fit the result
+// limits, or -1 if all of them do.
+func (l logsLimits) overLimit(logs []*ethtyp.Log, maxResults int) (int, string) {
+	size := 0
+	for i, log := range logs {
+		if maxResults > 0 && i >= maxResults {
+			return i, fmt.Sprintf("query returned more than %d results", maxResults)
+		}
+		size += logJSONSize(log)
+		if l.maxBytes > 0 && size > l.maxBytes {
+			return i, fmt.Sprintf("query response exceeded %d bytes", l.maxBytes)
+		}
+	}
+	return -1, ""
+}
+
+func limitError(msg string, from, to uint64) error {
+	return jsonrpc.Error("limit_exceeded", "%s; try [0x%x, 0x%x]", msg, from, to).Data(map[string]string{
+		"from": hexutil.EncodeUint64(from),
+		"to":   hexutil.EncodeUint64(to),
+	})
+}
+
+// logRange resolves the block range of a query, open or tagged ends are the
+// latest block.
+func logRange(filter eth.FilterQuery) (uint64, uint64, error) {
+	head, err := backend.BlockNumber()
+	if err != nil {
+		return 0, 0, err
+	}
+	from, to := head, head
+	if filter.FromBlock != nil && filter.FromBlock.Sign() >= 0 {
+		from = filter.FromBlock.Uint64()
+	}
+	if filter.ToBlock != nil && filter.ToBlock.Sign() >= 0 {
+		to = filter.ToBlock.Uint64()
+	}
+	return from, to, nil
+}
+
+// scanLogs queries the blocks from..to chunk by chunk, dropping the first
+// skip logs of block from, until the logs no longer fit the limits. It
+// returns the logs and the last block scanned.
+func scanLogs(filter eth.FilterQuery, from, to uint64, skip int, limits logsLimits, maxResults int) ([]*ethtyp.Log, uint64, error) {
+	logs := []*ethtyp.Log{}
+	for start := from; ; start += logsChunk {
+		end := to
+		if end-start >= logsChunk {
+			end = start + logsChunk - 1
+		}
+		query := filter
+		query.FromBlock = new(big.Int).SetUint64(start)
+		query.ToBlock = new(big.Int).SetUint64(end)
+		chunk, err := backend.GetLogs(query)
+		if err != nil {
+			return nil, 0, err
+		}
+		if start == from {
+			for skipped := 0; skipped < skip && len(chunk) > 0 && chunk[0].BlockNumber == from; skipped++ {
+				chunk = chunk[1:]
+			}
+		}
+		logs = append(logs, chunk...)
+		if n, _ := limits.overLimit(logs, maxResults); n >= 0 || end == to {
+			return logs, end, nil
+		}
+	}
+}
+
+// boundedGetLogs runs a query within the limits, a query over them fails
+// with a narrower range to try instead.
+func boundedGetLogs(filter eth.FilterQuery) (interface{}, error) {
+	limits := getLogsLimits()
+	if filter.BlockHash != nil {
+		logs, err := backend.GetLogs(filter)
+		if err != nil {
+			return nil, jsonrpc.InternalError(err)
+		}
+		if n, msg := limits.overLimit(logs, limits.maxResults); n >= 0 {
+			return nil, jsonrpc.Error("limit_exceeded", msg)
+		}
+		return logs, nil
+	}
+
+	from, to, err := logRange(filter)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	if from > to {
+		return nil, jsonrpc.InvalidParams("invalid block range [0x%x, 0x%x]", from, to)
+	}
+	if limits.maxRange > 0 && to-from+1 > limits.maxRange {
+		msg := fmt.Sprintf("block range exceeds %d blocks", limits.maxRange)
+		return nil, limitError(msg, from, from+limits.maxRange-1)
+	}
+
+	logs, _, err := scanLogs(filter, from, to, 0, limits, limits.maxResults)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	if n, msg := limits.overLimit(logs, limits.maxResults); n >= 0 {
+		suggested := from
+		if block := logs[n].BlockNumber; block > from {
+			suggested = block - 1
+		}
+		return nil, limitError(msg, from, suggested)
+	}
+	return logs, nil
+}
+
+// A cursor points at a block and the number of matching logs of that block
+// already returned.
+func encodeLogsCursor(block uint64, skip int) string {
+	b := make([]byte, 12)
+	binary.BigEndian.PutUint64(b, block)
+	binary.BigEndian.PutUint32(b[8:], uint32(skip))
+	return hexutil.Encode(b)
+}
+
+func decodeLogsCursor(cursor string) (uint64, int, error) {
+	b, err := hexutil.Decode(cursor)
+	if err != nil || len(b) != 12 {
+		return 0, 0, fmt.Errorf("invalid cursor %s", cursor)
+	}
+	return binary.BigEndian.Uint64(b), int(binary.BigEndian.Uint32(b[8:])), nil
+}
+
+// pagedGetLogs serves eth_getLogs with the cursor extension: each call scans
+// at most the range limit from the cursor on and returns at most limit logs,
+// with the cursor to continue from.
+func pagedGetLogs(filter eth.FilterQuery, v interface{}) (interface{}, error) {
+	opts, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, jsonrpc.InvalidParams("invalid page options given %v", v)
+	}
+	if filter.BlockHash != nil {
+		return nil, jsonrpc.InvalidParams("pages are not supported with blockHash")
+	}
+	limits := getLogsLimits()
+	maxResults := limits.maxResults
+	if limit, ok := opts["limit"]; ok {
+		n, err := ToUint64(limit)
+		if err != nil || n == 0 {
+			return nil, jsonrpc.InvalidParams("invalid limit given %v", limit)
+		}
+		if maxResults == 0 || int(n) < maxResults {
+			maxResults = int(n)
+		}
+	}
+
+	from, to, err := logRange(filter)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	skip := 0
+	if cursor, ok := opts["cursor"].(string); ok && cursor != "" {
+		block, n, err := decodeLogsCursor(cursor)
+		if err != nil || block < from || block > to {
+			return nil, jsonrpc.InvalidParams("invalid cursor given %v", cursor)
+		}
+		from, skip = block, n
+	}
+	if from > to {
+		return nil, jsonrpc.InvalidParams("invalid block range [0x%x, 0x%x]", from, to)
+	}
+	end := to
+	if limits.maxRange > 0 && end-from+1 > limits.maxRange {
+		end = from + limits.maxRange - 1
+	}
+
+	logs, scanned, err := scanLogs(filter, from, end, skip, limits, maxResults)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+
+	page := &logsPage{Logs: logs}
+	if n, _ := limits.overLimit(logs, maxResults); n >= 0 {
+		if n == 0 {
+			n = 1 // always make progress, even with a single oversized log
+		}
+		if n < len(logs) {
+			block, returned := logs[n].BlockNumber, 0
+			for _, log := range logs[:n] {
+				if log.BlockNumber == block {
+					returned++
+				}
+			}
+			if block == from {
+				returned += skip
+			}
+			next := encodeLogsCursor(block, returned)
+			page.Logs, page.Cursor = logs[:n], &next
+			return page, nil
+		}
+	}
+	if scanned < to {
+		next := encodeLogsCursor(scanned+1, 0)
+		page.Cursor = &next
+	}
+	return page, nil
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	eth "github.com/arcology-network/evm"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	jsonrpc "github.com/deliveroo/jsonrpc-go"
+	"github.com/spf13/viper"
+)
+
+// logsBackend serves three logs per block for blocks 1 to head.
+type logsBackend struct {
+	internal.EthereumAPI
+	head    uint64
+	scanned int
+}
+
+func (b *logsBackend) BlockNumber() (uint64, error) { return b.head, nil }
+
+func (b *logsBackend) GetLogs(filter eth.FilterQuery) ([]*ethtyp.Log, error) {
+	var logs []*ethtyp.Log
+	for number := filter.FromBlock.Uint64(); number <= filter.ToBlock.Uint64() && number <= b.head; number++ {
+		b.scanned++
+		for i := 0; i < 3; i++ {
+			logs = append(logs, &ethtyp.Log{BlockNumber: number, Index: uint(i)})
+		}
+	}
+	return logs, nil
+}
+
+func TestBoundedGetLogs(t *testing.T) {
+	served := &logsBackend{head: 10}
+	defer func(saved internal.EthereumAPI, chunk uint64) { backend, logsChunk = saved, chunk }(backend, logsChunk)
+	backend, logsChunk = served, 2
+	viper.Set("getlogs-max-range", 5)
+	viper.Set("getlogs-max-results", 4)
+	defer viper.Set("getlogs-max-range", 0)
+	defer viper.Set("getlogs-max-results", 0)
+
+	query := func(from, to int64) eth.FilterQuery {
+		return eth.FilterQuery{FromBlock: big.NewInt(from), ToBlock: big.NewInt(to)}
+	}
+	message := func(err error) string {
+		if rpcErr, ok := err.(*jsonrpc.RPCError); ok {
+			return rpcErr.Message
+		}
+		return fmt.Sprint(err)
+	}
+	if _, err := boundedGetLogs(query(1, 10)); message(err) != "block range exceeds 5 blocks; try [0x1, 0x5]" {
+		t.Fatalf("expected the range limit, got %v", err)
+	}
+	if _, err := boundedGetLogs(query(1, 5)); message(err) != "query returned more than 4 results; try [0x1, 0x1]" {
+		t.Fatalf("expected the result limit, got %v", err)
+	}
+	if served.scanned != 2 {
+		t.Fatalf("expected the scan to stop after the first chunk, scanned %d blocks", served.scanned)
+	}
+
+	var all []*ethtyp.Log
+	opts := map[string]interface{}{}
+	for pages := 0; ; pages++ {
+		if pages > 20 {
+			t.Fatal("pagination does not terminate")
+		}
+		result, err := pagedGetLogs(query(1, 10), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page := result.(*logsPage)
+		all = append(all, page.Logs...)
+		if page.Cursor == nil {
+			break
+		}
+		opts["cursor"] = *page.Cursor
+	}
+	if len(all) != 30 {
+		t.Fatalf("expected 30 logs over all pages, got %d", len(all))
+	}
+	for i, log := range all {
+		if log.BlockNumber != uint64(i/3+1) || log.Index != uint(i%3) {
+			t.Fatalf("log %d out of order: block %d index %d", i, log.BlockNumber, log.Index)
+		}
+	}
+}
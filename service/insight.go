@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/common/hexutil"
+	jsonrpc "github.com/deliveroo/jsonrpc-go"
+)
+
+var (
+	executionInsights *internal.ExecutionInsights
+
+	errNoExecutionInsights = errors.New("execution insights not configured")
+)
+
+// insightMethods serve the parallel execution metadata of recent blocks. The
+// batches they report are the rounds in which receipts were published, not
+// the executing sequences of the scheduler, which this service never sees.
+func insightMethods() jsonrpc.Methods {
+	return jsonrpc.Methods{
+		"arcology_getBlockExecution":       arcologyGetBlockExecution,
+		"arcology_getTransactionExecution": arcologyGetTransactionExecution,
+	}
+}
+
+type executionBatchResponse struct {
+	Round        hexutil.Uint64 `json:"round"`
+	Transactions []ethcmn.Hash  `json:"transactions"`
+}
+
+type txExecutionResponse struct {
+	Hash       ethcmn.Hash    `json:"hash"`
+	Batches    []int          `json:"batches"`
+	Reexecuted bool           `json:"reexecuted"`
+	Included   bool           `json:"included"`
+	Status     hexutil.Uint64 `json:"status"`
+	Reason     string         `json:"reason,omitempty"`
+}
+
+type blockExecutionResponse struct {
+	Number       hexutil.Uint64            `json:"number"`
+	Batches      []*executionBatchResponse `json:"batches"`
+	Transactions []*txExecutionResponse    `json:"transactions"`
+	Conflicts    []ethcmn.Hash             `json:"conflicts"`
+}
+
+func txExecution(tx *internal.TxExecution) *txExecutionResponse {
+	return &txExecutionResponse{
+		Hash:       tx.Hash,
+		Batches:    tx.Batches,
+		Reexecuted: len(tx.Batches) > 1,
+		Included:   tx.Included,
+		Status:     hexutil.Uint64(tx.Status),
+		Reason:     tx.Reason,
+	}
+}
+
+func arcologyGetBlockExecution(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, jsonrpc.InvalidParams("block number expected")
+	}
+	number, err := ToBlockNumber(params[0])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid block number given %v", params[0])
+	}
+	if executionInsights == nil {
+		return nil, jsonrpc.InternalError(errNoExecutionInsights)
+	}
+	if number < 0 {
+		latest, err := backend.BlockNumber()
+		if err != nil {
+			return nil, jsonrpc.InternalError(err)
+		}
+		number = int64(latest)
+	}
+	block := executionInsights.Block(uint64(number))
+	if block == nil {
+		return nil, nil
+	}
+
+	response := &blockExecutionResponse{
+		Number:       hexutil.Uint64(block.Height),
+		Batches:      make([]*executionBatchResponse, len(block.Batches)),
+		Transactions: make([]*txExecutionResponse, len(block.Txs)),
+		Conflicts:    []ethcmn.Hash{},
+	}
+	for i, batch := range block.Batches {
+		response.Batches[i] = &executionBatchResponse{Round: hexutil.Uint64(batch.Round), Transactions: batch.Txs}
+	}
+	for i, tx := range block.Txs {
+		response.Transactions[i] = txExecution(tx)
+		if tx.Reason == internal.ExecutionConflict {
+			response.Conflicts = append(response.Conflicts, tx.Hash)
+		}
+	}
+	return response, nil
+}
+
+func arcologyGetTransactionExecution(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, jsonrpc.InvalidParams("transaction hash expected")
+	}
+	hash, err := ToHash(params[0])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid hash given %v", params[0])
+	}
+	if executionInsights == nil {
+		return nil, jsonrpc.InternalError(errNoExecutionInsights)
+	}
+	block, tx := executionInsights.Transaction(hash)
+	if tx == nil {
+		return nil, nil
+	}
+	return map[string]interface{}{
+		"blockNumber": hexutil.Uint64(block.Height),
+		"execution":   txExecution(tx),
+	}, nil
+}
@@ -0,0 +1,494 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/arcology-network/component-lib/ethrpc"
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/common/hexutil"
+	jsonrpc "github.com/deliveroo/jsonrpc-go"
+)
+
+// otsAPILevel is the version of the Otterscan API these methods implement.
+const otsAPILevel = 8
+
+// otsCreatorPage is how many blocks of transactions to an address are read
+// at a time looking for its creation.
+var otsCreatorPage = 16
+
+var (
+	accountIndex *internal.AccountIndex
+	txTracer     internal.Tracer
+
+	errNoAccountIndex = errors.New("account index not configured")
+	errNoTracer       = errors.New("tracing not supported by this backend")
+)
+
+// otsMethods are the Otterscan methods. The searches need the account index,
+// the traces a backend that can trace.
+func otsMethods() jsonrpc.Methods {
+	return jsonrpc.Methods{
+		"ots_getApiLevel":                    otsGetApiLevel,
+		"ots_hasCode":                        otsHasCode,
+		"ots_getBlockDetails":                otsGetBlockDetails,
+		"ots_getBlockTransactions":           otsGetBlockTransactions,
+		"ots_getContractCreator":             otsGetContractCreator,
+		"ots_getInternalOperations":          otsGetInternalOperations,
+		"ots_traceTransaction":               otsTraceTransaction,
+		"ots_searchTransactionsBefore":       otsSearchTransactionsBefore,
+		"ots_searchTransactionsAfter":        otsSearchTransactionsAfter,
+		"ots_getTransactionBySenderAndNonce": otsGetTransactionBySenderAndNonce,
+	}
+}
+
+func otsGetApiLevel(ctx context.Context) (interface{}, error) {
+	return otsAPILevel, nil
+}
+
+func otsHasCode(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 2 {
+		return nil, jsonrpc.InvalidParams("address and block number expected")
+	}
+	address, err := ToAddress(params[0])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid address given %v", params[0])
+	}
+	number, err := ToBlockNumber(params[1])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid block number given %v", params[1])
+	}
+	code, err := backend.GetCode(address, number)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	return len(code) > 0, nil
+}
+
+// otsBlock renders a block without its transactions. Otterscan has no use for
+// the bloom, it is left out to keep the reply small.
+func otsBlock(block *ethrpc.RPCBlock) map[string]interface{} {
+	fields := parseBlock(block).(map[string]interface{})
+	delete(fields, "transactions")
+	fields["transactionCount"] = len(block.Transactions)
+	fields["logsBloom"] = nil
+	return fields
+}
+
+// otsReceipt renders a receipt with the timestamp of its block.
+func otsReceipt(receipt *internal.BlockReceipt, timestamp uint64) map[string]interface{} {
+	fields := formatReceipt(receipt)
+	fields["timestamp"] = hexutil.Uint64(timestamp)
+	return fields
+}
+
+// otsBlockWithReceipts loads a block with its transactions and their
+// receipts by transaction hash, the block is nil if it is unknown.
+func otsBlockWithReceipts(v interface{}) (*ethrpc.RPCBlock, map[ethcmn.Hash]*internal.BlockReceipt, error) {
+	number, err := ToBlockNumber(v)
+	if err != nil {
+		return nil, nil, jsonrpc.InvalidParams("invalid block number given %v", v)
+	}
+	block, err := backend.GetBlockByNumber(number, true)
+	if err != nil {
+		return nil, nil, jsonrpc.InternalError(err)
+	}
+	if block == nil || block.Header == nil {
+		return nil, nil, nil
+	}
+	receipts, err := blockReceipts(hexutil.EncodeUint64(block.Header.Number.Uint64()))
+	if err != nil {
+		return nil, nil, err
+	}
+	byHash := make(map[ethcmn.Hash]*internal.BlockReceipt, len(receipts))
+	for _, receipt := range receipts {
+		byHash[receipt.Receipt.TxHash] = receipt
+	}
+	return block, byHash, nil
+}
+
+func blockTransactions(block *ethrpc.RPCBlock) []*ethrpc.RPCTransaction {
+	txs := make([]*ethrpc.RPCTransaction, 0, len(block.Transactions))
+	for _, tx := range block.Transactions {
+		if tx, ok := tx.(*ethrpc.RPCTransaction); ok {
+			txs = append(txs, tx)
+		}
+	}
+	return txs
+}
+
+func otsGetBlockDetails(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, jsonrpc.InvalidParams("block number expected")
+	}
+	block, receipts, err := otsBlockWithReceipts(params[0])
+	if err != nil || block == nil {
+		return nil, err
+	}
+
+	fees := new(big.Int)
+	for _, tx := range blockTransactions(block) {
+		receipt, ok := receipts[tx.Hash]
+		if !ok {
+			return nil, jsonrpc.InternalError(fmt.Errorf("receipt of %x not found", tx.Hash))
+		}
+		if tx.GasPrice != nil {
+			fees.Add(fees, new(big.Int).Mul(tx.GasPrice, new(big.Int).SetUint64(receipt.Receipt.GasUsed)))
+		}
+	}
+	// Monaco pays no block or uncle rewards.
+	return map[string]interface{}{
+		"block": otsBlock(block),
+		"issuance": map[string]interface{}{
+			"blockReward": "0x0",
+			"uncleReward": "0x0",
+			"issuance":    "0x0",
+		},
+		"totalFees": (*hexutil.Big)(fees),
+	}, nil
+}
+
+func otsGetBlockTransactions(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 3 {
+		return nil, jsonrpc.InvalidParams("block number, page number and page size expected")
+	}
+	pageNumber, err := ToUint64(params[1])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid page number given %v", params[1])
+	}
+	pageSize, err := ToUint64(params[2])
+	if err != nil || pageSize == 0 {
+		return nil, jsonrpc.InvalidParams("invalid page size given %v", params[2])
+	}
+	block, byHash, err := otsBlockWithReceipts(params[0])
+	if err != nil || block == nil {
+		return nil, err
+	}
+
+	txs := blockTransactions(block)
+	start, end := pageNumber*pageSize, (pageNumber+1)*pageSize
+	if start > uint64(len(txs)) {
+		start = uint64(len(txs))
+	}
+	if end > uint64(len(txs)) {
+		end = uint64(len(txs))
+	}
+	txs = txs[start:end]
+
+	receipts := make([]map[string]interface{}, 0, len(txs))
+	for _, tx := range txs {
+		receipt, ok := byHash[tx.Hash]
+		if !ok {
+			return nil, jsonrpc.InternalError(fmt.Errorf("receipt of %x not found", tx.Hash))
+		}
+		fields := otsReceipt(receipt, block.Header.Time)
+		fields["logs"], fields["logsBloom"] = nil, nil
+		receipts = append(receipts, fields)
+	}
+
+	fullblock := otsBlock(block)
+	fullblock["transactions"] = txs
+	return map[string]interface{}{
+		"fullblock": fullblock,
+		"receipts":  receipts,
+	}, nil
+}
+
+// otsGetContractCreator finds the transaction that deployed a contract. It
+// comes from the contract index when there is one, otherwise it is the oldest
+// indexed transaction to the contract that is a creation; transactions sent
+// to the address before it was deployed come first. Either way contracts
+// created by other contracts are not found.
+func otsGetContractCreator(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, jsonrpc.InvalidParams("address expected")
+	}
+	address, err := ToAddress(params[0])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid address given %v", params[0])
+	}
+	if contractIndex != nil {
+		creation, err := contractIndex.Creation(address)
+		if err != nil {
+			return nil, jsonrpc.InternalError(err)
+		}
+		if creation == nil {
+			return nil, nil
+		}
+		return map[string]interface{}{
+			"hash":    creation.TxHash,
+			"creator": creation.Creator,
+		}, nil
+	}
+	if accountIndex == nil {
+		return nil, jsonrpc.InternalError(errNoAccountIndex)
+	}
+	for after := uint64(0); ; {
+		refs, more := accountIndex.After(address, after, otsCreatorPage)
+		for _, ref := range refs {
+			tx, err := backend.GetTransactionByHash(ref.Hash)
+			if err != nil {
+				return nil, jsonrpc.InternalError(err)
+			}
+			if tx == nil || tx.To != nil {
+				continue
+			}
+			receipt, err := backend.GetTransactionReceipt(ref.Hash)
+			if err != nil {
+				return nil, jsonrpc.InternalError(err)
+			}
+			if receipt != nil && receipt.ContractAddress == address {
+				return map[string]interface{}{
+					"hash":    tx.Hash,
+					"creator": tx.From,
+				}, nil
+			}
+		}
+		if !more || len(refs) == 0 {
+			return nil, nil
+		}
+		after = refs[len(refs)-1].Block
+	}
+}
+
+// callTrace traces a transaction with the call tracer.
+func callTrace(params []interface{}) (*internal.CallFrame, error) {
+	if len(params) < 1 {
+		return nil, jsonrpc.InvalidParams("transaction hash expected")
+	}
+	hash, err := ToHash(params[0])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid hash given %v", params[0])
+	}
+	if txTracer == nil {
+		return nil, jsonrpc.InternalError(errNoTracer)
+	}
+	data, err := txTracer.TraceTransaction(hash, &internal.TraceConfig{Tracer: "callTracer"})
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	var root internal.CallFrame
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	return &root, nil
+}
+
+// walkCalls visits the calls of a trace depth first, the root at depth 0.
+func walkCalls(frame *internal.CallFrame, depth int, visit func(*internal.CallFrame, int)) {
+	visit(frame, depth)
+	for _, call := range frame.Calls {
+		walkCalls(call, depth+1, visit)
+	}
+}
+
+type otsTrace struct {
+	Type  string         `json:"type"`
+	Depth int            `json:"depth"`
+	From  ethcmn.Address `json:"from"`
+	To    ethcmn.Address `json:"to"`
+	Value *hexutil.Big   `json:"value"`
+	Input hexutil.Bytes  `json:"input"`
+}
+
+func otsTraceTransaction(ctx context.Context, params []interface{}) (interface{}, error) {
+	root, err := callTrace(params)
+	if err != nil {
+		return nil, err
+	}
+	traces := []*otsTrace{}
+	walkCalls(root, 0, func(call *internal.CallFrame, depth int) {
+		traces = append(traces, &otsTrace{
+			Type:  call.Type,
+			Depth: depth,
+			From:  call.From,
+			To:    call.To,
+			Value: call.Value,
+			Input: call.Input,
+		})
+	})
+	return traces, nil
+}
+
+// Kinds of internal operations.
+const (
+	otsTransfer = iota
+	otsSelfDestruct
+	otsCreate
+	otsCreate2
+)
+
+type otsInternalOperation struct {
+	Type  int            `json:"type"`
+	From  ethcmn.Address `json:"from"`
+	To    ethcmn.Address `json:"to"`
+	Value *hexutil.Big   `json:"value"`
+}
+
+func otsGetInternalOperations(ctx context.Context, params []interface{}) (interface{}, error) {
+	root, err := callTrace(params)
+	if err != nil {
+		return nil, err
+	}
+	operations := []*otsInternalOperation{}
+	walkCalls(root, 0, func(call *internal.CallFrame, depth int) {
+		if depth == 0 || call.Error != "" {
+			return
+		}
+		operation := &otsInternalOperation{From: call.From, To: call.To, Value: call.Value}
+		if operation.Value == nil {
+			operation.Value = (*hexutil.Big)(new(big.Int))
+		}
+		switch call.Type {
+		case "CALL", "CALLCODE":
+			if operation.Value.ToInt().Sign() == 0 {
+				return
+			}
+			operation.Type = otsTransfer
+		case "SELFDESTRUCT":
+			operation.Type = otsSelfDestruct
+		case "CREATE":
+			operation.Type = otsCreate
+		case "CREATE2":
+			operation.Type = otsCreate2
+		default:
+			return
+		}
+		operations = append(operations, operation)
+	})
+	return operations, nil
+}
+
+// otsSearchResult is a page of the transactions of an address, newest first.
+// The first page holds the newest transactions, the last page the oldest.
+type otsSearchResult struct {
+	Txs       []*ethrpc.RPCTransaction `json:"txs"`
+	Receipts  []map[string]interface{} `json:"receipts"`
+	FirstPage bool                     `json:"firstPage"`
+	LastPage  bool                     `json:"lastPage"`
+}
+
+func toSearchParams(params []interface{}) (address ethcmn.Address, number uint64, pageSize int, err error) {
+	if len(params) < 3 {
+		return ethcmn.Address{}, 0, 0, jsonrpc.InvalidParams("address, block number and page size expected")
+	}
+	addr, err := ToAddress(params[0])
+	if err != nil {
+		return ethcmn.Address{}, 0, 0, jsonrpc.InvalidParams("invalid address given %v", params[0])
+	}
+	if number, err = ToUint64(params[1]); err != nil {
+		return ethcmn.Address{}, 0, 0, jsonrpc.InvalidParams("invalid block number given %v", params[1])
+	}
+	size, err := ToUint64(params[2])
+	if err != nil || size == 0 {
+		return ethcmn.Address{}, 0, 0, jsonrpc.InvalidParams("invalid page size given %v", params[2])
+	}
+	return addr, number, int(size), nil
+}
+
+func otsSearchTransactionsBefore(ctx context.Context, params []interface{}) (interface{}, error) {
+	address, number, pageSize, err := toSearchParams(params)
+	if err != nil {
+		return nil, err
+	}
+	if accountIndex == nil {
+		return nil, jsonrpc.InternalError(errNoAccountIndex)
+	}
+	refs, more := accountIndex.Before(address, number, pageSize)
+	result, err := otsSearchPage(refs)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	result.FirstPage, result.LastPage = number == 0, !more
+	return result, nil
+}
+
+func otsSearchTransactionsAfter(ctx context.Context, params []interface{}) (interface{}, error) {
+	address, number, pageSize, err := toSearchParams(params)
+	if err != nil {
+		return nil, err
+	}
+	if accountIndex == nil {
+		return nil, jsonrpc.InternalError(errNoAccountIndex)
+	}
+	refs, more := accountIndex.After(address, number, pageSize)
+	for i, j := 0, len(refs)-1; i < j; i, j = i+1, j-1 {
+		refs[i], refs[j] = refs[j], refs[i]
+	}
+	result, err := otsSearchPage(refs)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	result.FirstPage, result.LastPage = !more, number == 0
+	return result, nil
+}
+
+// otsSearchPage loads the transactions and receipts of a page, receipts carry
+// the timestamp of their block.
+func otsSearchPage(refs []internal.AccountTxRef) (*otsSearchResult, error) {
+	result := &otsSearchResult{
+		Txs:      make([]*ethrpc.RPCTransaction, 0, len(refs)),
+		Receipts: make([]map[string]interface{}, 0, len(refs)),
+	}
+	timestamps := make(map[uint64]uint64)
+	for _, ref := range refs {
+		tx, err := backend.GetTransactionByHash(ref.Hash)
+		if err != nil {
+			return nil, err
+		}
+		receipt, err := backend.GetTransactionReceipt(ref.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if tx == nil || receipt == nil {
+			return nil, fmt.Errorf("transaction %x not found", ref.Hash)
+		}
+		timestamp, ok := timestamps[ref.Block]
+		if !ok {
+			block, err := backend.GetBlockByNumber(int64(ref.Block), false)
+			if err != nil {
+				return nil, err
+			}
+			if block == nil || block.Header == nil {
+				return nil, fmt.Errorf("block %d not found", ref.Block)
+			}
+			timestamp = block.Header.Time
+			timestamps[ref.Block] = timestamp
+		}
+
+		r, err := txReceipt(receipt, tx)
+		if err != nil {
+			return nil, err
+		}
+		fields := otsReceipt(r, timestamp)
+		result.Txs = append(result.Txs, tx)
+		result.Receipts = append(result.Receipts, fields)
+	}
+	return result, nil
+}
+
+func otsGetTransactionBySenderAndNonce(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 2 {
+		return nil, jsonrpc.InvalidParams("sender and nonce expected")
+	}
+	sender, err := ToAddress(params[0])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid address given %v", params[0])
+	}
+	nonce, err := ToUint64(params[1])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid nonce given %v", params[1])
+	}
+	if accountIndex == nil {
+		return nil, jsonrpc.InternalError(errNoAccountIndex)
+	}
+	hash, ok := accountIndex.BySenderAndNonce(sender, nonce)
+	if !ok {
+		return nil, nil
+	}
+	return hash, nil
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/arcology-network/component-lib/ethrpc"
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+	ethcrp "github.com/arcology-network/evm/crypto"
+	"github.com/arcology-network/evm/ethdb/memorydb"
+)
+
+// emptyBackend knows no blocks.
+type emptyBackend struct {
+	internal.EthereumAPI
+}
+
+func (emptyBackend) GetBlockByNumber(number int64, fullTx bool) (*ethrpc.RPCBlock, error) {
+	return nil, nil
+}
+
+func TestOtsMissingBlock(t *testing.T) {
+	defer func(saved internal.EthereumAPI) { backend = saved }(backend)
+	backend = emptyBackend{}
+	ctx := context.Background()
+
+	if details, err := otsGetBlockDetails(ctx, []interface{}{"0x5"}); err != nil || details != nil {
+		t.Fatalf("expected no details of an unknown block, got %v %v", details, err)
+	}
+	if txs, err := otsGetBlockTransactions(ctx, []interface{}{"0x5", float64(0), float64(10)}); err != nil || txs != nil {
+		t.Fatalf("expected no transactions of an unknown block, got %v %v", txs, err)
+	}
+	if _, err := otsHasCode(ctx, []interface{}{"0x0000000000000000000000000000000000000001"}); err == nil {
+		t.Fatal("expected the missing block number to be rejected")
+	}
+}
+
+func TestOtsContractCreator(t *testing.T) {
+	savedBackend, savedIndex, savedContracts, savedPage := backend, accountIndex, contractIndex, otsCreatorPage
+	t.Cleanup(func() {
+		backend, accountIndex, contractIndex, otsCreatorPage = savedBackend, savedIndex, savedContracts, savedPage
+	})
+	chain := internal.NewDevChain(big.NewInt(1), nil, 0, nil)
+	index, err := internal.NewAccountIndex(memorydb.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend, accountIndex, contractIndex, otsCreatorPage = chain, index, nil, 1
+	ctx := context.Background()
+
+	// the address is funded twice before the contract is deployed at it
+	sender := ethcmn.Address{1}
+	contract := ethcrp.CreateAddress(sender, 2)
+	chain.SetBalance(sender, big.NewInt(1e18))
+	chain.ImpersonateAccount(sender)
+	msgs := []eth.CallMsg{{From: sender, To: &contract, Value: big.NewInt(1)}, {From: sender, To: &contract, Value: big.NewInt(1)}, {From: sender}}
+	var creation ethcmn.Hash
+	for i, msg := range msgs {
+		hash, err := chain.SendImpersonatedTransaction(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		number, _ := chain.BlockNumber()
+		if err := index.Add(number, []internal.AccountTx{{Hash: hash, From: sender, To: &contract, Nonce: uint64(i)}}); err != nil {
+			t.Fatal(err)
+		}
+		creation = hash
+	}
+
+	if _, err := otsGetContractCreator(ctx, nil); err == nil {
+		t.Fatal("expected the missing address to be rejected")
+	}
+	if _, err := otsTraceTransaction(ctx, nil); err == nil {
+		t.Fatal("expected the missing hash to be rejected")
+	}
+	found, err := otsGetContractCreator(ctx, []interface{}{contract.Hex()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creator, _ := found.(map[string]interface{}); creator == nil || creator["hash"] != creation || creator["creator"] != sender {
+		t.Fatalf("expected the creation after the transfers, got %v", found)
+	}
+}
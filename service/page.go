@@ -0,0 +1,46 @@
+package service
+
+import (
+	"github.com/arcology-network/evm/common/hexutil"
+	jsonrpc "github.com/deliveroo/jsonrpc-go"
+)
+
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// itemsPage is a page of a query over one of the indexes, the cursor fetches
+// the following page and is null on the last one.
+type itemsPage struct {
+	Items  interface{} `json:"items"`
+	Cursor *string     `json:"cursor"`
+}
+
+// toPage parses the cursor and limit of a paged query.
+func toPage(opts map[string]interface{}) ([]byte, int, error) {
+	var cursor []byte
+	if v, ok := opts["cursor"].(string); ok && v != "" {
+		var err error
+		if cursor, err = hexutil.Decode(v); err != nil {
+			return nil, 0, jsonrpc.InvalidParams("invalid cursor given %v", v)
+		}
+	}
+	limit := defaultPageLimit
+	if v, ok := opts["limit"]; ok {
+		n, err := ToUint64(v)
+		if err != nil || n == 0 || n > maxPageLimit {
+			return nil, 0, jsonrpc.InvalidParams("invalid limit given %v, at most %d", v, maxPageLimit)
+		}
+		limit = int(n)
+	}
+	return cursor, limit, nil
+}
+
+func nextCursor(next []byte) *string {
+	if next == nil {
+		return nil
+	}
+	cursor := hexutil.Encode(next)
+	return &cursor
+}
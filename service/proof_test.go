@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/common/hexutil"
+)
+
+func TestGetProof(t *testing.T) {
+	chain := internal.NewDevChain(big.NewInt(1), nil, 0, nil)
+	saved := backend
+	t.Cleanup(func() { backend = saved })
+	backend = chain
+	ctx := context.Background()
+	address := ethcmn.Address{1}
+	if err := chain.SetBalance(address, big.NewInt(1000)); err != nil {
+		t.Fatal(err)
+	}
+	if err := chain.SetStorageAt(address, ethcmn.Hash{1}, ethcmn.BigToHash(big.NewInt(42))); err != nil {
+		t.Fatal(err)
+	}
+	number, err := chain.Mine(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag := hexutil.EncodeUint64(number)
+
+	block, err := getBlockByNumber(ctx, []interface{}{tag})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := block.(map[string]interface{})["stateRoot"].(ethcmn.Hash)
+	keys := []interface{}{ethcmn.Hash{1}.Hex(), ethcmn.Hash{2}.Hex()}
+	response, err := getProof(ctx, []interface{}{address.Hex(), keys, tag})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := response.(*internal.AccountResult)
+	if result.Balance.ToInt().Int64() != 1000 || result.StorageProof[0].Value.ToInt().Int64() != 42 || result.StorageProof[1].Value.ToInt().Sign() != 0 {
+		t.Fatalf("unexpected proof %+v", result)
+	}
+	if err := internal.VerifyProof(root, result); err != nil {
+		t.Fatal(err)
+	}
+
+	result.Balance = (*hexutil.Big)(big.NewInt(1001))
+	if err := internal.VerifyProof(root, result); err == nil {
+		t.Fatal("expected a tampered balance to fail")
+	}
+	result.Balance = (*hexutil.Big)(big.NewInt(1000))
+	result.StorageProof[0].Value = (*hexutil.Big)(big.NewInt(43))
+	if err := internal.VerifyProof(root, result); err == nil {
+		t.Fatal("expected a tampered slot to fail")
+	}
+
+	absent, err := getProof(ctx, []interface{}{ethcmn.Address{2}.Hex(), []interface{}{}, tag})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := internal.VerifyProof(root, absent.(*internal.AccountResult)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	jsonrpc "github.com/deliveroo/jsonrpc-go"
+	"github.com/spf13/viper"
+)
+
+// apiFilters is the filter set behind the backend, it enforces the client
+// quotas and serves the admin api.
+var apiFilters *internal.Filters
+
+// apiKeys are the api keys clients may be identified by. Other keys are
+// ignored, otherwise a client could dodge its quotas by making keys up.
+var apiKeys = map[string]bool{}
+
+// peerHosts are the addresses of the instances sharing filters, the only
+// ones trusted to forward a call on behalf of a client.
+var peerHosts = map[string]bool{}
+
+// setPeerHosts resolves the hosts of the peer rpc urls.
+func setPeerHosts(peers []string) {
+	peerHosts = map[string]bool{}
+	for _, peer := range peers {
+		u, err := url.Parse(peer)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+		addrs, err := net.LookupHost(u.Hostname())
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			peerHosts[addr] = true
+		}
+	}
+}
+
+// requestKey returns the api key a request was sent with, if any.
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get("X-Api-Key"); key != "" {
+		return key
+	}
+	return r.URL.Query().Get("apikey")
+}
+
+func apiKey(ctx context.Context) string {
+	r := jsonrpc.RequestFromContext(ctx)
+	if r == nil {
+		return ""
+	}
+	return requestKey(r)
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// requestClient identifies the client of a request: by the identity a peer
+// forwarded it with, by an allowed api key or else by remote ip.
+func requestClient(r *http.Request) string {
+	if r.Header.Get(internal.FilterHopHeader) != "" && peerHosts[remoteHost(r)] {
+		if client := r.Header.Get(internal.FilterClientHeader); client != "" {
+			return client
+		}
+	}
+	if key := requestKey(r); key != "" && apiKeys[key] {
+		return "key:" + key
+	}
+	if viper.GetBool("trust-forwarded-for") {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return "ip:" + strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+	return "ip:" + remoteHost(r)
+}
+
+// clientID identifies the client of a request for its quotas.
+func clientID(ctx context.Context) string {
+	r := jsonrpc.RequestFromContext(ctx)
+	if r == nil {
+		return "ip:unknown"
+	}
+	return requestClient(r)
+}
+
+// forwardedByPeer reports whether a request is a filter call forwarded by
+// another instance, which must be answered without forwarding it again.
+func forwardedByPeer(ctx context.Context) bool {
+	r := jsonrpc.RequestFromContext(ctx)
+	return r != nil && r.Header.Get(internal.FilterHopHeader) != ""
+}
+
+func quotaError(err error) error {
+	if _, ok := err.(*internal.ErrQuotaExceeded); ok {
+		return jsonrpc.Error("limit_exceeded", err.Error())
+	}
+	return jsonrpc.InternalError(err)
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	internal "github.com/arcology-network/eth-api-svc/backend"
+)
+
+func TestRequestClient(t *testing.T) {
+	defer func(keys, hosts map[string]bool) { apiKeys, peerHosts = keys, hosts }(apiKeys, peerHosts)
+	apiKeys = map[string]bool{"granted": true}
+	peerHosts = map[string]bool{"10.0.0.2": true}
+
+	request := func(remote string, headers map[string]string) string {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.RemoteAddr = remote + ":1234"
+		for name, value := range headers {
+			r.Header.Set(name, value)
+		}
+		return requestClient(r)
+	}
+
+	if id := request("10.0.0.1", map[string]string{"X-Api-Key": "granted"}); id != "key:granted" {
+		t.Fatalf("expected the allowed key, got %s", id)
+	}
+	if id := request("10.0.0.1", map[string]string{"X-Api-Key": "made-up"}); id != "ip:10.0.0.1" {
+		t.Fatalf("expected an unknown key to be ignored, got %s", id)
+	}
+
+	forwarded := map[string]string{internal.FilterHopHeader: "1", internal.FilterClientHeader: "ip:10.0.0.9"}
+	if id := request("10.0.0.2", forwarded); id != "ip:10.0.0.9" {
+		t.Fatalf("expected the identity a peer forwarded, got %s", id)
+	}
+	if id := request("10.0.0.3", forwarded); id != "ip:10.0.0.3" {
+		t.Fatalf("expected a forwarded identity from a stranger to be ignored, got %s", id)
+	}
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/arcology-network/component-lib/ethrpc"
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/common/hexutil"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	jsonrpc "github.com/deliveroo/jsonrpc-go"
+)
+
+var receiptCache *internal.ReceiptCache
+
+// formatReceipt renders a receipt the way geth's eth_getTransactionReceipt
+// does.
+func formatReceipt(r *internal.BlockReceipt) map[string]interface{} {
+	receipt := r.Receipt
+	logs := receipt.Logs
+	if logs == nil {
+		logs = []*ethtyp.Log{}
+	}
+	var blockNumber *hexutil.Big
+	if receipt.BlockNumber != nil {
+		blockNumber = (*hexutil.Big)(receipt.BlockNumber)
+	}
+	fields := map[string]interface{}{
+		"blockHash":         receipt.BlockHash,
+		"blockNumber":       blockNumber,
+		"transactionHash":   receipt.TxHash,
+		"transactionIndex":  hexutil.Uint64(receipt.TransactionIndex),
+		"from":              r.From,
+		"to":                r.To,
+		"gasUsed":           hexutil.Uint64(receipt.GasUsed),
+		"cumulativeGasUsed": hexutil.Uint64(receipt.CumulativeGasUsed),
+		"contractAddress":   nil,
+		"logs":              logs,
+		"logsBloom":         receipt.Bloom,
+		"type":              hexutil.Uint(receipt.Type),
+		"effectiveGasPrice": (*hexutil.Big)(r.GasPrice),
+	}
+	if len(receipt.PostState) > 0 {
+		fields["root"] = hexutil.Bytes(receipt.PostState)
+	} else {
+		fields["status"] = hexutil.Uint(receipt.Status)
+	}
+	if receipt.ContractAddress != (ethcmn.Address{}) {
+		fields["contractAddress"] = receipt.ContractAddress
+	}
+	return fields
+}
+
+// txReceipt pairs a receipt with the fields of its transaction, taken from
+// its block when tx is nil, and numbers its logs across the block. Storage
+// numbers the logs of each receipt from 0, so the logs of the receipts before
+// it in the block are added up.
+func txReceipt(receipt *ethtyp.Receipt, tx *ethrpc.RPCTransaction) (*internal.BlockReceipt, error) {
+	if receiptCache != nil {
+		if cached, ok := receiptCache.ByTransaction(receipt.TxHash); ok {
+			return cached, nil
+		}
+	}
+
+	index := int(receipt.TransactionIndex)
+	var block *ethrpc.RPCBlock
+	if tx == nil || (len(receipt.Logs) > 0 && index > 0) {
+		var err error
+		if block, err = backend.GetBlockByHash(receipt.BlockHash, true); err != nil {
+			return nil, err
+		}
+		if block == nil || block.Header == nil || index >= len(block.Transactions) {
+			return nil, fmt.Errorf("block %x of transaction %x not found", receipt.BlockHash, receipt.TxHash)
+		}
+	}
+	if tx == nil {
+		var ok bool
+		if tx, ok = block.Transactions[index].(*ethrpc.RPCTransaction); !ok {
+			return nil, fmt.Errorf("block %x: unexpected transaction %T", receipt.BlockHash, block.Transactions[index])
+		}
+	}
+
+	offset := 0
+	if len(receipt.Logs) > 0 && index > 0 {
+		for _, v := range block.Transactions[:index] {
+			previousTx, ok := v.(*ethrpc.RPCTransaction)
+			if !ok {
+				return nil, fmt.Errorf("block %x: unexpected transaction %T", receipt.BlockHash, v)
+			}
+			previous, err := backend.GetTransactionReceipt(previousTx.Hash)
+			if err != nil {
+				return nil, err
+			}
+			if previous != nil {
+				offset += len(previous.Logs)
+			}
+		}
+	}
+
+	numbered := *receipt
+	numbered.Logs = make([]*ethtyp.Log, len(receipt.Logs))
+	for i, log := range receipt.Logs {
+		cpy := *log
+		cpy.Index = uint(offset + i)
+		numbered.Logs[i] = &cpy
+	}
+	return &internal.BlockReceipt{
+		Receipt:  &numbered,
+		From:     tx.From,
+		To:       tx.To,
+		GasPrice: tx.GasPrice,
+	}, nil
+}
+
+// blockReceipts looks a block up by number, tag or hash, in the receipt
+// cache first.
+func blockReceipts(v interface{}) ([]*internal.BlockReceipt, error) {
+	if str, ok := v.(string); ok && len(str) == 66 {
+		hash, err := ToHash(str)
+		if err != nil {
+			return nil, jsonrpc.InvalidParams("invalid block hash given %v", v)
+		}
+		if receiptCache != nil {
+			if receipts, ok := receiptCache.ByHash(hash); ok {
+				return receipts, nil
+			}
+		}
+		receipts, err := backend.GetBlockReceiptsByHash(hash)
+		if err != nil {
+			return nil, jsonrpc.InternalError(err)
+		}
+		return receipts, nil
+	}
+
+	number, err := ToBlockNumber(v)
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid block number given %v", v)
+	}
+	if receiptCache != nil {
+		height := uint64(number)
+		if number == ethrpc.BlockNumberLatest || number == ethrpc.BlockNumberPending {
+			if height, err = backend.BlockNumber(); err != nil {
+				return nil, jsonrpc.InternalError(err)
+			}
+		}
+		if receipts, ok := receiptCache.ByNumber(height); ok {
+			return receipts, nil
+		}
+	}
+	receipts, err := backend.GetBlockReceipts(number)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	return receipts, nil
+}
+
+func getBlockReceipts(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, jsonrpc.InvalidParams("block number or hash expected")
+	}
+	receipts, err := blockReceipts(params[0])
+	if err != nil {
+		return nil, err
+	}
+	response := make([]map[string]interface{}, len(receipts))
+	for i, receipt := range receipts {
+		response[i] = formatReceipt(receipt)
+	}
+	return response, nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/arcology-network/component-lib/ethrpc"
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	ethcmn "github.com/arcology-network/evm/common"
+	ethtyp "github.com/arcology-network/evm/core/types"
+)
+
+// receiptsBackend serves the receipts and transactions of one block.
+type receiptsBackend struct {
+	internal.EthereumAPI
+	receipts []*internal.BlockReceipt
+}
+
+func (b *receiptsBackend) find(hash ethcmn.Hash) *internal.BlockReceipt {
+	for _, receipt := range b.receipts {
+		if receipt.Receipt.TxHash == hash {
+			return receipt
+		}
+	}
+	return nil
+}
+
+// GetTransactionReceipt numbers the logs of each receipt from 0, as storage
+// does.
+func (b *receiptsBackend) GetTransactionReceipt(hash ethcmn.Hash) (*ethtyp.Receipt, error) {
+	stored := b.find(hash).Receipt
+	receipt := *stored
+	receipt.Logs = make([]*ethtyp.Log, len(stored.Logs))
+	for i, log := range stored.Logs {
+		cpy := *log
+		cpy.Index = uint(i)
+		receipt.Logs[i] = &cpy
+	}
+	return &receipt, nil
+}
+
+func (b *receiptsBackend) GetBlockByHash(hash ethcmn.Hash, fullTx bool) (*ethrpc.RPCBlock, error) {
+	transactions := make([]interface{}, len(b.receipts))
+	for i, receipt := range b.receipts {
+		transactions[i] = &ethrpc.RPCTransaction{Hash: receipt.Receipt.TxHash, From: receipt.From, To: receipt.To, GasPrice: receipt.GasPrice}
+	}
+	return &ethrpc.RPCBlock{
+		Header:       &ethtyp.Header{Number: big.NewInt(5)},
+		Transactions: transactions,
+	}, nil
+}
+
+func goldenReceipts() []*internal.BlockReceipt {
+	blockHash := ethcmn.Hash{0x11}
+	receipt := func(txHash ethcmn.Hash, index uint, gasUsed, cumulative uint64) *ethtyp.Receipt {
+		return &ethtyp.Receipt{
+			Status:            ethtyp.ReceiptStatusSuccessful,
+			CumulativeGasUsed: cumulative,
+			GasUsed:           gasUsed,
+			TxHash:            txHash,
+			BlockHash:         blockHash,
+			BlockNumber:       big.NewInt(5),
+			TransactionIndex:  index,
+			Logs: []*ethtyp.Log{{
+				Address:     ethcmn.Address{0x31},
+				Topics:      []ethcmn.Hash{{0x41}},
+				Data:        []byte{1, 2},
+				BlockNumber: 5,
+				TxHash:      txHash,
+				TxIndex:     index,
+				BlockHash:   blockHash,
+				Index:       index,
+			}},
+		}
+	}
+	call, create := receipt(ethcmn.Hash{0x21}, 0, 30000, 30000), receipt(ethcmn.Hash{0x22}, 1, 53000, 83000)
+	create.ContractAddress = ethcmn.Address{0x71}
+	to := ethcmn.Address{0x61}
+	return []*internal.BlockReceipt{
+		{Receipt: call, From: ethcmn.Address{0x51}, To: &to, GasPrice: big.NewInt(1e9)},
+		{Receipt: create, From: ethcmn.Address{0x51}, GasPrice: big.NewInt(1e9)},
+	}
+}
+
+// TestReceiptsGolden checks eth_getBlockReceipts and eth_getTransactionReceipt,
+// served from the receipt cache and from storage, against a golden file
+// recorded from formatReceipt, so that any change to the receipt json shows
+// up in review.
+func TestReceiptsGolden(t *testing.T) {
+	want, err := os.ReadFile("testdata/receipts.golden.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipts := goldenReceipts()
+	savedCache, savedBackend, savedWaits := receiptCache, backend, options.Waits
+	t.Cleanup(func() { receiptCache, backend, options.Waits = savedCache, savedBackend, savedWaits })
+	receiptCache = internal.NewReceiptCache(16)
+	receiptCache.Add(5, ethcmn.Hash{0x11}, receipts)
+	backend = &receiptsBackend{receipts: receipts}
+	options.Waits = 1
+	ctx := context.Background()
+
+	byBlock, err := getBlockReceipts(ctx, []interface{}{ethcmn.Hash{0x11}.Hex()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byTx := func() []interface{} {
+		var responses []interface{}
+		for _, receipt := range receipts {
+			response, err := getTransactionReceipt(ctx, []interface{}{receipt.Receipt.TxHash.Hex()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			responses = append(responses, response)
+		}
+		return responses
+	}
+	cached := byTx()
+	receiptCache = nil
+	stored := byTx()
+
+	for method, response := range map[string]interface{}{
+		"eth_getBlockReceipts":                byBlock,
+		"eth_getTransactionReceipt":           cached,
+		"eth_getTransactionReceipt (storage)": stored,
+	} {
+		got, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != strings.TrimSpace(string(want)) {
+			t.Fatalf("%s differs from the golden receipts:\n%s", method, got)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/common/hexutil"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	jsonrpc "github.com/deliveroo/jsonrpc-go"
+)
+
+const maxSimulatedBlocks = 256
+
+var (
+	simulator internal.Simulator
+
+	errNoSimulator = errors.New("simulation not supported by this backend")
+)
+
+type simCallError struct {
+	Code    int            `json:"code"`
+	Message string         `json:"message"`
+	Data    *hexutil.Bytes `json:"data,omitempty"`
+}
+
+type simCallResponse struct {
+	ReturnData hexutil.Bytes  `json:"returnData"`
+	Logs       []*ethtyp.Log  `json:"logs"`
+	GasUsed    hexutil.Uint64 `json:"gasUsed"`
+	Status     hexutil.Uint64 `json:"status"`
+	Error      *simCallError  `json:"error,omitempty"`
+}
+
+type simBlockResponse struct {
+	Number        hexutil.Uint64     `json:"number"`
+	Hash          ethcmn.Hash        `json:"hash"`
+	ParentHash    ethcmn.Hash        `json:"parentHash"`
+	Timestamp     hexutil.Uint64     `json:"timestamp"`
+	GasLimit      hexutil.Uint64     `json:"gasLimit"`
+	GasUsed       hexutil.Uint64     `json:"gasUsed"`
+	Miner         ethcmn.Address     `json:"miner"`
+	BaseFeePerGas hexutil.Uint64     `json:"baseFeePerGas"`
+	Transactions  []ethcmn.Hash      `json:"transactions"`
+	Calls         []*simCallResponse `json:"calls"`
+}
+
+func toOptionalUint64(m map[string]interface{}, key string) (*uint64, error) {
+	v, ok := m[key]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	n, err := ToUint64(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s given %v", key, v)
+	}
+	return &n, nil
+}
+
+func toBlockOverrides(v interface{}) (*internal.BlockOverrides, error) {
+	if v == nil {
+		return nil, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid block overrides given %v", v)
+	}
+	overrides := &internal.BlockOverrides{}
+	var err error
+	if overrides.Number, err = toOptionalUint64(m, "number"); err != nil {
+		return nil, err
+	}
+	if overrides.Time, err = toOptionalUint64(m, "time"); err != nil {
+		return nil, err
+	}
+	if overrides.GasLimit, err = toOptionalUint64(m, "gasLimit"); err != nil {
+		return nil, err
+	}
+	if v, ok := m["feeRecipient"]; ok && v != nil {
+		address, err := ToAddress(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid feeRecipient given %v", v)
+		}
+		overrides.FeeRecipient = &address
+	}
+	return overrides, nil
+}
+
+func toStorage(v interface{}) (map[ethcmn.Hash]ethcmn.Hash, error) {
+	if v == nil {
+		return nil, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid storage given %v", v)
+	}
+	storage := make(map[ethcmn.Hash]ethcmn.Hash, len(m))
+	for key, value := range m {
+		slot, err := ToHash(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid storage value given %v", value)
+		}
+		storage[ethcmn.HexToHash(key)] = slot
+	}
+	return storage, nil
+}
+
+func toStateOverrides(v interface{}) (map[ethcmn.Address]*internal.StateOverride, error) {
+	if v == nil {
+		return nil, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid state overrides given %v", v)
+	}
+	overrides := make(map[ethcmn.Address]*internal.StateOverride, len(m))
+	for address, v := range m {
+		fields, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid override of %s given %v", address, v)
+		}
+		override := &internal.StateOverride{}
+		if v, ok := fields["balance"].(string); ok {
+			balance, err := hexutil.DecodeBig(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid balance given %v", v)
+			}
+			override.Balance = balance
+		}
+		var err error
+		if override.Nonce, err = toOptionalUint64(fields, "nonce"); err != nil {
+			return nil, err
+		}
+		if v, ok := fields["code"].(string); ok {
+			if override.Code, err = hexutil.Decode(v); err != nil {
+				return nil, fmt.Errorf("invalid code given %v", v)
+			}
+		}
+		if override.State, err = toStorage(fields["state"]); err != nil {
+			return nil, err
+		}
+		if override.StateDiff, err = toStorage(fields["stateDiff"]); err != nil {
+			return nil, err
+		}
+		if override.State != nil && override.StateDiff != nil {
+			return nil, fmt.Errorf("both state and stateDiff given for %s", address)
+		}
+		overrides[ethcmn.HexToAddress(address)] = override
+	}
+	return overrides, nil
+}
+
+func toSimBlocks(opts map[string]interface{}) ([]*internal.SimBlock, error) {
+	list, ok := opts["blockStateCalls"].([]interface{})
+	if !ok {
+		return nil, errors.New("blockStateCalls expected")
+	}
+	if len(list) > maxSimulatedBlocks {
+		return nil, fmt.Errorf("too many blocks, at most %d", maxSimulatedBlocks)
+	}
+	blocks := make([]*internal.SimBlock, len(list))
+	for i, v := range list {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid block state calls given %v", v)
+		}
+		block := &internal.SimBlock{}
+		var err error
+		if block.BlockOverrides, err = toBlockOverrides(m["blockOverrides"]); err != nil {
+			return nil, err
+		}
+		if block.StateOverrides, err = toStateOverrides(m["stateOverrides"]); err != nil {
+			return nil, err
+		}
+		if calls, ok := m["calls"].([]interface{}); ok {
+			for _, call := range calls {
+				msg, err := ToCallArgs(call)
+				if err != nil {
+					return nil, fmt.Errorf("invalid call msg given %v", call)
+				}
+				block.Calls = append(block.Calls, msg)
+			}
+		}
+		blocks[i] = block
+	}
+	return blocks, nil
+}
+
+func simCall(call *internal.SimCallResult) *simCallResponse {
+	logs := call.Logs
+	if logs == nil {
+		logs = []*ethtyp.Log{}
+	}
+	response := &simCallResponse{
+		ReturnData: call.ReturnData,
+		Logs:       logs,
+		GasUsed:    hexutil.Uint64(call.GasUsed),
+		Status:     hexutil.Uint64(call.Status),
+	}
+	if call.Status == ethtyp.ReceiptStatusFailed {
+		if len(call.Revert) > 0 {
+			data := hexutil.Bytes(call.Revert)
+			response.Error = &simCallError{Code: 3, Message: "execution reverted", Data: &data}
+		} else {
+			response.Error = &simCallError{Code: -32015, Message: call.Error}
+		}
+	}
+	return response
+}
+
+// simulateV1 runs eth_simulateV1. Transactions are given by hash only and
+// transfers are not traced.
+func simulateV1(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, jsonrpc.InvalidParams("simulation options expected")
+	}
+	opts, ok := params[0].(map[string]interface{})
+	if !ok {
+		return nil, jsonrpc.InvalidParams("invalid simulation options given %v", params[0])
+	}
+	blocks, err := toSimBlocks(opts)
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("%v", err)
+	}
+	if v, _ := opts["traceTransfers"].(bool); v {
+		return nil, jsonrpc.InvalidParams("traceTransfers not supported")
+	}
+	if v, _ := opts["returnFullTransactions"].(bool); v {
+		return nil, jsonrpc.InvalidParams("returnFullTransactions not supported")
+	}
+	validation, _ := opts["validation"].(bool)
+	number := int64(-1)
+	if len(params) > 1 && params[1] != nil {
+		if number, err = toTraceBlock(params[1]); err != nil {
+			return nil, err
+		}
+	}
+	if simulator == nil {
+		return nil, jsonrpc.InternalError(errNoSimulator)
+	}
+
+	results, err := simulator.Simulate(number, blocks, validation)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	response := make([]*simBlockResponse, len(results))
+	for i, result := range results {
+		block := &simBlockResponse{
+			Number:       hexutil.Uint64(result.Number),
+			Hash:         result.Hash,
+			ParentHash:   result.ParentHash,
+			Timestamp:    hexutil.Uint64(result.Time),
+			GasLimit:     hexutil.Uint64(result.GasLimit),
+			GasUsed:      hexutil.Uint64(result.GasUsed),
+			Miner:        result.FeeRecipient,
+			Transactions: result.TxHashes,
+			Calls:        make([]*simCallResponse, len(result.Calls)),
+		}
+		if block.Transactions == nil {
+			block.Transactions = []ethcmn.Hash{}
+		}
+		for j, call := range result.Calls {
+			block.Calls[j] = simCall(call)
+		}
+		response[i] = block
+	}
+	return response, nil
+}
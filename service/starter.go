@@ -17,6 +17,8 @@ import (
 
 	internal "github.com/arcology-network/eth-api-svc/backend"
 	wal "github.com/arcology-network/eth-api-svc/wallet"
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/ethdb/leveldb"
 	jsonrpc "github.com/deliveroo/jsonrpc-go"
 
 	mainCfg "github.com/arcology-network/component-lib/config"
@@ -48,8 +50,48 @@ func init() {
 	flags.String("nname", "node1", "node name in cluster")
 
 	flags.String("local-block", "local-block", "topic of received proposer block ")
+	flags.String("local-txs", "local-txs", "topic of received transactions accepted by the gateway")
 
 	flags.Int("filtertimeout", 5, "filter timeout minutes")
+	flags.Int("filter-max-logs", 10000, "max logs a filter buffers between polls, 0 for unlimited")
+	flags.Int("filter-max-hashes", 10000, "max block or transaction hashes a filter buffers between polls, 0 for unlimited")
+	flags.Int("filter-max-bytes", 16<<20, "max estimated bytes a filter buffers between polls, 0 for unlimited")
+	flags.Int64("filter-memory-budget", 512<<20, "max estimated bytes buffered by all filters, 0 for unlimited")
+	flags.String("filter-overflow", internal.OverflowDropOldest, "what to do with a filter over its limits: drop (oldest changes) or evict")
+
+	flags.String("filter-db", "", "leveldb directory to keep filters across restarts, empty to keep them in memory")
+	flags.Uint64("filter-backfill-horizon", 10000, "max blocks a restored filter may have missed, older filters are dropped")
+	flags.String("filter-redis", "", "host:port of a redis server to share filters between instances, overrides filter-db")
+	flags.Int("filter-redis-ttl", 60, "minutes a shared filter record outlives its last update, so the filters of dead instances expire, 0 to keep them")
+	flags.StringSlice("filter-peers", []string{}, "rpc urls of the instances sharing filters, in node index order")
+
+	flags.Int("filter-quota-filters", 100, "max filters installed per client, 0 for unlimited")
+	flags.Int("filter-quota-addresses", 1000, "max addresses in a log filter, 0 for unlimited")
+	flags.Int("filter-quota-topics", 1000, "max topics in a log filter, 0 for unlimited")
+	flags.Uint64("filter-quota-log-range", 10000, "max blocks scanned by eth_getFilterLogs, 0 for unlimited")
+	flags.Bool("trust-forwarded-for", false, "identify clients by the X-Forwarded-For header of a trusted proxy")
+	flags.StringSlice("api-keys", []string{}, "api keys clients are identified by for their quotas, other keys are ignored")
+	flags.String("admin-key", "", "api key enabling the admin_ methods, empty to disable them")
+
+	flags.Uint64("getlogs-max-range", 10000, "max blocks an eth_getLogs call may scan, 0 for unlimited")
+	flags.Int("getlogs-max-results", 10000, "max logs an eth_getLogs call may return, 0 for unlimited")
+	flags.Int("getlogs-max-bytes", 10<<20, "max estimated bytes an eth_getLogs call may return, 0 for unlimited")
+
+	flags.Uint64("trace-filter-max-range", 100, "max blocks a trace_filter call may re-execute, 0 for unlimited")
+
+	flags.String("log-index", "", "leveldb directory of a local index of recent logs, empty to query storage only")
+	flags.Uint64("log-index-horizon", 100000, "max blocks kept in the local log index, 0 for unlimited")
+
+	flags.String("account-index", "", "leveldb directory of the per-account transaction index, empty to disable ots_search*")
+	flags.String("token-index", "", "leveldb directory of the token transfer index, empty to disable arcology_getToken*")
+	flags.Uint64("token-snapshot-horizon", 100000, "blocks of token balance history kept, 0 to keep all of it")
+	flags.String("contract-index", "", "leveldb directory of the contract deployment index, empty to disable eth_getContractCreation")
+	flags.Uint64("execution-insight-blocks", 0, "blocks of parallel execution metadata kept for arcology_getBlockExecution, 0 to disable")
+	flags.Uint64("receipt-cache-blocks", 128, "recent blocks whose receipts eth_getBlockReceipts serves without a storage query, 0 to disable")
+
+	flags.String("record", "", "record backend calls to this jsonl file")
+	flags.String("replay", "", "serve backend calls from this recorded jsonl file")
+	flags.Bool("replay-strict", false, "fail backend calls missing from the replay file")
 
 	flags.String("ethapicfg", "./eth-api.tom", "eth config file path")
 	flags.String("monacocfg", "./monaco.toml", "main config file path")
@@ -58,11 +100,63 @@ func init() {
 func startCmd(cmd *cobra.Command, args []string) error {
 	//mainConfig.InitCfg(viper.GetString("maincfg"))
 
-	filters := internal.NewFilters(time.Minute * viper.GetDuration("filtertimeout"))
-	rpcStart(filters)
+	internal.SetInstanceHint(byte(viper.GetInt("nidx")))
+	filters := internal.NewFilters(time.Minute*viper.GetDuration("filtertimeout"), internal.FilterLimits{
+		MaxLogs:      viper.GetInt("filter-max-logs"),
+		MaxHashes:    viper.GetInt("filter-max-hashes"),
+		MaxBytes:     viper.GetInt("filter-max-bytes"),
+		MemoryBudget: viper.GetInt64("filter-memory-budget"),
+		Overflow:     viper.GetString("filter-overflow"),
+	})
+	var logIndex *internal.LogIndex
+	if path := viper.GetString("log-index"); path != "" {
+		index, err := openLogIndex(path, viper.GetUint64("log-index-horizon"))
+		if err != nil {
+			return err
+		}
+		logIndex = index
+	}
+	if path := viper.GetString("account-index"); path != "" {
+		db, err := leveldb.New(path, 64, 64, "accounts", false)
+		if err != nil {
+			return err
+		}
+		if accountIndex, err = internal.NewAccountIndex(db); err != nil {
+			return err
+		}
+	}
+	if path := viper.GetString("token-index"); path != "" {
+		db, err := leveldb.New(path, 64, 64, "tokens", false)
+		if err != nil {
+			return err
+		}
+		if tokenIndex, err = internal.NewTokenIndex(db, viper.GetUint64("token-snapshot-horizon")); err != nil {
+			return err
+		}
+	}
+	if path := viper.GetString("contract-index"); path != "" {
+		db, err := leveldb.New(path, 64, 64, "contracts", false)
+		if err != nil {
+			return err
+		}
+		if contractIndex, err = internal.NewContractIndex(db); err != nil {
+			return err
+		}
+	}
+	if n := viper.GetUint64("execution-insight-blocks"); n > 0 {
+		executionInsights = internal.NewExecutionInsights(n)
+	}
+	if n := viper.GetUint64("receipt-cache-blocks"); n > 0 {
+		receiptCache = internal.NewReceiptCache(n)
+	}
+	rpcStart(filters, logIndex)
 	log.InitLog("ethapi.log", viper.GetString("logcfg"), "ethapi", viper.GetString("nname"), viper.GetInt("nidx"))
-	en := NewConfig(filters)
-	en.Start()
+	en := NewConfig(filters, logIndex, accountIndex, tokenIndex, contractIndex, executionInsights, receiptCache)
+	if !options.Dev && viper.GetString("replay") == "" {
+		// The dev chain produces blocks itself and a replayed session reads
+		// the recorded ones, there is no cluster to subscribe to.
+		en.Start()
+	}
 
 	// Wait forever
 	tmCommon.TrapSignal(func() {
@@ -73,7 +167,15 @@ func startCmd(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func rpcStart(filters *internal.Filters) {
+func openLogIndex(path string, horizon uint64) (*internal.LogIndex, error) {
+	db, err := leveldb.New(path, 64, 64, "logindex", false)
+	if err != nil {
+		return nil, err
+	}
+	return internal.NewLogIndex(db, horizon)
+}
+
+func rpcStart(filters *internal.Filters, logIndex *internal.LogIndex) {
 
 	LoadCfg(viper.GetString("ethapicfg"), &options)
 	mainCfg.InitCfg(viper.GetString("monacocfg"))
@@ -106,12 +208,15 @@ func rpcStart(filters *internal.Filters) {
 		"eth_getStorageAt":          getStorageAt,
 		"eth_accounts":              accounts,
 		"eth_estimateGas":           estimateGas,
+		"eth_createAccessList":      createAccessList,
 		"eth_gasPrice":              gasPrice,
 		"eth_sendTransaction":       sendTransaction,
 		"eth_getTransactionReceipt": getTransactionReceipt,
+		"eth_getBlockReceipts":      getBlockReceipts,
 		"eth_getTransactionByHash":  getTransactionByHash,
 		"eth_sendRawTransaction":    sendRawTransaction,
 		"eth_call":                  call,
+		"eth_simulateV1":            simulateV1,
 		"eth_getLogs":               getLogs,
 
 		"eth_getBlockTransactionCountByHash":      getBlockTransactionCountByHash,
@@ -141,13 +246,106 @@ func rpcStart(filters *internal.Filters) {
 		"eth_getFilterLogs":               getFilterLogs,
 	})
 
-	if options.Debug {
+	wallet = wal.NewWallet(new(big.Int).SetUint64(options.ChainID), privateKeys)
+
+	replay, record := viper.GetString("replay"), viper.GetString("record")
+	proofs := true
+	if replay != "" {
+		// a replayed session needs neither a chain nor the cluster
+		replayer, err := internal.NewReplayer(replay, viper.GetBool("replay-strict"))
+		if err != nil {
+			panic(err)
+		}
+		backend = replayer
+	} else if options.Dev {
+		accounts := make([]ethcmn.Address, 0, len(wallet.Accounts()))
+		for _, account := range wallet.Accounts() {
+			accounts = append(accounts, ethcmn.HexToAddress(account))
+		}
+		chain := internal.NewDevChain(new(big.Int).SetUint64(options.ChainID), accounts, time.Duration(options.DevBlockTime)*time.Second, filters)
+		backend = chain
+		if record == "" {
+			txTracer, simulator = chain, chain
+		}
+	} else if options.Debug {
 		backend = internal.NewEthereumAPIMock(new(big.Int).SetUint64(options.ChainID))
 	} else {
 		backend = internal.NewMonaco(options.Zookeeper, filters)
+		if logIndex != nil {
+			backend = internal.NewIndexed(backend, logIndex, filters)
+		}
+		// storage cannot prove its state
+		proofs = false
+	}
+	if proofs {
+		server.Register(jsonrpc.Methods{"eth_getProof": getProof})
 	}
 
-	wallet = wal.NewWallet(new(big.Int).SetUint64(options.ChainID), privateKeys)
+	if options.Debug {
+		// the test control methods act on the dev chain, or on the state
+		// the mock keeps
+		if control, ok := backend.(internal.DevControl); ok {
+			devControl = control
+			server.Register(devMethods())
+		}
+	}
+	if record != "" && replay == "" {
+		recorder, err := internal.NewRecorder(backend, record)
+		if err != nil {
+			panic(err)
+		}
+		backend = recorder
+	}
+	if txTracer == nil {
+		// Monaco's executor can neither attach a tracer nor keep state across
+		// calls, ExecTxs returns the return data of each call and nothing
+		// else. debug_trace*, trace_* and eth_simulateV1 run the transactions
+		// here instead, on the state the backend serves. Recorded and replayed sessions
+		// trace the same way so that the state they read is in the session.
+		reexecutor := internal.NewReexecutor(backend, new(big.Int).SetUint64(options.ChainID))
+		txTracer, simulator = reexecutor, reexecutor
+	}
+
+	var filterState internal.FilterState
+	if addr := viper.GetString("filter-redis"); addr != "" {
+		state, err := internal.NewRedisFilterState(addr, time.Minute*viper.GetDuration("filter-redis-ttl"))
+		if err != nil {
+			panic(err)
+		}
+		filterState = state
+	} else if path := viper.GetString("filter-db"); path != "" {
+		db, err := leveldb.New(path, 16, 16, "filters", false)
+		if err != nil {
+			panic(err)
+		}
+		filterState = internal.NewDatabaseFilterState(db)
+	}
+	if filterState != nil {
+		if err := filters.Restore(filterState, backend, viper.GetUint64("filter-backfill-horizon")); err != nil {
+			panic(err)
+		}
+	}
+	filters.SetPeers(viper.GetStringSlice("filter-peers"))
+	setPeerHosts(viper.GetStringSlice("filter-peers"))
+	for _, key := range viper.GetStringSlice("api-keys") {
+		apiKeys[key] = true
+	}
+	filters.SetQuotas(internal.FilterQuotas{
+		MaxFilters:   viper.GetInt("filter-quota-filters"),
+		MaxAddresses: viper.GetInt("filter-quota-addresses"),
+		MaxTopics:    viper.GetInt("filter-quota-topics"),
+		MaxLogRange:  viper.GetUint64("filter-quota-log-range"),
+	})
+	apiFilters = filters
+	if viper.GetString("admin-key") != "" {
+		server.Register(adminMethods())
+	}
+	server.Register(otsMethods())
+	server.Register(debugMethods())
+	server.Register(traceMethods())
+	server.Register(tokenMethods())
+	server.Register(contractMethods())
+	server.Register(insightMethods())
 
 	c := cors.AllowAll()
 
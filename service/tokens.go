@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/common/hexutil"
+	jsonrpc "github.com/deliveroo/jsonrpc-go"
+)
+
+var (
+	tokenIndex *internal.TokenIndex
+
+	errNoTokenIndex = errors.New("token index not configured")
+)
+
+// tokenMethods serve the token transfer index.
+func tokenMethods() jsonrpc.Methods {
+	return jsonrpc.Methods{
+		"arcology_getTokenTransfers": arcologyGetTokenTransfers,
+		"arcology_getTokenHoldings":  arcologyGetTokenHoldings,
+	}
+}
+
+type tokenTransferResponse struct {
+	Standard        string         `json:"standard"`
+	Token           ethcmn.Address `json:"token"`
+	From            ethcmn.Address `json:"from"`
+	To              ethcmn.Address `json:"to"`
+	TokenID         *hexutil.Big   `json:"tokenId,omitempty"`
+	Value           *hexutil.Big   `json:"value"`
+	BlockNumber     hexutil.Uint64 `json:"blockNumber"`
+	TransactionHash ethcmn.Hash    `json:"transactionHash"`
+	LogIndex        hexutil.Uint   `json:"logIndex"`
+}
+
+// tokenHoldingResponse is a holding, or an error in place of a balance the
+// index cannot know.
+type tokenHoldingResponse struct {
+	Standard    string         `json:"standard"`
+	Token       ethcmn.Address `json:"token"`
+	TokenID     *hexutil.Big   `json:"tokenId,omitempty"`
+	Balance     *hexutil.Big   `json:"balance"`
+	Error       string         `json:"error,omitempty"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+}
+
+// toTokenQuery parses the holder and the page options of a token query.
+func toTokenQuery(params []interface{}) (ethcmn.Address, map[string]interface{}, []byte, int, error) {
+	if len(params) < 1 {
+		return ethcmn.Address{}, nil, nil, 0, jsonrpc.InvalidParams("holder expected")
+	}
+	holder, err := ToAddress(params[0])
+	if err != nil {
+		return ethcmn.Address{}, nil, nil, 0, jsonrpc.InvalidParams("invalid address given %v", params[0])
+	}
+	opts := map[string]interface{}{}
+	if len(params) > 1 && params[1] != nil {
+		if opts, _ = params[1].(map[string]interface{}); opts == nil {
+			return ethcmn.Address{}, nil, nil, 0, jsonrpc.InvalidParams("invalid page options given %v", params[1])
+		}
+	}
+	cursor, limit, err := toPage(opts)
+	if err != nil {
+		return ethcmn.Address{}, nil, nil, 0, err
+	}
+	return holder, opts, cursor, limit, nil
+}
+
+func arcologyGetTokenTransfers(ctx context.Context, params []interface{}) (interface{}, error) {
+	holder, opts, cursor, limit, err := toTokenQuery(params)
+	if err != nil {
+		return nil, err
+	}
+	if tokenIndex == nil {
+		return nil, jsonrpc.InternalError(errNoTokenIndex)
+	}
+	var token *ethcmn.Address
+	if v, ok := opts["token"]; ok && v != nil {
+		address, err := ToAddress(v)
+		if err != nil {
+			return nil, jsonrpc.InvalidParams("invalid token given %v", v)
+		}
+		token = &address
+	}
+
+	transfers, next, err := tokenIndex.Transfers(holder, token, cursor, limit)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	items := make([]*tokenTransferResponse, len(transfers))
+	for i, transfer := range transfers {
+		items[i] = &tokenTransferResponse{
+			Standard:        transfer.Standard,
+			Token:           transfer.Token,
+			From:            transfer.From,
+			To:              transfer.To,
+			TokenID:         (*hexutil.Big)(transfer.TokenID),
+			Value:           (*hexutil.Big)(transfer.Value),
+			BlockNumber:     hexutil.Uint64(transfer.Block),
+			TransactionHash: transfer.TxHash,
+			LogIndex:        hexutil.Uint(transfer.LogIndex),
+		}
+	}
+	return &itemsPage{Items: items, Cursor: nextCursor(next)}, nil
+}
+
+func arcologyGetTokenHoldings(ctx context.Context, params []interface{}) (interface{}, error) {
+	holder, opts, cursor, limit, err := toTokenQuery(params)
+	if err != nil {
+		return nil, err
+	}
+	if tokenIndex == nil {
+		return nil, jsonrpc.InternalError(errNoTokenIndex)
+	}
+	number := uint64(0)
+	if v, ok := opts["blockNumber"]; ok && v != nil {
+		if number, err = ToUint64(v); err != nil {
+			return nil, jsonrpc.InvalidParams("invalid block number given %v", v)
+		}
+	}
+
+	holdings, next, err := tokenIndex.Holdings(holder, number, cursor, limit)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	items := make([]*tokenHoldingResponse, len(holdings))
+	for i, holding := range holdings {
+		items[i] = &tokenHoldingResponse{
+			Standard:    holding.Standard,
+			Token:       holding.Token,
+			TokenID:     (*hexutil.Big)(holding.TokenID),
+			BlockNumber: hexutil.Uint64(holding.Block),
+		}
+		// a balance goes negative when the index missed earlier transfers
+		if holding.Balance.Sign() < 0 {
+			items[i].Error = "balance unknown, transfers before the index started are missing"
+		} else {
+			items[i].Balance = (*hexutil.Big)(holding.Balance)
+		}
+	}
+	return &itemsPage{Items: items, Cursor: nextCursor(next)}, nil
+}
@@ -0,0 +1,451 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/common/hexutil"
+	jsonrpc "github.com/deliveroo/jsonrpc-go"
+	"github.com/spf13/viper"
+)
+
+// traceMethods serve the OpenEthereum/Erigon trace_ namespace. The flat
+// traces are built from the call tracer, so the top call's gas includes the
+// intrinsic gas and vmTrace and stateDiff are not available.
+//
+// They trace through txTracer like the debug_trace* methods. Monaco's
+// executor only answers ExecTxs with the return data of each call, so on
+// Monaco the traces come from the Reexecutor and share its limits: a block
+// replayed in order shows what Monaco executed only if none of its
+// transactions conflicted.
+func traceMethods() jsonrpc.Methods {
+	return jsonrpc.Methods{
+		"trace_block":             traceBlockTraces,
+		"trace_transaction":       traceTransaction,
+		"trace_filter":            traceFilter,
+		"trace_call":              traceCall,
+		"trace_replayTransaction": traceReplayTransaction,
+	}
+}
+
+// parityTrace is a call in a flattened Parity trace.
+type parityTrace struct {
+	Action              map[string]interface{} `json:"action"`
+	BlockHash           *ethcmn.Hash           `json:"blockHash,omitempty"`
+	BlockNumber         *uint64                `json:"blockNumber,omitempty"`
+	Error               string                 `json:"error,omitempty"`
+	Result              map[string]interface{} `json:"result"`
+	Subtraces           int                    `json:"subtraces"`
+	TraceAddress        []int                  `json:"traceAddress"`
+	TransactionHash     *ethcmn.Hash           `json:"transactionHash,omitempty"`
+	TransactionPosition *uint64                `json:"transactionPosition,omitempty"`
+	Type                string                 `json:"type"`
+}
+
+// parityReplay is the reply of trace_call and trace_replayTransaction.
+type parityReplay struct {
+	Output          hexutil.Bytes  `json:"output"`
+	StateDiff       interface{}    `json:"stateDiff"`
+	Trace           []*parityTrace `json:"trace"`
+	VMTrace         interface{}    `json:"vmTrace"`
+	TransactionHash *ethcmn.Hash   `json:"transactionHash,omitempty"`
+}
+
+// parityError renders a call tracer error the way OpenEthereum does.
+func parityError(err string) string {
+	switch err {
+	case "execution reverted":
+		return "Reverted"
+	case "out of gas":
+		return "Out of gas"
+	}
+	return err
+}
+
+// flattenCalls lists a call and its subcalls depth first, each with its
+// position in the tree.
+func flattenCalls(frame *internal.CallFrame, address []int, traces []*parityTrace) []*parityTrace {
+	value := frame.Value
+	if value == nil {
+		value = (*hexutil.Big)(new(big.Int))
+	}
+	trace := &parityTrace{
+		Error:        parityError(frame.Error),
+		Subtraces:    len(frame.Calls),
+		TraceAddress: append([]int{}, address...),
+	}
+	switch frame.Type {
+	case "CREATE", "CREATE2":
+		trace.Type = "create"
+		trace.Action = map[string]interface{}{
+			"from":  frame.From,
+			"gas":   frame.Gas,
+			"init":  frame.Input,
+			"value": value,
+		}
+		if frame.Error == "" {
+			trace.Result = map[string]interface{}{
+				"address": frame.To,
+				"code":    frame.Output,
+				"gasUsed": frame.GasUsed,
+			}
+		}
+	case "SELFDESTRUCT":
+		trace.Type = "suicide"
+		trace.Action = map[string]interface{}{
+			"address":       frame.From,
+			"refundAddress": frame.To,
+			"balance":       value,
+		}
+	default:
+		if frame.Type == "DELEGATECALL" || frame.Type == "STATICCALL" {
+			value = (*hexutil.Big)(new(big.Int))
+		}
+		output := frame.Output
+		if output == nil {
+			output = hexutil.Bytes{}
+		}
+		trace.Type = "call"
+		trace.Action = map[string]interface{}{
+			"callType": strings.ToLower(frame.Type),
+			"from":     frame.From,
+			"to":       frame.To,
+			"gas":      frame.Gas,
+			"input":    frame.Input,
+			"value":    value,
+		}
+		if frame.Error == "" {
+			trace.Result = map[string]interface{}{
+				"gasUsed": frame.GasUsed,
+				"output":  output,
+			}
+		}
+	}
+	traces = append(traces, trace)
+	for i, call := range frame.Calls {
+		traces = flattenCalls(call, append(address, i), traces)
+	}
+	return traces
+}
+
+func decodeCallFrame(data json.RawMessage) (*internal.CallFrame, error) {
+	var root internal.CallFrame
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	return &root, nil
+}
+
+// blockParityTraces traces the transactions of a block.
+func blockParityTraces(number int64) ([]*parityTrace, error) {
+	if txTracer == nil {
+		return nil, jsonrpc.InternalError(errNoTracer)
+	}
+	block, err := backend.GetBlockByNumber(number, false)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	if block == nil {
+		return nil, jsonrpc.InternalError(fmt.Errorf("block %d not found", number))
+	}
+	blockNumber, blockHash := block.Header.Number.Uint64(), block.Header.Hash()
+	txs, err := txTracer.TraceBlock(int64(blockNumber), &internal.TraceConfig{Tracer: "callTracer"})
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	traces := []*parityTrace{}
+	for i, tx := range txs {
+		root, err := decodeCallFrame(tx.Result)
+		if err != nil {
+			return nil, jsonrpc.InternalError(err)
+		}
+		position, txHash := uint64(i), tx.TxHash
+		for _, trace := range flattenCalls(root, nil, nil) {
+			trace.BlockHash, trace.BlockNumber = &blockHash, &blockNumber
+			trace.TransactionHash, trace.TransactionPosition = &txHash, &position
+			traces = append(traces, trace)
+		}
+	}
+	return traces, nil
+}
+
+func traceBlockTraces(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, jsonrpc.InvalidParams("block number expected")
+	}
+	number, err := ToBlockNumber(params[0])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid block number given %v", params[0])
+	}
+	return blockParityTraces(number)
+}
+
+// txCallTrace traces a transaction with the call tracer, along with where it
+// is in the chain.
+func txCallTrace(v interface{}) (*internal.CallFrame, *parityTrace, error) {
+	hash, err := ToHash(v)
+	if err != nil {
+		return nil, nil, jsonrpc.InvalidParams("invalid hash given %v", v)
+	}
+	if txTracer == nil {
+		return nil, nil, jsonrpc.InternalError(errNoTracer)
+	}
+	tx, err := backend.GetTransactionByHash(hash)
+	if err != nil {
+		return nil, nil, jsonrpc.InternalError(err)
+	}
+	if tx == nil || tx.BlockNumber == nil || tx.TransactionIndex == nil {
+		return nil, nil, jsonrpc.InternalError(fmt.Errorf("transaction %x not found", hash))
+	}
+	block, err := backend.GetBlockByNumber(tx.BlockNumber.Int64(), false)
+	if err != nil {
+		return nil, nil, jsonrpc.InternalError(err)
+	}
+	if block == nil {
+		return nil, nil, jsonrpc.InternalError(fmt.Errorf("block %d not found", tx.BlockNumber))
+	}
+	data, err := txTracer.TraceTransaction(hash, &internal.TraceConfig{Tracer: "callTracer"})
+	if err != nil {
+		return nil, nil, jsonrpc.InternalError(err)
+	}
+	root, err := decodeCallFrame(data)
+	if err != nil {
+		return nil, nil, jsonrpc.InternalError(err)
+	}
+	blockNumber, blockHash, position := tx.BlockNumber.Uint64(), block.Header.Hash(), *tx.TransactionIndex
+	return root, &parityTrace{
+		BlockHash:           &blockHash,
+		BlockNumber:         &blockNumber,
+		TransactionHash:     &hash,
+		TransactionPosition: &position,
+	}, nil
+}
+
+func traceTransaction(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, jsonrpc.InvalidParams("transaction hash expected")
+	}
+	root, location, err := txCallTrace(params[0])
+	if err != nil {
+		return nil, err
+	}
+	traces := flattenCalls(root, nil, nil)
+	for _, trace := range traces {
+		trace.BlockHash, trace.BlockNumber = location.BlockHash, location.BlockNumber
+		trace.TransactionHash, trace.TransactionPosition = location.TransactionHash, location.TransactionPosition
+	}
+	return traces, nil
+}
+
+// toTraceTypes checks the requested trace types, only the call trace is
+// available.
+func toTraceTypes(v interface{}) error {
+	types, ok := v.([]interface{})
+	if !ok {
+		return jsonrpc.InvalidParams("invalid trace types given %v", v)
+	}
+	for _, typ := range types {
+		if typ != "trace" {
+			return jsonrpc.InvalidParams("trace type %v not supported", typ)
+		}
+	}
+	return nil
+}
+
+func traceCall(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 2 {
+		return nil, jsonrpc.InvalidParams("call msg and trace types expected")
+	}
+	msg, err := ToCallArgs(params[0])
+	if err != nil {
+		return nil, jsonrpc.InvalidParams("invalid call msg given %v", params[0])
+	}
+	if msg.Gas == 0 {
+		msg.Gas = math.MaxUint32
+	}
+	if msg.GasPrice == nil {
+		msg.GasPrice = big.NewInt(0xff)
+	}
+	if err := toTraceTypes(params[1]); err != nil {
+		return nil, err
+	}
+	number := int64(-1)
+	if len(params) > 2 && params[2] != nil {
+		if number, err = toTraceBlock(params[2]); err != nil {
+			return nil, err
+		}
+	}
+	if txTracer == nil {
+		return nil, jsonrpc.InternalError(errNoTracer)
+	}
+	data, err := txTracer.TraceCall(msg, number, &internal.TraceConfig{Tracer: "callTracer"})
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	root, err := decodeCallFrame(data)
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	return &parityReplay{Output: root.Output, Trace: flattenCalls(root, nil, nil)}, nil
+}
+
+func traceReplayTransaction(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 2 {
+		return nil, jsonrpc.InvalidParams("transaction hash and trace types expected")
+	}
+	if err := toTraceTypes(params[1]); err != nil {
+		return nil, err
+	}
+	root, location, err := txCallTrace(params[0])
+	if err != nil {
+		return nil, err
+	}
+	return &parityReplay{
+		Output:          root.Output,
+		Trace:           flattenCalls(root, nil, nil),
+		TransactionHash: location.TransactionHash,
+	}, nil
+}
+
+// traceFilterQuery is the filter of trace_filter, empty address lists match
+// any address.
+type traceFilterQuery struct {
+	from, to      uint64
+	fromAddresses map[ethcmn.Address]bool
+	toAddresses   map[ethcmn.Address]bool
+	after, count  uint64
+}
+
+func (q *traceFilterQuery) matches(trace *parityTrace) bool {
+	var from, to interface{}
+	switch trace.Type {
+	case "suicide":
+		from, to = trace.Action["address"], trace.Action["refundAddress"]
+	case "create":
+		from = trace.Action["from"]
+		if trace.Result != nil {
+			to = trace.Result["address"]
+		}
+	default:
+		from, to = trace.Action["from"], trace.Action["to"]
+	}
+	if len(q.fromAddresses) > 0 {
+		if address, ok := from.(ethcmn.Address); !ok || !q.fromAddresses[address] {
+			return false
+		}
+	}
+	if len(q.toAddresses) > 0 {
+		if address, ok := to.(ethcmn.Address); !ok || !q.toAddresses[address] {
+			return false
+		}
+	}
+	return true
+}
+
+func toAddressSet(v interface{}) (map[ethcmn.Address]bool, error) {
+	set := make(map[ethcmn.Address]bool)
+	if v == nil {
+		return set, nil
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, jsonrpc.InvalidParams("invalid address list given %v", v)
+	}
+	for _, item := range list {
+		address, err := ToAddress(item)
+		if err != nil {
+			return nil, jsonrpc.InvalidParams("invalid address given %v", item)
+		}
+		set[address] = true
+	}
+	return set, nil
+}
+
+func toTraceFilter(v interface{}) (*traceFilterQuery, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, jsonrpc.InvalidParams("invalid trace filter given %v", v)
+	}
+	head, err := backend.BlockNumber()
+	if err != nil {
+		return nil, jsonrpc.InternalError(err)
+	}
+	q := &traceFilterQuery{from: head, to: head, count: math.MaxUint64}
+	for key, bound := range map[string]*uint64{"fromBlock": &q.from, "toBlock": &q.to} {
+		if m[key] == nil {
+			continue
+		}
+		number, err := ToBlockNumber(m[key])
+		if err != nil {
+			return nil, jsonrpc.InvalidParams("invalid %s given %v", key, m[key])
+		}
+		if number >= 0 {
+			*bound = uint64(number)
+		}
+	}
+	if q.fromAddresses, err = toAddressSet(m["fromAddress"]); err != nil {
+		return nil, err
+	}
+	if q.toAddresses, err = toAddressSet(m["toAddress"]); err != nil {
+		return nil, err
+	}
+	for key, n := range map[string]*uint64{"after": &q.after, "count": &q.count} {
+		if m[key] == nil {
+			continue
+		}
+		if *n, err = ToUint64(m[key]); err != nil {
+			return nil, jsonrpc.InvalidParams("invalid %s given %v", key, m[key])
+		}
+	}
+	return q, nil
+}
+
+func traceFilter(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, jsonrpc.InvalidParams("trace filter expected")
+	}
+	q, err := toTraceFilter(params[0])
+	if err != nil {
+		return nil, err
+	}
+	if q.from > q.to {
+		return nil, jsonrpc.InvalidParams("invalid block range [0x%x, 0x%x]", q.from, q.to)
+	}
+	if maxRange := viper.GetUint64("trace-filter-max-range"); maxRange > 0 && q.to-q.from+1 > maxRange {
+		msg := fmt.Sprintf("block range exceeds %d blocks", maxRange)
+		return nil, limitError(msg, q.from, q.from+maxRange-1)
+	}
+
+	traces := []*parityTrace{}
+	skipped := uint64(0)
+	for number := q.from; number <= q.to && uint64(len(traces)) < q.count; number++ {
+		// every block is re-executed, stop once the caller has gone
+		if err := ctx.Err(); err != nil {
+			return nil, jsonrpc.InternalError(err)
+		}
+		block, err := blockParityTraces(int64(number))
+		if err != nil {
+			return nil, err
+		}
+		for _, trace := range block {
+			if !q.matches(trace) {
+				continue
+			}
+			if skipped < q.after {
+				skipped++
+				continue
+			}
+			if uint64(len(traces)) == q.count {
+				break
+			}
+			traces = append(traces, trace)
+		}
+	}
+	return traces, nil
+}
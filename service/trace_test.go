@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+)
+
+func TestFlattenCalls(t *testing.T) {
+	a, b, c := ethcmn.Address{1}, ethcmn.Address{2}, ethcmn.Address{3}
+	root := &internal.CallFrame{Type: "CALL", From: a, To: b, Calls: []*internal.CallFrame{
+		{Type: "STATICCALL", From: b, To: c},
+		{Type: "CREATE", From: b, To: c, Error: "execution reverted", Calls: []*internal.CallFrame{
+			{Type: "SELFDESTRUCT", From: c, To: a},
+		}},
+	}}
+	traces := flattenCalls(root, nil, nil)
+	if len(traces) != 4 {
+		t.Fatalf("expected 4 traces, got %d", len(traces))
+	}
+	expected := []struct {
+		typ     string
+		address []int
+		sub     int
+	}{{"call", []int{}, 2}, {"call", []int{0}, 0}, {"create", []int{1}, 1}, {"suicide", []int{1, 0}, 0}}
+	for i, want := range expected {
+		trace := traces[i]
+		if trace.Type != want.typ || trace.Subtraces != want.sub || len(trace.TraceAddress) != len(want.address) {
+			t.Fatalf("trace %d: expected %v, got %+v", i, want, trace)
+		}
+		for j := range want.address {
+			if trace.TraceAddress[j] != want.address[j] {
+				t.Fatalf("trace %d: expected address %v, got %v", i, want.address, trace.TraceAddress)
+			}
+		}
+	}
+	if traces[1].Action["callType"] != "staticcall" || traces[2].Error != "Reverted" || traces[2].Result != nil {
+		t.Fatalf("unexpected traces %+v %+v", traces[1], traces[2])
+	}
+
+	q := &traceFilterQuery{fromAddresses: map[ethcmn.Address]bool{b: true}, toAddresses: map[ethcmn.Address]bool{}}
+	if q.matches(traces[0]) || !q.matches(traces[1]) || !q.matches(traces[2]) {
+		t.Fatal("expected only the calls from b to match")
+	}
+}
+
+func TestTraceFilterCancel(t *testing.T) {
+	chain := internal.NewDevChain(big.NewInt(1), nil, 0, nil)
+	sender, to := ethcmn.Address{1}, ethcmn.Address{2}
+	chain.SetBalance(sender, big.NewInt(1e18))
+	chain.ImpersonateAccount(sender)
+	if _, err := chain.SendImpersonatedTransaction(eth.CallMsg{From: sender, To: &to}); err != nil {
+		t.Fatal(err)
+	}
+	oldBackend, oldTracer := backend, txTracer
+	t.Cleanup(func() { backend, txTracer = oldBackend, oldTracer })
+	backend, txTracer = chain, chain
+
+	filter := []interface{}{map[string]interface{}{"fromBlock": "0x1", "toBlock": "0x1"}}
+	traces, err := traceFilter(context.Background(), filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(traces.([]*parityTrace)) != 1 {
+		t.Fatalf("expected one trace, got %v", traces)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := traceFilter(ctx, filter); err == nil {
+		t.Fatal("expected a cancelled trace_filter to stop")
+	}
+}
@@ -61,6 +61,52 @@ func ToBlockNumber(v interface{}) (int64, error) {
 	}
 }
 
+// ToUint64 accepts a hex quantity string or a plain JSON number.
+func ToUint64(v interface{}) (uint64, error) {
+	switch n := v.(type) {
+	case float64:
+		if n < 0 {
+			return 0, errors.New("negative number given")
+		}
+		return uint64(n), nil
+	case string:
+		if len(n) > 2 && n[:2] == "0x" {
+			return strconv.ParseUint(n[2:], 16, 64)
+		}
+		return strconv.ParseUint(n, 10, 64)
+	default:
+		return 0, errors.New("unexpected data type given")
+	}
+}
+
+// ToBigInt accepts a hex quantity string or a plain JSON number.
+func ToBigInt(v interface{}) (*big.Int, error) {
+	switch n := v.(type) {
+	case float64:
+		if n < 0 {
+			return nil, errors.New("negative number given")
+		}
+		return new(big.Int).SetUint64(uint64(n)), nil
+	case string:
+		var value *big.Int
+		var ok bool
+		if len(n) > 2 && n[:2] == "0x" {
+			value, ok = new(big.Int).SetString(n[2:], 16)
+		} else {
+			value, ok = new(big.Int).SetString(n, 10)
+		}
+		if !ok {
+			return nil, errors.New("invalid characters included")
+		}
+		if value.Sign() < 0 {
+			return nil, errors.New("negative number given")
+		}
+		return value, nil
+	default:
+		return nil, errors.New("unexpected data type given")
+	}
+}
+
 func ToAddress(v interface{}) (ethcmn.Address, error) {
 	if str, ok := v.(string); !ok {
 		return ethcmn.Address{}, errors.New("unexpected data type given")
@@ -173,6 +219,28 @@ func ToCallMsg(v interface{}, needFrom bool) (eth.CallMsg, error) {
 	}
 }
 
+// ToCallArgs reads the call of a simulation or a traced call the way geth
+// does, from being optional and input an alias of data. eth_call and
+// eth_estimateGas keep parsing with ToCallMsg.
+func ToCallArgs(v interface{}) (eth.CallMsg, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return eth.CallMsg{}, errors.New("unexpected data type given")
+	}
+	if _, ok := m["data"]; !ok {
+		if input, ok := m["input"]; ok {
+			args := make(map[string]interface{}, len(m))
+			for key, value := range m {
+				args[key] = value
+			}
+			args["data"] = input
+			m = args
+		}
+	}
+	_, needFrom := m["from"]
+	return ToCallMsg(m, needFrom)
+}
+
 type SendTxArgs struct {
 	To       *ethcmn.Address
 	Gas      uint64
@@ -218,31 +286,74 @@ func ToID(v interface{}) (internal.ID, error) {
 		return internal.ID(id), nil
 	}
 }
+
+// ToFilter parses a log filter object. Block bounds are hex quantities or
+// tags, plain decimal strings and JSON numbers are still accepted for older
+// clients. Blocks are final as soon as they are committed, so safe and
+// finalized are the latest block.
 func ToFilter(v interface{}) (eth.FilterQuery, error) {
-	if m, ok := v.(map[string]interface{}); !ok {
+	m, ok := v.(map[string]interface{})
+	if !ok {
 		return eth.FilterQuery{}, errors.New("unexpected data type given")
-	} else {
-		if v, ok := m["fromBlock"]; ok {
-			from, err := strconv.ParseInt(v.(string), 10, 64)
-			if err != nil {
-				return eth.FilterQuery{}, err
-			}
-			m["fromBlock"] = fmt.Sprintf("0x%x", from)
+	}
+	crit := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		crit[k] = v
+	}
+	for _, key := range []string{"fromBlock", "toBlock"} {
+		v, ok := crit[key]
+		if !ok {
+			continue
 		}
-		if v, ok := m["toBlock"]; ok {
-			to, err := strconv.ParseInt(v.(string), 10, 64)
-			if err != nil {
-				return eth.FilterQuery{}, err
-			}
-			m["toBlock"] = fmt.Sprintf("0x%x", to)
+		bound, err := toBlockTag(v)
+		if err != nil {
+			return eth.FilterQuery{}, fmt.Errorf("invalid %s: %v", key, err)
 		}
-		bytes, _ := json.Marshal(m)
-		var filter ethflt.FilterCriteria
-		if err := filter.UnmarshalJSON(bytes); err != nil {
-			return eth.FilterQuery{}, err
+		if bound == "" {
+			delete(crit, key)
+		} else {
+			crit[key] = bound
 		}
+	}
 
-		return eth.FilterQuery(filter), nil
+	bytes, err := json.Marshal(crit)
+	if err != nil {
+		return eth.FilterQuery{}, err
+	}
+	var filter ethflt.FilterCriteria
+	if err := filter.UnmarshalJSON(bytes); err != nil {
+		return eth.FilterQuery{}, err
+	}
+	return eth.FilterQuery(filter), nil
+}
+
+// toBlockTag normalizes a block bound of a filter, an empty tag is no bound.
+func toBlockTag(v interface{}) (string, error) {
+	switch bound := v.(type) {
+	case nil:
+		return "", nil
+	case float64:
+		if bound < 0 {
+			return "", errors.New("negative block number")
+		}
+		return NumberToHex(uint64(bound)), nil
+	case string:
+		switch bound {
+		case "latest", "earliest", "pending":
+			return bound, nil
+		case "safe", "finalized":
+			return "latest", nil
+		}
+		if len(bound) > 2 && bound[:2] == "0x" {
+			return bound, nil
+		}
+		number, err := strconv.ParseUint(bound, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("unknown block %q", bound)
+		}
+		return NumberToHex(number), nil
+	default:
+		return "", errors.New("unexpected data type given")
 	}
 }
 
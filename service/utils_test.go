@@ -15,3 +15,70 @@ func TestNumberToHex(t *testing.T) {
 	t.Log(NumberToHex(new(big.Int).SetUint64(100)))
 	t.Log(NumberToHex(uint64(100)))
 }
+
+func TestToBigInt(t *testing.T) {
+	for _, v := range []interface{}{"0x10", "16", float64(16)} {
+		if n, err := ToBigInt(v); err != nil || n.Int64() != 16 {
+			t.Fatalf("%v: expected 16, got %v %v", v, n, err)
+		}
+	}
+	for _, v := range []interface{}{"-16", float64(-16), "0xzz", true} {
+		if _, err := ToBigInt(v); err == nil {
+			t.Fatalf("%v: expected an error", v)
+		}
+	}
+}
+
+func TestToFilter(t *testing.T) {
+	filter, err := ToFilter(map[string]interface{}{
+		"fromBlock": "0x10",
+		"toBlock":   "latest",
+		"address":   "0x0000000000000000000000000000000000000001",
+		"topics":    []interface{}{nil, []interface{}{"0x0000000000000000000000000000000000000000000000000000000000000002", "0x0000000000000000000000000000000000000000000000000000000000000003"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filter.FromBlock.Int64() != 16 || filter.ToBlock.Sign() >= 0 {
+		t.Fatalf("wrong range %v to %v", filter.FromBlock, filter.ToBlock)
+	}
+	if len(filter.Addresses) != 1 || len(filter.Topics) != 2 || len(filter.Topics[0]) != 0 || len(filter.Topics[1]) != 2 {
+		t.Fatalf("wrong criteria %+v", filter)
+	}
+
+	filter, err = ToFilter(map[string]interface{}{"fromBlock": "16", "toBlock": "finalized"})
+	if err != nil || filter.FromBlock.Int64() != 16 || filter.ToBlock.Sign() >= 0 {
+		t.Fatalf("legacy decimal or finalized not accepted: %v", err)
+	}
+	if _, err := ToFilter(map[string]interface{}{
+		"fromBlock": "0x1",
+		"blockHash": "0x0000000000000000000000000000000000000000000000000000000000000001",
+	}); err == nil {
+		t.Fatal("expected blockHash to exclude a range")
+	}
+}
+
+func TestToCallArgs(t *testing.T) {
+	call := map[string]interface{}{
+		"from":  "0x0000000000000000000000000000000000000001",
+		"to":    "0x0000000000000000000000000000000000000002",
+		"input": "0x0102",
+	}
+	msg, err := ToCallArgs(call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.From[19] != 1 || msg.To == nil || msg.To[19] != 2 || len(msg.Data) != 2 {
+		t.Fatalf("unexpected call %+v", msg)
+	}
+
+	// eth_call and eth_estimateGas parse as they always did
+	if msg, err = ToCallMsg(call, false); err != nil || msg.From[19] != 0 || len(msg.Data) != 0 {
+		t.Fatalf("unexpected call msg %+v %v", msg, err)
+	}
+
+	delete(call, "from")
+	if msg, err = ToCallArgs(call); err != nil || msg.From[19] != 0 {
+		t.Fatalf("from should be optional: %+v %v", msg, err)
+	}
+}
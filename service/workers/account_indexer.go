@@ -0,0 +1,94 @@
+package workers
+
+import (
+	"math/big"
+
+	ethCommon "github.com/arcology-network/3rd-party/eth/common"
+	ethTypes "github.com/arcology-network/3rd-party/eth/types"
+	"github.com/arcology-network/common-lib/types"
+	"github.com/arcology-network/component-lib/actor"
+	"github.com/arcology-network/component-lib/log"
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	ethcmn "github.com/arcology-network/evm/common"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	"go.uber.org/zap"
+)
+
+// AccountIndexer records the transactions of each completed block under
+// their sender and recipient.
+type AccountIndexer struct {
+	actor.WorkerThread
+	index  *internal.AccountIndex
+	signer ethtyp.Signer
+}
+
+//return a Subscriber struct
+func NewAccountIndexer(concurrency int, groupid string, index *internal.AccountIndex, chainID uint64) *AccountIndexer {
+	ai := AccountIndexer{}
+	ai.Set(concurrency, groupid)
+	ai.index = index
+	ai.signer = ethtyp.LatestSignerForChainID(new(big.Int).SetUint64(chainID))
+	return &ai
+}
+
+func (*AccountIndexer) OnStart() {}
+func (*AccountIndexer) Stop()    {}
+
+func (ai *AccountIndexer) OnMessageArrived(msgs []*actor.Message) error {
+	result := ""
+	var receipts *[]*ethTypes.Receipt
+	var block *types.MonacoBlock
+
+	for _, v := range msgs {
+		switch v.Name {
+		case actor.MsgBlockCompleted:
+			result = v.Data.(string)
+		case actor.MsgSelectedReceipts:
+			receipts = v.Data.(*[]*ethTypes.Receipt)
+		case actor.MsgPendingBlock:
+			block = v.Data.(*types.MonacoBlock)
+		}
+	}
+	if actor.MsgBlockCompleted_Success != result || block == nil {
+		return nil
+	}
+
+	executed := make(map[ethCommon.Hash]*ethTypes.Receipt)
+	if receipts != nil {
+		for _, receipt := range *receipts {
+			executed[receipt.TxHash] = receipt
+		}
+	}
+
+	txs := make([]internal.AccountTx, 0, len(executed))
+	for _, rawTx := range block.Txs {
+		tx, ok := decodeRawTx(rawTx)
+		if !ok {
+			continue
+		}
+		receipt, ok := executed[ethCommon.BytesToHash(tx.Hash().Bytes())]
+		if !ok {
+			continue
+		}
+		from, err := ethtyp.Sender(ai.signer, tx)
+		if err != nil {
+			continue
+		}
+		to := tx.To()
+		if to == nil {
+			created := ethcmn.BytesToAddress(receipt.ContractAddress.Bytes())
+			to = &created
+		}
+		txs = append(txs, internal.AccountTx{
+			Hash:  tx.Hash(),
+			From:  from,
+			To:    to,
+			Nonce: tx.Nonce(),
+		})
+	}
+
+	if err := ai.index.Add(block.Height, txs); err != nil {
+		ai.AddLog(log.LogLevel_Error, "index account transactions failed", zap.Uint64("height", block.Height), zap.Error(err))
+	}
+	return nil
+}
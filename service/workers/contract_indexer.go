@@ -0,0 +1,102 @@
+package workers
+
+import (
+	"math/big"
+
+	ethCommon "github.com/arcology-network/3rd-party/eth/common"
+	ethTypes "github.com/arcology-network/3rd-party/eth/types"
+	"github.com/arcology-network/common-lib/types"
+	"github.com/arcology-network/component-lib/actor"
+	"github.com/arcology-network/component-lib/log"
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	ethcmn "github.com/arcology-network/evm/common"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	ethcrp "github.com/arcology-network/evm/crypto"
+	"go.uber.org/zap"
+)
+
+// ContractIndexer records the contracts deployed by the transactions of each
+// completed block, with the hash of the code they were deployed with.
+type ContractIndexer struct {
+	actor.WorkerThread
+	index  *internal.ContractIndex
+	signer ethtyp.Signer
+	code   func(address ethcmn.Address, number int64) ([]byte, error)
+}
+
+//return a Subscriber struct
+func NewContractIndexer(concurrency int, groupid string, index *internal.ContractIndex, chainID uint64, code func(ethcmn.Address, int64) ([]byte, error)) *ContractIndexer {
+	ci := ContractIndexer{}
+	ci.Set(concurrency, groupid)
+	ci.index = index
+	ci.signer = ethtyp.LatestSignerForChainID(new(big.Int).SetUint64(chainID))
+	ci.code = code
+	return &ci
+}
+
+func (*ContractIndexer) OnStart() {}
+func (*ContractIndexer) Stop()    {}
+
+func (ci *ContractIndexer) OnMessageArrived(msgs []*actor.Message) error {
+	result := ""
+	var receipts *[]*ethTypes.Receipt
+	var block *types.MonacoBlock
+
+	for _, v := range msgs {
+		switch v.Name {
+		case actor.MsgBlockCompleted:
+			result = v.Data.(string)
+		case actor.MsgSelectedReceipts:
+			receipts = v.Data.(*[]*ethTypes.Receipt)
+		case actor.MsgPendingBlock:
+			block = v.Data.(*types.MonacoBlock)
+		}
+	}
+	if actor.MsgBlockCompleted_Success != result || block == nil {
+		return nil
+	}
+
+	deployed := make(map[ethCommon.Hash]*ethTypes.Receipt)
+	if receipts != nil {
+		for _, receipt := range *receipts {
+			if receipt.Status == ethTypes.ReceiptStatusSuccessful && receipt.ContractAddress != (ethCommon.Address{}) {
+				deployed[receipt.TxHash] = receipt
+			}
+		}
+	}
+
+	creations := make([]*internal.ContractCreation, 0, len(deployed))
+	for _, rawTx := range block.Txs {
+		if len(deployed) == 0 {
+			break
+		}
+		tx, ok := decodeRawTx(rawTx)
+		if !ok || tx.To() != nil {
+			continue
+		}
+		receipt, ok := deployed[ethCommon.BytesToHash(tx.Hash().Bytes())]
+		if !ok {
+			continue
+		}
+		from, err := ethtyp.Sender(ci.signer, tx)
+		if err != nil {
+			continue
+		}
+		creation := &internal.ContractCreation{
+			Address: ethcmn.BytesToAddress(receipt.ContractAddress.Bytes()),
+			Creator: from,
+			TxHash:  tx.Hash(),
+		}
+		if code, err := ci.code(creation.Address, int64(block.Height)); err != nil {
+			ci.AddLog(log.LogLevel_Error, "read deployed code failed", zap.Uint64("height", block.Height), zap.String("address", creation.Address.Hex()), zap.Error(err))
+		} else if len(code) > 0 {
+			creation.CodeHash = ethcrp.Keccak256Hash(code)
+		}
+		creations = append(creations, creation)
+	}
+
+	if err := ci.index.Add(block.Height, creations); err != nil {
+		ci.AddLog(log.LogLevel_Error, "index contract deployments failed", zap.Uint64("height", block.Height), zap.Error(err))
+	}
+	return nil
+}
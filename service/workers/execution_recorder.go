@@ -0,0 +1,72 @@
+package workers
+
+import (
+	ethTypes "github.com/arcology-network/3rd-party/eth/types"
+	"github.com/arcology-network/common-lib/types"
+	"github.com/arcology-network/component-lib/actor"
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	ethcmn "github.com/arcology-network/evm/common"
+)
+
+// ExecutionRecorder follows the receipt batches and the inclusive list of
+// each block and keeps them once the block completes.
+type ExecutionRecorder struct {
+	actor.WorkerThread
+	insights *internal.ExecutionInsights
+	pending  map[uint64]*internal.BlockExecution
+}
+
+//return a Subscriber struct
+func NewExecutionRecorder(concurrency int, groupid string, insights *internal.ExecutionInsights) *ExecutionRecorder {
+	er := ExecutionRecorder{}
+	er.Set(concurrency, groupid)
+	er.insights = insights
+	er.pending = make(map[uint64]*internal.BlockExecution)
+	return &er
+}
+
+func (*ExecutionRecorder) OnStart() {}
+func (*ExecutionRecorder) Stop()    {}
+
+func (er *ExecutionRecorder) block(height uint64) *internal.BlockExecution {
+	block, ok := er.pending[height]
+	if !ok {
+		block = internal.NewBlockExecution(height)
+		er.pending[height] = block
+	}
+	return block
+}
+
+func (er *ExecutionRecorder) OnMessageArrived(msgs []*actor.Message) error {
+	for _, v := range msgs {
+		switch v.Name {
+		case actor.MsgReceipts:
+			receipts := *v.Data.(*[]*ethTypes.Receipt)
+			hashes := make([]ethcmn.Hash, len(receipts))
+			statuses := make([]uint64, len(receipts))
+			for i, receipt := range receipts {
+				hashes[i] = ethcmn.BytesToHash(receipt.TxHash.Bytes())
+				statuses[i] = uint64(receipt.Status)
+			}
+			er.block(v.Height).AddBatch(v.Round, hashes, statuses)
+		case actor.MsgInclusive:
+			inclusive := v.Data.(*types.InclusiveList)
+			hashes := make([]ethcmn.Hash, len(inclusive.HashList))
+			for i, hash := range inclusive.HashList {
+				hashes[i] = ethcmn.BytesToHash(hash.Bytes())
+			}
+			er.block(v.Height).SetInclusive(hashes, inclusive.Successful)
+		case actor.MsgBlockCompleted:
+			if block, ok := er.pending[v.Height]; ok && v.Data.(string) == actor.MsgBlockCompleted_Success {
+				block.Finish()
+				er.insights.Add(block)
+			}
+			for height := range er.pending {
+				if height <= v.Height {
+					delete(er.pending, height)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -8,19 +8,35 @@ import (
 	"github.com/arcology-network/common-lib/common"
 	"github.com/arcology-network/common-lib/types"
 	"github.com/arcology-network/component-lib/actor"
+	"github.com/arcology-network/component-lib/ethrpc"
+	"github.com/arcology-network/component-lib/log"
 	internal "github.com/arcology-network/eth-api-svc/backend"
+	ethcmn "github.com/arcology-network/evm/common"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	"go.uber.org/zap"
 )
 
 type FilterManager struct {
 	actor.WorkerThread
-	filters *internal.Filters
+	filters  *internal.Filters
+	logIndex *internal.LogIndex
+	receipts *internal.ReceiptCache
+	signer   ethtyp.Signer
 }
 
 //return a Subscriber struct
-func NewFilterManager(concurrency int, groupid string, filters *internal.Filters) *FilterManager {
+func NewFilterManager(concurrency int, groupid string, filters *internal.Filters, logIndex *internal.LogIndex, receipts *internal.ReceiptCache, chainID uint64) *FilterManager {
 	fm := FilterManager{}
 	fm.Set(concurrency, groupid)
 	fm.filters = filters
+	fm.logIndex = logIndex
+	fm.receipts = receipts
+	fm.signer = ethtyp.LatestSignerForChainID(new(big.Int).SetUint64(chainID))
+	if filters != nil {
+		filters.SetErrorLog(func(msg string, fields ...zap.Field) {
+			fm.AddLog(log.LogLevel_Error, msg, fields...)
+		})
+	}
 	return &fm
 }
 
@@ -49,6 +65,15 @@ func (fm *FilterManager) OnMessageArrived(msgs []*actor.Message) error {
 
 			blockHash := block.Hash()
 
+			// log indices run across the block, firstLogs[i] is the index of
+			// the first log of receipt i
+			firstLogs := make([]uint, len(*receipts))
+			logCount := uint(0)
+			for i, receipt := range *receipts {
+				firstLogs[i] = logCount
+				logCount += uint(len(receipt.Logs))
+			}
+
 			worker := func(start, end int, idx int, args ...interface{}) {
 				receipts := args[0].([]interface{})[0].(*[]*ethTypes.Receipt)
 				for i := start; i < end; i++ {
@@ -59,15 +84,30 @@ func (fm *FilterManager) OnMessageArrived(msgs []*actor.Message) error {
 
 					for k := range (*receipts)[i].Logs {
 						(*receipts)[i].Logs[k].BlockHash = (*receipts)[i].BlockHash
+						(*receipts)[i].Logs[k].BlockNumber = block.Height
 						(*receipts)[i].Logs[k].TxHash = (*receipts)[i].TxHash
 						(*receipts)[i].Logs[k].TxIndex = (*receipts)[i].TransactionIndex
+						(*receipts)[i].Logs[k].Index = firstLogs[i] + uint(k)
 					}
 					//storageTypes.SaveReceipt(s.datastore, block.Height, txhash, (*receipts)[i])
 				}
 			}
 
 			common.ParallelWorker(len(*receipts), fm.Concurrency, worker, receipts)
-			fm.filters.OnResultsArrived(block.Height, *receipts, ethCommon.BytesToHash(blockHash))
+			// Monaco blocks are hashed apart from their headers and carry no
+			// parent hash, so reorganizations are detected by height only: a
+			// rollback shows up as a block at a height already delivered.
+			fm.filters.OnResultsArrived(block.Height, *receipts, ethCommon.BytesToHash(blockHash), ethCommon.Hash{})
+
+			if fm.logIndex != nil {
+				err := fm.logIndex.Add(block.Height, ethcmn.BytesToHash(blockHash), ethrpc.ToLogs(*receipts))
+				if err != nil {
+					fm.AddLog(log.LogLevel_Error, "index logs failed", zap.Uint64("height", block.Height), zap.Error(err))
+				}
+			}
+			if fm.receipts != nil {
+				fm.receipts.Add(block.Height, ethcmn.BytesToHash(blockHash), fm.blockReceipts(block, *receipts))
+			}
 		}
 
 		//s.MsgBroker.Send(actor.MsgLatestHeight, height)
@@ -75,3 +115,39 @@ func (fm *FilterManager) OnMessageArrived(msgs []*actor.Message) error {
 
 	return nil
 }
+
+// blockReceipts pairs the receipts of a block with the transactions they
+// belong to.
+func (fm *FilterManager) blockReceipts(block *types.MonacoBlock, receipts []*ethTypes.Receipt) []*internal.BlockReceipt {
+	txs := make(map[ethcmn.Hash]*ethtyp.Transaction, len(block.Txs))
+	for _, rawTx := range block.Txs {
+		if tx, ok := decodeRawTx(rawTx); ok {
+			txs[tx.Hash()] = tx
+		}
+	}
+
+	results := make([]*internal.BlockReceipt, len(receipts))
+	for i, receipt := range receipts {
+		converted := &ethtyp.Receipt{
+			PostState:         receipt.PostState,
+			Status:            receipt.Status,
+			CumulativeGasUsed: receipt.CumulativeGasUsed,
+			Bloom:             ethtyp.BytesToBloom(receipt.Bloom.Bytes()),
+			Logs:              ethrpc.ToLogs([]*ethTypes.Receipt{receipt}),
+			TxHash:            ethcmn.BytesToHash(receipt.TxHash.Bytes()),
+			ContractAddress:   ethcmn.BytesToAddress(receipt.ContractAddress.Bytes()),
+			GasUsed:           receipt.GasUsed,
+			BlockHash:         ethcmn.BytesToHash(receipt.BlockHash.Bytes()),
+			BlockNumber:       receipt.BlockNumber,
+			TransactionIndex:  receipt.TransactionIndex,
+		}
+		result := &internal.BlockReceipt{Receipt: converted, GasPrice: new(big.Int)}
+		if tx, ok := txs[converted.TxHash]; ok {
+			converted.Type = tx.Type()
+			result.From, _ = ethtyp.Sender(fm.signer, tx)
+			result.To, result.GasPrice = tx.To(), tx.GasPrice()
+		}
+		results[i] = result
+	}
+	return results
+}
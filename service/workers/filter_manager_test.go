@@ -0,0 +1,61 @@
+package workers
+
+import (
+	"testing"
+	"time"
+
+	ethCommon "github.com/arcology-network/3rd-party/eth/common"
+	ethTypes "github.com/arcology-network/3rd-party/eth/types"
+	"github.com/arcology-network/common-lib/types"
+	"github.com/arcology-network/component-lib/actor"
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	eth "github.com/arcology-network/evm"
+	ethcmn "github.com/arcology-network/evm/common"
+	ethtyp "github.com/arcology-network/evm/core/types"
+)
+
+// TestFilterManager delivers a completed block, its logs must reach the
+// filters and the receipt cache numbered across the block.
+func TestFilterManager(t *testing.T) {
+	filters := internal.NewFilters(time.Minute, internal.FilterLimits{})
+	id := filters.NewFilter(eth.FilterQuery{})
+	cache := internal.NewReceiptCache(16)
+	newLog := func() *ethTypes.Log {
+		return &ethTypes.Log{Address: ethCommon.Address{0x31}, Topics: []ethCommon.Hash{{0x41}}}
+	}
+	receipts := []*ethTypes.Receipt{
+		{TxHash: ethCommon.Hash{1}, Status: ethTypes.ReceiptStatusSuccessful, Logs: []*ethTypes.Log{newLog(), newLog()}},
+		{TxHash: ethCommon.Hash{2}, Status: ethTypes.ReceiptStatusSuccessful, Logs: []*ethTypes.Log{newLog()}},
+	}
+
+	fm := NewFilterManager(1, "filter-manager", filters, nil, cache, 1)
+	if err := fm.OnMessageArrived([]*actor.Message{
+		{Name: actor.MsgBlockCompleted, Data: actor.MsgBlockCompleted_Success},
+		{Name: actor.MsgSelectedReceipts, Data: &receipts},
+		{Name: actor.MsgPendingBlock, Data: &types.MonacoBlock{Height: 3}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := filters.GetFilterChanges(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs := changes.([]*ethtyp.Log)
+	if len(logs) != 3 {
+		t.Fatalf("expected the 3 logs of the block, got %d", len(logs))
+	}
+	for i, log := range logs {
+		if log.Index != uint(i) || log.BlockNumber != 3 || log.TxIndex != uint(i/2) {
+			t.Fatalf("log %d: unexpected position %+v", i, log)
+		}
+	}
+
+	cached, ok := cache.ByTransaction(ethcmn.Hash{2})
+	if !ok {
+		t.Fatal("expected the receipts of block 3 to be cached")
+	}
+	if receipt := cached.Receipt; receipt.TransactionIndex != 1 || receipt.BlockNumber.Uint64() != 3 || len(receipt.Logs) != 1 || receipt.Logs[0].Index != 2 {
+		t.Fatalf("unexpected cached receipt %+v", receipt)
+	}
+}
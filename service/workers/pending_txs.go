@@ -0,0 +1,82 @@
+package workers
+
+import (
+	"fmt"
+
+	"github.com/arcology-network/component-lib/actor"
+	"github.com/arcology-network/component-lib/log"
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	ethcmn "github.com/arcology-network/evm/common"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	"go.uber.org/zap"
+)
+
+// PendingTxs feeds the transactions accepted by the gateway to the pending
+// transaction filters.
+type PendingTxs struct {
+	actor.WorkerThread
+	filters *internal.Filters
+}
+
+//return a Subscriber struct
+func NewPendingTxs(concurrency int, groupid string, filters *internal.Filters) *PendingTxs {
+	pt := PendingTxs{}
+	pt.Set(concurrency, groupid)
+	pt.filters = filters
+	return &pt
+}
+
+func (*PendingTxs) OnStart() {}
+func (*PendingTxs) Stop()    {}
+
+func (pt *PendingTxs) OnMessageArrived(msgs []*actor.Message) error {
+	for _, v := range msgs {
+		if v.Name != actor.MsgTxLocals {
+			continue
+		}
+
+		var rawTxs [][]byte
+		switch data := v.Data.(type) {
+		case [][]byte:
+			rawTxs = data
+		case *[][]byte:
+			rawTxs = *data
+		default:
+			pt.AddLog(log.LogLevel_Error, "unexpected pending transactions", zap.String("type", fmt.Sprintf("%T", v.Data)))
+			continue
+		}
+
+		hashes := make([]ethcmn.Hash, 0, len(rawTxs))
+		for _, rawTx := range rawTxs {
+			if hash, ok := pendingTxHash(rawTx); ok {
+				hashes = append(hashes, hash)
+			}
+		}
+		if len(hashes) > 0 {
+			pt.filters.OnPendingTransactions(hashes)
+		}
+	}
+	return nil
+}
+
+// decodeRawTx decodes a raw transaction, which may carry the gateway's
+// one-byte source prefix.
+func decodeRawTx(rawTx []byte) (*ethtyp.Transaction, bool) {
+	tx := new(ethtyp.Transaction)
+	if err := tx.UnmarshalBinary(rawTx); err == nil {
+		return tx, true
+	}
+	if len(rawTx) > 1 {
+		if err := tx.UnmarshalBinary(rawTx[1:]); err == nil {
+			return tx, true
+		}
+	}
+	return nil, false
+}
+
+func pendingTxHash(rawTx []byte) (ethcmn.Hash, bool) {
+	if tx, ok := decodeRawTx(rawTx); ok {
+		return tx.Hash(), true
+	}
+	return ethcmn.Hash{}, false
+}
@@ -0,0 +1,55 @@
+package workers
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/arcology-network/component-lib/actor"
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	ethcmn "github.com/arcology-network/evm/common"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	ethcrp "github.com/arcology-network/evm/crypto"
+)
+
+func TestPendingTxs(t *testing.T) {
+	key, _ := ethcrp.GenerateKey()
+	signer := ethtyp.NewEIP155Signer(big.NewInt(1))
+	var rawTxs [][]byte
+	var hashes []ethcmn.Hash
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		tx, err := ethtyp.SignTx(ethtyp.NewTransaction(nonce, ethcmn.Address{1}, new(big.Int), 21000, big.NewInt(1), nil), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, err := tx.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rawTxs = append(rawTxs, raw)
+		hashes = append(hashes, tx.Hash())
+	}
+	// the second one carries the gateway's source prefix
+	rawTxs[1] = append([]byte{1}, rawTxs[1]...)
+
+	filters := internal.NewFilters(time.Minute, internal.FilterLimits{})
+	id := filters.NewPendingTransactionFilter()
+	pt := NewPendingTxs(1, "pending-txs", filters)
+	if err := pt.OnMessageArrived([]*actor.Message{
+		{Name: actor.MsgTxLocals, Data: rawTxs[:1]},
+		{Name: actor.MsgTxLocals, Data: [][]byte{{0xde, 0xad}}},
+		{Name: actor.MsgTxLocals, Data: &[][]byte{rawTxs[1]}},
+		{Name: actor.MsgBlockCompleted, Data: rawTxs},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := filters.GetFilterChanges(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := changes.([]ethcmn.Hash)
+	if len(got) != 2 || got[0] != hashes[0] || got[1] != hashes[1] {
+		t.Fatalf("expected the hashes of both transactions, got %v", got)
+	}
+}
@@ -0,0 +1,77 @@
+package workers
+
+import (
+	ethTypes "github.com/arcology-network/3rd-party/eth/types"
+	"github.com/arcology-network/common-lib/types"
+	"github.com/arcology-network/component-lib/actor"
+	"github.com/arcology-network/component-lib/log"
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	ethcmn "github.com/arcology-network/evm/common"
+	ethtyp "github.com/arcology-network/evm/core/types"
+	"go.uber.org/zap"
+)
+
+// TokenIndexer decodes the token transfers in the receipts of each completed
+// block into the token index.
+type TokenIndexer struct {
+	actor.WorkerThread
+	index *internal.TokenIndex
+}
+
+//return a Subscriber struct
+func NewTokenIndexer(concurrency int, groupid string, index *internal.TokenIndex) *TokenIndexer {
+	ti := TokenIndexer{}
+	ti.Set(concurrency, groupid)
+	ti.index = index
+	return &ti
+}
+
+func (*TokenIndexer) OnStart() {}
+func (*TokenIndexer) Stop()    {}
+
+func (ti *TokenIndexer) OnMessageArrived(msgs []*actor.Message) error {
+	result := ""
+	var receipts *[]*ethTypes.Receipt
+	var block *types.MonacoBlock
+
+	for _, v := range msgs {
+		switch v.Name {
+		case actor.MsgBlockCompleted:
+			result = v.Data.(string)
+		case actor.MsgSelectedReceipts:
+			receipts = v.Data.(*[]*ethTypes.Receipt)
+		case actor.MsgPendingBlock:
+			block = v.Data.(*types.MonacoBlock)
+		}
+	}
+	if actor.MsgBlockCompleted_Success != result || block == nil {
+		return nil
+	}
+
+	// The filter manager fills in the block fields of these receipts at the
+	// same time, so only the fields it leaves alone are read here.
+	var logs []*ethtyp.Log
+	if receipts != nil {
+		for _, receipt := range *receipts {
+			txHash := ethcmn.BytesToHash(receipt.TxHash.Bytes())
+			for _, l := range receipt.Logs {
+				topics := make([]ethcmn.Hash, len(l.Topics))
+				for i, topic := range l.Topics {
+					topics[i] = ethcmn.BytesToHash(topic.Bytes())
+				}
+				logs = append(logs, &ethtyp.Log{
+					Address: ethcmn.BytesToAddress(l.Address.Bytes()),
+					Topics:  topics,
+					Data:    l.Data,
+					TxHash:  txHash,
+					Index:   uint(len(logs)),
+				})
+			}
+		}
+	}
+
+	if err := ti.index.Add(block.Height, internal.DecodeTokenTransfers(block.Height, logs)); err != nil {
+		ti.AddLog(log.LogLevel_Error, "index token transfers failed", zap.Uint64("height", block.Height), zap.Error(err))
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package workers
+
+import (
+	"math/big"
+	"testing"
+
+	ethCommon "github.com/arcology-network/3rd-party/eth/common"
+	ethTypes "github.com/arcology-network/3rd-party/eth/types"
+	"github.com/arcology-network/common-lib/types"
+	"github.com/arcology-network/component-lib/actor"
+	internal "github.com/arcology-network/eth-api-svc/backend"
+	ethcmn "github.com/arcology-network/evm/common"
+	"github.com/arcology-network/evm/ethdb/memorydb"
+)
+
+func TestTokenIndexer(t *testing.T) {
+	index, err := internal.NewTokenIndex(memorydb.New(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, alice := ethcmn.Address{0xa0}, ethcmn.Address{1}
+	transfer := &ethTypes.Log{
+		Address: ethCommon.BytesToAddress(token.Bytes()),
+		Topics: []ethCommon.Hash{
+			ethCommon.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"),
+			{},
+			ethCommon.BytesToHash(alice.Bytes()),
+		},
+		Data: ethCommon.BigToHash(big.NewInt(1)).Bytes(),
+	}
+	receipts := []*ethTypes.Receipt{{TxHash: ethCommon.Hash{7}, Logs: []*ethTypes.Log{transfer}}}
+
+	ti := NewTokenIndexer(1, "token-indexer", index)
+	if err := ti.OnMessageArrived([]*actor.Message{
+		{Name: actor.MsgBlockCompleted, Data: actor.MsgBlockCompleted_Success},
+		{Name: actor.MsgSelectedReceipts, Data: &receipts},
+		{Name: actor.MsgPendingBlock, Data: &types.MonacoBlock{Height: 3}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	transfers, _, err := index.Transfers(alice, nil, nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transfers) != 1 || transfers[0].Block != 3 || transfers[0].TxHash != (ethcmn.Hash{7}) || transfers[0].Value.Int64() != 1 {
+		t.Fatalf("expected the minted token, got %+v", transfers)
+	}
+	if index.Head() != 3 {
+		t.Fatalf("expected block 3 indexed, got %d", index.Head())
+	}
+}